@@ -2,10 +2,21 @@
 source /opt/ros/melodic/setup.bash
 export PATH=$PWD/bin:/usr/local/go/bin:$PATH
 export GOPATH=$PWD:/usr/local/go
+export GO111MODULE=off
 
 roscore &
+go get gopkg.in/yaml.v3
 go install github.com/fetchrobotics/rosgo/gengo
 go generate github.com/fetchrobotics/rosgo/tests
+GOPATH=$PWD go generate github.com/fetchrobotics/rosgo/dynamic_reconfigure
+GOPATH=$PWD go generate github.com/fetchrobotics/rosgo/diagnostics
+GOPATH=$PWD go generate github.com/fetchrobotics/rosgo/tf
+GOPATH=$PWD go generate github.com/fetchrobotics/rosgo/message_filters
 go test github.com/fetchrobotics/rosgo/xmlrpc
 go test github.com/fetchrobotics/rosgo/ros
+go test github.com/fetchrobotics/rosgo/rostest
+go test github.com/fetchrobotics/rosgo/dynamic_reconfigure
+go test github.com/fetchrobotics/rosgo/diagnostics
+go test github.com/fetchrobotics/rosgo/tf
+go test github.com/fetchrobotics/rosgo/message_filters
 go test github.com/fetchrobotics/rosgo/tests/...
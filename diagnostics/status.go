@@ -0,0 +1,76 @@
+package diagnostics
+
+import (
+	"diagnostic_msgs"
+	"fmt"
+)
+
+// Levels of a DiagnosticStatus.
+const (
+	OK    uint8 = 0
+	Warn  uint8 = 1
+	Error uint8 = 2
+	Stale uint8 = 3
+)
+
+// Status is the DiagnosticStatus filled in by a task, with the helpers of the
+// DiagnosticStatusWrapper of diagnostic_updater.
+type Status struct {
+	diagnostic_msgs.DiagnosticStatus
+}
+
+// Summary sets the level and the message of the status.
+func (s *Status) Summary(level uint8, message string) {
+	s.Level = level
+	s.Message = message
+}
+
+// Summaryf sets the level and the message of the status formatted with fmt.Sprintf.
+func (s *Status) Summaryf(level uint8, format string, args ...interface{}) {
+	s.Summary(level, fmt.Sprintf(format, args...))
+}
+
+// ClearSummary resets the status to OK with an empty message.
+func (s *Status) ClearSummary() {
+	s.Summary(OK, "")
+}
+
+// MergeSummary raises the level of the status to level. The message is appended to
+// the messages of the same severity, where OK is one severity and all other levels are
+// another, and replaces the message of a lower severity.
+func (s *Status) MergeSummary(level uint8, message string) {
+	if (level > OK) == (s.Level > OK) {
+		if s.Message != "" && message != "" {
+			s.Message += "; "
+		}
+		s.Message += message
+	} else if level > s.Level {
+		s.Message = message
+	}
+	if level > s.Level {
+		s.Level = level
+	}
+}
+
+// MergeSummaryf merges a message formatted with fmt.Sprintf like MergeSummary.
+func (s *Status) MergeSummaryf(level uint8, format string, args ...interface{}) {
+	s.MergeSummary(level, fmt.Sprintf(format, args...))
+}
+
+// Add appends a key value pair. Booleans are written as True or False like
+// diagnostic_updater does, other values with fmt.Sprint.
+func (s *Status) Add(key string, value interface{}) {
+	text := fmt.Sprint(value)
+	if b, ok := value.(bool); ok {
+		text = "False"
+		if b {
+			text = "True"
+		}
+	}
+	s.Values = append(s.Values, diagnostic_msgs.KeyValue{Key: key, Value: text})
+}
+
+// Addf appends a key value pair whose value is formatted with fmt.Sprintf.
+func (s *Status) Addf(key string, format string, args ...interface{}) {
+	s.Values = append(s.Values, diagnostic_msgs.KeyValue{Key: key, Value: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,250 @@
+package diagnostics
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// CompositeTask runs several tasks as one. The status holds the values of all tasks,
+// and its summary is merged from theirs with MergeSummary.
+type CompositeTask struct {
+	name  string
+	mutex sync.Mutex
+	tasks []Task
+}
+
+// NewCompositeTask creates a composite task of tasks.
+func NewCompositeTask(name string, tasks ...Task) *CompositeTask {
+	return &CompositeTask{name: name, tasks: tasks}
+}
+
+// AddTask adds a task to the composite task.
+func (t *CompositeTask) AddTask(task Task) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.tasks = append(t.tasks, task)
+}
+
+func (t *CompositeTask) Name() string {
+	return t.name
+}
+
+func (t *CompositeTask) Run(status *Status) {
+	t.mutex.Lock()
+	tasks := append([]Task(nil), t.tasks...)
+	t.mutex.Unlock()
+
+	original := *status
+	var combined Status
+	for _, task := range tasks {
+		status.Summary(original.Level, original.Message)
+		task.Run(status)
+		combined.MergeSummary(status.Level, status.Message)
+	}
+	status.Summary(combined.Level, combined.Message)
+}
+
+// FrequencyStatusParams are the acceptable frequencies of a FrequencyStatus.
+type FrequencyStatusParams struct {
+	// MinFrequency and MaxFrequency are the acceptable frequencies in Hz. A maximum
+	// of zero or +Inf means that there is none.
+	MinFrequency float64
+	MaxFrequency float64
+
+	// Tolerance widens the range of acceptable frequencies by this fraction.
+	Tolerance float64
+
+	// WindowSize is the number of updates over which the frequency is measured.
+	// It defaults to 5.
+	WindowSize int
+}
+
+// FrequencyStatus is a task that checks how often Tick is called, e.g. for each
+// message of a topic, over the last updates.
+type FrequencyStatus struct {
+	name   string
+	params FrequencyStatusParams
+	now    func() time.Time
+
+	mutex  sync.Mutex
+	count  int
+	times  []time.Time
+	counts []int
+	index  int
+}
+
+// NewFrequencyStatus creates a frequency status task whose window follows the clock
+// of node.
+func NewFrequencyStatus(node ros.Node, name string, params FrequencyStatusParams) *FrequencyStatus {
+	if params.WindowSize < 1 {
+		params.WindowSize = 5
+	}
+	f := &FrequencyStatus{name: name, params: params, now: node.Clock().Now}
+	f.Clear()
+	return f
+}
+
+// Clear forgets the events of the window.
+func (f *FrequencyStatus) Clear() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	now := f.now()
+	f.count = 0
+	f.times = make([]time.Time, f.params.WindowSize)
+	f.counts = make([]int, f.params.WindowSize)
+	for i := range f.times {
+		f.times[i] = now
+	}
+	f.index = 0
+}
+
+// Tick records an event.
+func (f *FrequencyStatus) Tick() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.count++
+}
+
+func (f *FrequencyStatus) Name() string {
+	return f.name
+}
+
+func (f *FrequencyStatus) Run(status *Status) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	now := f.now()
+	events := f.count - f.counts[f.index]
+	window := now.Sub(f.times[f.index]).Seconds()
+	frequency := float64(events) / window
+	f.counts[f.index] = f.count
+	f.times[f.index] = now
+	f.index = (f.index + 1) % f.params.WindowSize
+
+	min := f.params.MinFrequency * (1 - f.params.Tolerance)
+	max := f.params.MaxFrequency * (1 + f.params.Tolerance)
+	hasMax := f.params.MaxFrequency > 0 && finite(max)
+	switch {
+	case events == 0:
+		status.Summary(Error, "No events recorded.")
+	case frequency < min:
+		status.Summary(Warn, "Frequency too low.")
+	case hasMax && frequency > max:
+		status.Summary(Warn, "Frequency too high.")
+	default:
+		status.Summary(OK, "Desired frequency met")
+	}
+
+	status.Add("Events in window", events)
+	status.Add("Events since startup", f.count)
+	status.Addf("Duration of window (s)", "%f", window)
+	status.Addf("Actual frequency (Hz)", "%f", frequency)
+	if f.params.MinFrequency == f.params.MaxFrequency {
+		status.Addf("Target frequency (Hz)", "%f", f.params.MinFrequency)
+	}
+	if f.params.MinFrequency > 0 {
+		status.Addf("Minimum acceptable frequency (Hz)", "%f", min)
+	}
+	if hasMax {
+		status.Addf("Maximum acceptable frequency (Hz)", "%f", max)
+	}
+}
+
+// TimeStampStatusParams are the acceptable delays of a TimeStampStatus.
+type TimeStampStatusParams struct {
+	// MinAcceptable and MaxAcceptable bound the delay between a stamp and the time it
+	// is ticked. A negative delay is a stamp from the future.
+	MinAcceptable time.Duration
+	MaxAcceptable time.Duration
+}
+
+// DefaultTimeStampStatusParams accept stamps up to a second in the future and five
+// seconds in the past, like diagnostic_updater.
+var DefaultTimeStampStatusParams = TimeStampStatusParams{MinAcceptable: -time.Second, MaxAcceptable: 5 * time.Second}
+
+// TimeStampStatus is a task that checks the stamps passed to Tick, e.g. the header
+// stamps of the messages of a topic, against the current time.
+type TimeStampStatus struct {
+	name   string
+	params TimeStampStatusParams
+	now    func() time.Time
+
+	mutex      sync.Mutex
+	valid      bool
+	minDelta   time.Duration
+	maxDelta   time.Duration
+	zeroSeen   bool
+	earlyCount int
+	lateCount  int
+	zeroCount  int
+}
+
+// NewTimeStampStatus creates a time stamp status task that compares stamps with the
+// clock of node.
+func NewTimeStampStatus(node ros.Node, name string, params TimeStampStatusParams) *TimeStampStatus {
+	return &TimeStampStatus{name: name, params: params, now: node.Clock().Now}
+}
+
+// Tick records a stamp. A zero stamp is an error.
+func (s *TimeStampStatus) Tick(stamp ros.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if stamp.IsZero() {
+		s.zeroSeen = true
+		return
+	}
+	delta := s.now().Sub(time.Unix(int64(stamp.Sec), int64(stamp.NSec)))
+	if !s.valid || delta > s.maxDelta {
+		s.maxDelta = delta
+	}
+	if !s.valid || delta < s.minDelta {
+		s.minDelta = delta
+	}
+	s.valid = true
+}
+
+func (s *TimeStampStatus) Name() string {
+	return s.name
+}
+
+func (s *TimeStampStatus) Run(status *Status) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	status.Summary(OK, "Timestamps are reasonable.")
+	if !s.valid {
+		status.Summary(Warn, "No data since last update.")
+	} else {
+		if s.minDelta < s.params.MinAcceptable {
+			status.Summary(Error, "Timestamps too far in future seen.")
+			s.earlyCount++
+		}
+		if s.maxDelta > s.params.MaxAcceptable {
+			status.Summary(Error, "Timestamps too far in past seen.")
+			s.lateCount++
+		}
+	}
+	if s.zeroSeen {
+		status.Summary(Error, "Zero timestamp seen.")
+		s.zeroCount++
+	}
+
+	status.Addf("Earliest timestamp delay:", "%f", s.minDelta.Seconds())
+	status.Addf("Latest timestamp delay:", "%f", s.maxDelta.Seconds())
+	status.Addf("Earliest acceptable timestamp delay:", "%f", s.params.MinAcceptable.Seconds())
+	status.Addf("Latest acceptable timestamp delay:", "%f", s.params.MaxAcceptable.Seconds())
+	status.Add("Late diagnostic update count:", s.lateCount)
+	status.Add("Early diagnostic update count:", s.earlyCount)
+	status.Add("Zero seen diagnostic update count:", s.zeroCount)
+
+	s.valid = false
+	s.minDelta = 0
+	s.maxDelta = 0
+	s.zeroSeen = false
+}
+
+// finite reports whether x is neither infinite nor NaN.
+func finite(x float64) bool {
+	return !math.IsInf(x, 0) && !math.IsNaN(x)
+}
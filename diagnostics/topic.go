@@ -0,0 +1,109 @@
+package diagnostics
+
+import "github.com/fetchrobotics/rosgo/ros"
+
+// TopicDiagnostic is a task of an updater that checks the frequency of the messages of
+// a topic and, if it was given stamp params, their header stamps. It is named after the
+// resolved topic, e.g. "/scan topic status".
+type TopicDiagnostic struct {
+	*CompositeTask
+	updater   *Updater
+	frequency *FrequencyStatus
+	stamp     *TimeStampStatus
+}
+
+// NewTopicDiagnostic creates a topic diagnostic and adds it to updater. stamp may be
+// nil for topics without stamps.
+func NewTopicDiagnostic(updater *Updater, topic string, freq FrequencyStatusParams, stamp *TimeStampStatusParams) *TopicDiagnostic {
+	name, err := updater.node.ResolveName(topic, true)
+	if err != nil {
+		updater.node.Logger().Errorf("Diagnostic of topic %s: %v", topic, err)
+		name = topic
+	}
+	d := &TopicDiagnostic{
+		CompositeTask: NewCompositeTask(name + " topic status"),
+		updater:       updater,
+		frequency:     NewFrequencyStatus(updater.node, "Frequency Status", freq),
+	}
+	d.AddTask(d.frequency)
+	if stamp != nil {
+		d.stamp = NewTimeStampStatus(updater.node, "Timestamp Status", *stamp)
+		d.AddTask(d.stamp)
+	}
+	updater.AddTask(d)
+	return d
+}
+
+// Tick records a message. Its header stamp is checked if the diagnostic checks stamps
+// and the message has a header, as found by ros.HeaderFields.
+func (d *TopicDiagnostic) Tick(msg ros.Message) {
+	if _, stamp, _, ok := ros.HeaderFields(msg); ok {
+		d.TickStamp(*stamp)
+		return
+	}
+	d.frequency.Tick()
+}
+
+// TickStamp records a message stamped with stamp.
+func (d *TopicDiagnostic) TickStamp(stamp ros.Time) {
+	d.frequency.Tick()
+	if d.stamp != nil {
+		d.stamp.Tick(stamp)
+	}
+}
+
+// Shutdown removes the diagnostic from its updater.
+func (d *TopicDiagnostic) Shutdown() {
+	d.updater.RemoveTask(d.Name())
+}
+
+// DiagnosedPublisher is a publisher whose messages are checked by a TopicDiagnostic.
+type DiagnosedPublisher struct {
+	ros.Publisher
+	*TopicDiagnostic
+}
+
+// NewDiagnosedPublisher advertises topic on the node of updater and adds a diagnostic
+// of the messages published to updater.
+func NewDiagnosedPublisher(updater *Updater, topic string, msgType ros.MessageType, freq FrequencyStatusParams, stamp *TimeStampStatusParams, opts ...ros.PublisherOption) *DiagnosedPublisher {
+	return &DiagnosedPublisher{
+		Publisher:       updater.node.NewPublisher(topic, msgType, opts...),
+		TopicDiagnostic: NewTopicDiagnostic(updater, topic, freq, stamp),
+	}
+}
+
+// Publish ticks the diagnostic and publishes msg.
+func (p *DiagnosedPublisher) Publish(msg ros.Message) {
+	p.Tick(msg)
+	p.Publisher.Publish(msg)
+}
+
+// Shutdown removes the diagnostic and shuts the publisher down.
+func (p *DiagnosedPublisher) Shutdown() {
+	p.TopicDiagnostic.Shutdown()
+	p.Publisher.Shutdown()
+}
+
+// DiagnosedSubscriber is a subscriber whose messages are checked by a TopicDiagnostic.
+type DiagnosedSubscriber struct {
+	ros.Subscriber
+	*TopicDiagnostic
+}
+
+// NewDiagnosedSubscriber subscribes to topic on the node of updater like ros.Subscribe
+// and adds a diagnostic of the messages received to updater. Messages are ticked before
+// callback is called.
+func NewDiagnosedSubscriber[T ros.Message](updater *Updater, topic string, freq FrequencyStatusParams, stamp *TimeStampStatusParams, callback func(T, ros.MessageEvent), opts ...ros.SubscriberOption) *DiagnosedSubscriber {
+	d := NewTopicDiagnostic(updater, topic, freq, stamp)
+	sub := ros.Subscribe(updater.node, topic, func(msg T, event ros.MessageEvent) {
+		d.Tick(msg)
+		callback(msg, event)
+	}, opts...)
+	return &DiagnosedSubscriber{Subscriber: sub, TopicDiagnostic: d}
+}
+
+// Shutdown removes the diagnostic and shuts the subscriber down.
+func (s *DiagnosedSubscriber) Shutdown() {
+	s.TopicDiagnostic.Shutdown()
+	s.Subscriber.Shutdown()
+}
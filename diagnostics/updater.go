@@ -0,0 +1,211 @@
+// Package diagnostics publishes the diagnostics of a node on /diagnostics, modelled on
+// the diagnostic_updater ROS package.
+package diagnostics
+
+// Diagnostics messages
+//go:generate gengo -out=$GOPATH/src msg diagnostic_msgs/KeyValue
+//go:generate gengo -out=$GOPATH/src msg diagnostic_msgs/DiagnosticStatus
+//go:generate gengo -out=$GOPATH/src msg diagnostic_msgs/DiagnosticArray
+//go:generate gengo -out=$GOPATH/src msg std_msgs/Header
+import (
+	"diagnostic_msgs"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+const (
+	diagnosticsTopic = "/diagnostics"
+	periodParam      = "~diagnostic_period"
+	defaultPeriod    = 1.0
+)
+
+// Task produces the status of a component of a node.
+type Task interface {
+	// Name names the component in the statuses of the task.
+	Name() string
+
+	// Run fills in status, which starts as an error with the name of the task.
+	Run(status *Status)
+}
+
+type funcTask struct {
+	name string
+	run  func(status *Status)
+}
+
+func (t *funcTask) Name() string       { return t.name }
+func (t *funcTask) Run(status *Status) { t.run(status) }
+
+// NewTask returns a task that calls run.
+func NewTask(name string, run func(status *Status)) Task {
+	return &funcTask{name, run}
+}
+
+// Updater runs its tasks periodically and publishes their statuses together on
+// /diagnostics. The name of each status is prefixed with the name of the node.
+//
+// The period is read in seconds from the parameter ~diagnostic_period, which defaults
+// to one second, and can be changed with SetPeriod. Tasks run on the node's executor.
+type Updater struct {
+	node   ros.Node
+	pub    ros.Publisher
+	prefix string
+
+	mutex            sync.Mutex
+	tasks            []Task
+	hardwareID       string
+	warnedHardwareID bool
+	period           time.Duration
+	timer            ros.Timer
+}
+
+// NewUpdater creates an updater publishing the diagnostics of node.
+func NewUpdater(node ros.Node) *Updater {
+	u := &Updater{node: node}
+	name, _ := node.ResolveName("~", false)
+	u.prefix = strings.Trim(name, "/")
+	period, err := ros.GetParamFloat(node, periodParam, defaultPeriod)
+	if err != nil {
+		node.Logger().Errorf("Failed to read %s, using %vs: %v", periodParam, defaultPeriod, err)
+		period = defaultPeriod
+	} else if period <= 0 {
+		node.Logger().Errorf("%s must be positive but is %v, using %vs", periodParam, period, defaultPeriod)
+		period = defaultPeriod
+	}
+	u.pub = node.NewPublisher(diagnosticsTopic, diagnostic_msgs.MsgDiagnosticArray)
+	u.SetPeriod(time.Duration(period * float64(time.Second)))
+	return u
+}
+
+// Add adds a task that calls run.
+func (u *Updater) Add(name string, run func(status *Status)) {
+	u.AddTask(NewTask(name, run))
+}
+
+// AddTask adds a task and publishes an OK status for it to tell that the node is
+// starting up.
+func (u *Updater) AddTask(task Task) {
+	u.mutex.Lock()
+	u.tasks = append(u.tasks, task)
+	hardwareID := u.hardwareID
+	u.mutex.Unlock()
+
+	var status Status
+	status.Name = task.Name()
+	status.HardwareId = hardwareID
+	status.Summary(OK, "Node starting up")
+	u.publish([]diagnostic_msgs.DiagnosticStatus{status.DiagnosticStatus})
+}
+
+// RemoveTask removes the first task named name. It reports whether there was one.
+func (u *Updater) RemoveTask(name string) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	for i, task := range u.tasks {
+		if task.Name() == name {
+			u.tasks = append(u.tasks[:i], u.tasks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// SetHardwareID sets the hardware ID of the statuses. Devices without an ID should
+// use "none".
+func (u *Updater) SetHardwareID(id string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.hardwareID = id
+}
+
+// Period returns the time between two updates.
+func (u *Updater) Period() time.Duration {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.period
+}
+
+// SetPeriod changes the time between two updates. The next update is one period from
+// now. A period that is not positive is reported to the logger and ignored.
+func (u *Updater) SetPeriod(period time.Duration) {
+	if period <= 0 {
+		u.node.Logger().Errorf("SetPeriod: the period must be positive but is %v", period)
+		return
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.timer != nil {
+		u.timer.Stop()
+	}
+	u.period = period
+	u.timer = u.node.NewTimer(period, func(ros.TimerEvent) { u.ForceUpdate() })
+}
+
+// ForceUpdate runs the tasks and publishes their statuses immediately.
+func (u *Updater) ForceUpdate() {
+	u.mutex.Lock()
+	tasks := append([]Task(nil), u.tasks...)
+	hardwareID := u.hardwareID
+	u.mutex.Unlock()
+	if len(tasks) == 0 {
+		return
+	}
+
+	statuses := make([]diagnostic_msgs.DiagnosticStatus, 0, len(tasks))
+	allOK := true
+	for _, task := range tasks {
+		var status Status
+		status.Name = task.Name()
+		status.HardwareId = hardwareID
+		status.Summary(Error, "No message was set")
+		task.Run(&status)
+		allOK = allOK && status.Level == OK
+		statuses = append(statuses, status.DiagnosticStatus)
+	}
+
+	// Like diagnostic_updater, warn about a missing hardware ID once everything is OK,
+	// as devices may only know their ID once they work.
+	u.mutex.Lock()
+	if allOK && hardwareID == "" && !u.warnedHardwareID {
+		u.warnedHardwareID = true
+		u.node.Logger().Warn("diagnostics: No hardware ID was set. Devices without one should set it to \"none\".")
+	}
+	u.mutex.Unlock()
+	u.publish(statuses)
+}
+
+// Broadcast publishes a status with level and message for every task, without running
+// the tasks, e.g. to tell that the node is shutting down.
+func (u *Updater) Broadcast(level uint8, message string) {
+	u.mutex.Lock()
+	statuses := make([]diagnostic_msgs.DiagnosticStatus, 0, len(u.tasks))
+	for _, task := range u.tasks {
+		var status Status
+		status.Name = task.Name()
+		status.HardwareId = u.hardwareID
+		status.Summary(level, message)
+		statuses = append(statuses, status.DiagnosticStatus)
+	}
+	u.mutex.Unlock()
+	u.publish(statuses)
+}
+
+// Shutdown stops the updates and the publisher.
+func (u *Updater) Shutdown() {
+	u.mutex.Lock()
+	u.timer.Stop()
+	u.mutex.Unlock()
+	u.pub.Shutdown()
+}
+
+func (u *Updater) publish(statuses []diagnostic_msgs.DiagnosticStatus) {
+	msg := &diagnostic_msgs.DiagnosticArray{Status: statuses}
+	msg.Header.Stamp.FromNSec(uint64(u.node.Clock().Now().UnixNano()))
+	for i := range msg.Status {
+		msg.Status[i].Name = u.prefix + ": " + msg.Status[i].Name
+	}
+	u.pub.Publish(msg)
+}
@@ -0,0 +1,266 @@
+package diagnostics
+
+import (
+	"diagnostic_msgs"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+	"github.com/fetchrobotics/rosgo/rostest"
+)
+
+func newTestUpdater(t *testing.T, node *rostest.Node) *Updater {
+	// Stamps are taken relative to the manual clock, which starts at the epoch.
+	node.Executor().Advance(1000 * time.Second)
+	return NewUpdater(node)
+}
+
+// lastStatuses returns the statuses of the last message published on /diagnostics.
+func lastStatuses(t *testing.T, node *rostest.Node) []diagnostic_msgs.DiagnosticStatus {
+	t.Helper()
+	published := node.Published(diagnosticsTopic)
+	if len(published) == 0 {
+		t.Fatal("nothing was published on /diagnostics")
+	}
+	return published[len(published)-1].(*diagnostic_msgs.DiagnosticArray).Status
+}
+
+func TestStatus(t *testing.T) {
+	var s Status
+	s.Summary(OK, "Fine")
+	s.MergeSummary(OK, "Charged")
+	if s.Level != OK || s.Message != "Fine; Charged" {
+		t.Errorf("unexpected summary %d %q", s.Level, s.Message)
+	}
+	s.MergeSummary(Warn, "Hot")
+	s.MergeSummaryf(Error, "Fan %d stopped", 2)
+	s.MergeSummary(OK, "Quiet")
+	if s.Level != Error || s.Message != "Hot; Fan 2 stopped" {
+		t.Errorf("unexpected summary %d %q", s.Level, s.Message)
+	}
+	s.Add("Enabled", true)
+	s.Add("Count", 3)
+	s.Addf("Voltage", "%.1f", 12.25)
+	expected := []diagnostic_msgs.KeyValue{{Key: "Enabled", Value: "True"}, {Key: "Count", Value: "3"}, {Key: "Voltage", Value: "12.2"}}
+	if !reflect.DeepEqual(s.Values, expected) {
+		t.Errorf("expected values %v but got %v", expected, s.Values)
+	}
+}
+
+func TestUpdater(t *testing.T) {
+	node := rostest.NewNode("/robot/driver")
+	defer node.Shutdown()
+	node.SetParam("~diagnostic_period", 0.5)
+	u := newTestUpdater(t, node)
+	defer u.Shutdown()
+	if u.Period() != 500*time.Millisecond {
+		t.Errorf("expected a period of 0.5s but got %v", u.Period())
+	}
+
+	u.SetHardwareID("serial-42")
+	voltage := 12.5
+	u.Add("Battery", func(status *Status) {
+		status.Summary(OK, "Charged")
+		status.Add("Voltage", voltage)
+	})
+	u.Add("Silent", func(status *Status) {})
+	statuses := lastStatuses(t, node)
+	if len(statuses) != 1 || statuses[0].Name != "robot/driver: Silent" || statuses[0].Message != "Node starting up" {
+		t.Errorf("expected a startup status but got %+v", statuses)
+	}
+
+	node.Executor().Advance(500 * time.Millisecond)
+	statuses = lastStatuses(t, node)
+	expected := []diagnostic_msgs.DiagnosticStatus{
+		{Level: OK, Name: "robot/driver: Battery", Message: "Charged", HardwareId: "serial-42", Values: []diagnostic_msgs.KeyValue{{Key: "Voltage", Value: "12.5"}}},
+		{Level: Error, Name: "robot/driver: Silent", Message: "No message was set", HardwareId: "serial-42", Values: []diagnostic_msgs.KeyValue{}},
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected %+v but got %+v", expected, statuses)
+	}
+	if stamp := node.Published(diagnosticsTopic)[2].(*diagnostic_msgs.DiagnosticArray).Header.Stamp; stamp != ros.NewTime(1000, 500000000) {
+		t.Errorf("unexpected stamp %v", stamp)
+	}
+
+	if !u.RemoveTask("Silent") || u.RemoveTask("Silent") {
+		t.Error("expected the task to be removed once")
+	}
+	voltage = 11
+	u.ForceUpdate()
+	if statuses = lastStatuses(t, node); len(statuses) != 1 || statuses[0].Values[0].Value != "11" {
+		t.Errorf("expected a forced update but got %+v", statuses)
+	}
+	u.Broadcast(Stale, "Shutting down")
+	if statuses = lastStatuses(t, node); len(statuses) != 1 || statuses[0].Level != Stale || len(statuses[0].Values) != 0 {
+		t.Errorf("expected a broadcast but got %+v", statuses)
+	}
+
+	u.SetPeriod(2 * time.Second)
+	u.SetPeriod(0)
+	if u.Period() != 2*time.Second {
+		t.Errorf("expected a period of 2s to be kept but got %v", u.Period())
+	}
+	count := len(node.Published(diagnosticsTopic))
+	node.Executor().Advance(time.Second)
+	if len(node.Published(diagnosticsTopic)) != count {
+		t.Error("expected no update before the new period")
+	}
+	node.Executor().Advance(time.Second)
+	u.Shutdown()
+	node.Executor().Advance(2 * time.Second)
+	if len(node.Published(diagnosticsTopic)) != count+1 {
+		t.Errorf("expected one update before the shutdown but got %d", len(node.Published(diagnosticsTopic))-count)
+	}
+}
+
+func TestCompositeTask(t *testing.T) {
+	task := NewCompositeTask("Drive",
+		NewTask("Left", func(status *Status) {
+			status.Summary(OK, "Left OK")
+			status.Add("Left speed", 1)
+		}),
+		NewTask("Right", func(status *Status) {
+			status.Summary(Warn, "Right slow")
+			status.Add("Right speed", 0)
+		}))
+	task.AddTask(NewTask("Brake", func(status *Status) {
+		status.Summary(Warn, "Brake worn")
+	}))
+	var status Status
+	status.Summary(Error, "No message was set")
+	task.Run(&status)
+	if status.Level != Warn || status.Message != "Right slow; Brake worn" {
+		t.Errorf("unexpected summary %d %q", status.Level, status.Message)
+	}
+	if len(status.Values) != 2 {
+		t.Errorf("expected the values of all tasks but got %v", status.Values)
+	}
+}
+
+func TestFrequencyStatus(t *testing.T) {
+	node := rostest.NewNode("/robot/driver")
+	defer node.Shutdown()
+	f := NewFrequencyStatus(node, "Frequency Status", FrequencyStatusParams{MinFrequency: 9, MaxFrequency: 11, Tolerance: 0.1, WindowSize: 2})
+
+	run := func(ticks int) Status {
+		for i := 0; i < ticks; i++ {
+			f.Tick()
+		}
+		node.Executor().Advance(time.Second)
+		var status Status
+		f.Run(&status)
+		return status
+	}
+	if status := run(0); status.Level != Error || status.Message != "No events recorded." {
+		t.Errorf("unexpected status %d %q", status.Level, status.Message)
+	}
+	// The window spans the last two updates.
+	if status := run(20); status.Level != OK {
+		t.Errorf("expected 10Hz to be fine but got %q", status.Message)
+	}
+	if status := run(30); status.Level != Warn || status.Message != "Frequency too high." || status.Values[0].Value != "50" {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if status := run(5); status.Level != Warn || status.Message != "Frequency too high." {
+		t.Errorf("unexpected status %d %q", status.Level, status.Message)
+	}
+	if status := run(0); status.Level != Warn || status.Message != "Frequency too low." {
+		t.Errorf("unexpected status %d %q", status.Level, status.Message)
+	}
+}
+
+func TestTimeStampStatus(t *testing.T) {
+	node := rostest.NewNode("/robot/driver")
+	defer node.Shutdown()
+	node.Executor().Advance(100 * time.Second)
+	s := NewTimeStampStatus(node, "Timestamp Status", DefaultTimeStampStatusParams)
+
+	var status Status
+	s.Run(&status)
+	if status.Level != Warn {
+		t.Errorf("expected a warning without stamps but got %q", status.Message)
+	}
+
+	s.Tick(ros.NewTime(99, 0))
+	s.Tick(ros.NewTime(98, 0))
+	status = Status{}
+	s.Run(&status)
+	if status.Level != OK || status.Values[0].Value != "1.000000" || status.Values[1].Value != "2.000000" {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	s.Tick(ros.NewTime(90, 0))
+	s.Tick(ros.NewTime(102, 0))
+	status = Status{}
+	s.Run(&status)
+	if status.Level != Error || status.Message != "Timestamps too far in past seen." {
+		t.Errorf("unexpected status %d %q", status.Level, status.Message)
+	}
+	s.Tick(ros.Time{})
+	status = Status{}
+	s.Run(&status)
+	if status.Message != "Zero timestamp seen." {
+		t.Errorf("unexpected status %d %q", status.Level, status.Message)
+	}
+	if counts := status.Values[4:]; counts[0].Value != "1" || counts[1].Value != "1" || counts[2].Value != "1" {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestDiagnosedPublisher(t *testing.T) {
+	node := rostest.NewNode("/robot/driver")
+	defer node.Shutdown()
+	u := newTestUpdater(t, node)
+	defer u.Shutdown()
+	u.SetHardwareID("none")
+
+	// Relay diagnostics stamped ten seconds ago, as an aggregator falling behind would.
+	pub := NewDiagnosedPublisher(u, "diagnostics_agg", diagnostic_msgs.MsgDiagnosticArray, FrequencyStatusParams{MinFrequency: 2, MaxFrequency: 2}, &DefaultTimeStampStatusParams)
+	for i := 0; i < 2; i++ {
+		msg := &diagnostic_msgs.DiagnosticArray{}
+		msg.Header.Stamp = ros.NewTime(990, 0)
+		pub.Publish(msg)
+	}
+	if len(node.Published("/robot/diagnostics_agg")) != 2 {
+		t.Error("expected the messages to be published")
+	}
+	node.Executor().Advance(time.Second)
+	statuses := lastStatuses(t, node)
+	if len(statuses) != 1 || statuses[0].Name != "robot/driver: /robot/diagnostics_agg topic status" {
+		t.Fatalf("unexpected statuses %+v", statuses)
+	}
+	if statuses[0].Level != Error || statuses[0].Message != "Timestamps too far in past seen." {
+		t.Errorf("unexpected summary %d %q", statuses[0].Level, statuses[0].Message)
+	}
+
+	pub.Shutdown()
+	count := len(node.Published(diagnosticsTopic))
+	node.Executor().Advance(time.Second)
+	if len(node.Published(diagnosticsTopic)) != count {
+		t.Error("expected no statuses without tasks")
+	}
+}
+
+func TestDiagnosedSubscriber(t *testing.T) {
+	node := rostest.NewNode("/robot/driver")
+	defer node.Shutdown()
+	u := newTestUpdater(t, node)
+	defer u.Shutdown()
+
+	var received []string
+	sub := NewDiagnosedSubscriber(u, "/battery", FrequencyStatusParams{MinFrequency: 1}, nil, func(msg *diagnostic_msgs.KeyValue, event ros.MessageEvent) {
+		received = append(received, msg.Value)
+	})
+	defer sub.Shutdown()
+	node.Inject("/battery", &diagnostic_msgs.KeyValue{Key: "Voltage", Value: "12.5"})
+	node.Inject("/battery", &diagnostic_msgs.KeyValue{Key: "Voltage", Value: "12.4"})
+	node.Executor().Advance(time.Second)
+	if !reflect.DeepEqual(received, []string{"12.5", "12.4"}) {
+		t.Errorf("expected the callback to receive the messages but got %v", received)
+	}
+	statuses := lastStatuses(t, node)
+	if len(statuses) != 1 || statuses[0].Level != OK || statuses[0].Values[0].Value != "2" {
+		t.Errorf("unexpected statuses %+v", statuses)
+	}
+}
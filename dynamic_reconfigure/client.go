@@ -0,0 +1,177 @@
+package dynamic_reconfigure
+
+import (
+	"context"
+	msgs "dynamic_reconfigure"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// Client reconfigures the dynamic_reconfigure server of another node, whether it is
+// written with rosgo, roscpp or rospy.
+//
+// Configurations are maps from parameter names to values of type bool, int, float64
+// or string.
+type Client struct {
+	node        ros.Node
+	namespace   string
+	callback    func(config map[string]interface{})
+	service     ros.ServiceClient
+	descSub     ros.Subscriber
+	updateSub   ros.Subscriber
+	mutex       sync.Mutex
+	description *msgs.ConfigDescription
+	config      map[string]interface{}
+}
+
+// NewClient creates a client for the server in namespace, which is the name of the
+// server's node unless the server uses another namespace. callback, if not nil, is
+// called on the node's executor with each configuration the server publishes.
+func NewClient(node ros.Node, namespace string, callback func(config map[string]interface{})) *Client {
+	c := &Client{
+		node:      node,
+		namespace: namespace,
+		callback:  callback,
+		service:   node.NewServiceClient(joinName(namespace, "set_parameters"), msgs.SrvReconfigure),
+	}
+	c.descSub = ros.Subscribe(node, joinName(namespace, "parameter_descriptions"), c.onDescription)
+	c.updateSub = ros.Subscribe(node, joinName(namespace, "parameter_updates"), c.onUpdate)
+	return c
+}
+
+// Configuration returns the last configuration published by the server. If there is
+// none yet, it waits for one until ctx is done; the node does not need to spin.
+func (c *Client) Configuration(ctx context.Context) (map[string]interface{}, error) {
+	c.mutex.Lock()
+	config := c.config
+	c.mutex.Unlock()
+	if config != nil {
+		return config, nil
+	}
+	msg, _, err := ros.WaitForMessage(ctx, c.node, joinName(c.namespace, "parameter_updates"), msgs.MsgConfig)
+	if err != nil {
+		return nil, err
+	}
+	return configValues(msg.(*msgs.Config)), nil
+}
+
+// Description returns the last description of the parameters published by the server.
+// If there is none yet, it waits for one until ctx is done; the node does not need to
+// spin.
+func (c *Client) Description(ctx context.Context) (*msgs.ConfigDescription, error) {
+	c.mutex.Lock()
+	desc := c.description
+	c.mutex.Unlock()
+	if desc != nil {
+		return desc, nil
+	}
+	msg, _, err := ros.WaitForMessage(ctx, c.node, joinName(c.namespace, "parameter_descriptions"), msgs.MsgConfigDescription)
+	if err != nil {
+		return nil, err
+	}
+	desc = msg.(*msgs.ConfigDescription)
+	c.mutex.Lock()
+	c.description = desc
+	c.mutex.Unlock()
+	return desc, nil
+}
+
+// UpdateConfiguration asks the server to change the parameters in changes and returns
+// the configuration the server applied, which reflects its constraints.
+//
+// Once the description of the parameters is received, unknown parameters are rejected
+// and integers are sent as doubles to double parameters. Before, the types of the values
+// decide how they are sent.
+func (c *Client) UpdateConfiguration(changes map[string]interface{}) (map[string]interface{}, error) {
+	c.mutex.Lock()
+	desc := c.description
+	c.mutex.Unlock()
+
+	var types map[string]string
+	if desc != nil {
+		types = make(map[string]string)
+		for _, group := range desc.Groups {
+			for _, p := range group.Parameters {
+				types[p.Name] = p.Type
+			}
+		}
+	}
+
+	var srv msgs.Reconfigure
+	config := &srv.Request.Config
+	for name, value := range changes {
+		typ, ok := types[name]
+		if types != nil && !ok {
+			return nil, fmt.Errorf("server %s has no parameter %s", c.namespace, name)
+		}
+		if !ok {
+			typ = typeOf(value)
+		}
+		converted, ok := (&param{typ: typ}).convert(value)
+		if !ok {
+			return nil, fmt.Errorf("parameter %s cannot be set to %T", name, value)
+		}
+		switch v := converted.(type) {
+		case bool:
+			config.Bools = append(config.Bools, msgs.BoolParameter{Name: name, Value: v})
+		case int:
+			config.Ints = append(config.Ints, msgs.IntParameter{Name: name, Value: int32(v)})
+		case float64:
+			config.Doubles = append(config.Doubles, msgs.DoubleParameter{Name: name, Value: v})
+		case string:
+			config.Strs = append(config.Strs, msgs.StrParameter{Name: name, Value: v})
+		}
+	}
+
+	if err := c.service.Call(&srv); err != nil {
+		return nil, err
+	}
+	values := configValues(&srv.Response.Config)
+	c.mutex.Lock()
+	c.config = values
+	c.mutex.Unlock()
+	return values, nil
+}
+
+// Shutdown stops the subscribers and the service client.
+func (c *Client) Shutdown() {
+	c.descSub.Shutdown()
+	c.updateSub.Shutdown()
+	c.service.Shutdown()
+}
+
+func (c *Client) onDescription(msg *msgs.ConfigDescription, event ros.MessageEvent) {
+	c.mutex.Lock()
+	c.description = msg
+	c.mutex.Unlock()
+}
+
+func (c *Client) onUpdate(msg *msgs.Config, event ros.MessageEvent) {
+	values := configValues(msg)
+	c.mutex.Lock()
+	c.config = values
+	c.mutex.Unlock()
+	if c.callback != nil {
+		c.callback(values)
+	}
+}
+
+// typeOf returns the parameter type that value is sent as when the description of the
+// parameters is unknown.
+func typeOf(value interface{}) string {
+	v := reflect.ValueOf(value)
+	switch {
+	case !v.IsValid():
+		return ""
+	case v.Kind() == reflect.Bool:
+		return typeBool
+	case v.CanInt():
+		return typeInt
+	case v.CanFloat():
+		return typeDouble
+	}
+	return typeStr
+}
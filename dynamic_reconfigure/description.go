@@ -0,0 +1,376 @@
+package dynamic_reconfigure
+
+import (
+	msgs "dynamic_reconfigure"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// Parameter types as named in dynamic_reconfigure/ParamDescription.
+const (
+	typeBool   = "bool"
+	typeInt    = "int"
+	typeDouble = "double"
+	typeStr    = "str"
+)
+
+// defaultGroup is the only group of a server; it holds all parameters.
+const defaultGroup = "Default"
+
+// param describes a reconfigurable field of a config struct. Values are held as bool,
+// int, float64 or string according to typ.
+type param struct {
+	name        string
+	field       int
+	typ         string
+	level       uint32
+	description string
+	min         interface{}
+	max         interface{}
+	dflt        interface{}
+	enum        []enumConstant
+}
+
+type enumConstant struct {
+	name  string
+	value interface{}
+}
+
+// description holds the parameters of a config struct type.
+type description struct {
+	params []*param
+	byName map[string]*param
+}
+
+// newDescription reads the parameters of the struct type t from its field tags.
+func newDescription(t reflect.Type) (*description, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config type %s is not a struct", t)
+	}
+	d := &description{byName: make(map[string]*param)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := ros.ParamFieldName(field)
+		if name == "" {
+			continue
+		}
+		p, err := newParam(field, name)
+		if err != nil {
+			return nil, fmt.Errorf("field %s of %s: %v", field.Name, t, err)
+		}
+		p.field = i
+		if _, ok := d.byName[name]; ok {
+			return nil, fmt.Errorf("parameter %s of %s is declared twice", name, t)
+		}
+		d.params = append(d.params, p)
+		d.byName[name] = p
+	}
+	return d, nil
+}
+
+func newParam(field reflect.StructField, name string) (*param, error) {
+	p := &param{name: name, description: field.Tag.Get("description")}
+	switch field.Type.Kind() {
+	case reflect.Bool:
+		p.typ, p.min, p.max, p.dflt = typeBool, false, true, false
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Values travel as int32, so wider fields are limited to its range.
+		bits := field.Type.Bits()
+		if bits > 32 {
+			bits = 32
+		}
+		p.typ, p.min, p.max, p.dflt = typeInt, -1<<(bits-1), 1<<(bits-1)-1, 0
+	case reflect.Float32, reflect.Float64:
+		p.typ, p.min, p.max, p.dflt = typeDouble, math.Inf(-1), math.Inf(1), 0.0
+	case reflect.String:
+		p.typ, p.min, p.max, p.dflt = typeStr, "", "", ""
+	default:
+		return nil, fmt.Errorf("type %s cannot be reconfigured", field.Type)
+	}
+
+	if level, ok := field.Tag.Lookup("level"); ok {
+		l, err := strconv.ParseUint(level, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level %q", level)
+		}
+		p.level = uint32(l)
+	}
+	if enum, ok := field.Tag.Lookup("enum"); ok {
+		for _, constant := range strings.Split(enum, ",") {
+			parts := strings.SplitN(constant, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("enum constant %q is not of the form Name=value", constant)
+			}
+			value, err := p.parse(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, err
+			}
+			p.enum = append(p.enum, enumConstant{strings.TrimSpace(parts[0]), value})
+		}
+		p.dflt = p.enum[0].value
+	}
+	for _, bound := range []struct {
+		tag    string
+		target *interface{}
+	}{{"min", &p.min}, {"max", &p.max}, {"default", &p.dflt}} {
+		text, ok := field.Tag.Lookup(bound.tag)
+		if !ok {
+			continue
+		}
+		value, err := p.parse(text)
+		if err != nil {
+			return nil, err
+		}
+		*bound.target = value
+	}
+
+	if p.typ == typeInt && p.min.(int) > p.max.(int) || p.typ == typeDouble && p.min.(float64) > p.max.(float64) {
+		return nil, fmt.Errorf("min %v is greater than max %v", p.min, p.max)
+	}
+	if _, ok := field.Tag.Lookup("default"); !ok {
+		p.dflt = p.clamp(p.dflt)
+	}
+	if p.constrain(p.dflt, nil) != p.dflt {
+		return nil, fmt.Errorf("default %v violates the constraints", p.dflt)
+	}
+	return p, nil
+}
+
+// parse parses text as a value of the parameter's type.
+func (p *param) parse(text string) (interface{}, error) {
+	var value interface{}
+	var err error
+	switch p.typ {
+	case typeBool:
+		value, err = strconv.ParseBool(text)
+	case typeInt:
+		var i int64
+		i, err = strconv.ParseInt(text, 10, 32)
+		value = int(i)
+	case typeDouble:
+		value, err = strconv.ParseFloat(text, 64)
+	default:
+		value = text
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q", p.typ, text)
+	}
+	return value, nil
+}
+
+// convert converts a value of a parameter, a message or a caller to the parameter's
+// type. Integers are accepted for doubles but not the other way round.
+func (p *param) convert(value interface{}) (interface{}, bool) {
+	v := reflect.ValueOf(value)
+	switch {
+	case !v.IsValid():
+		return nil, false
+	case p.typ == typeBool && v.Kind() == reflect.Bool:
+		return v.Bool(), true
+	case p.typ == typeStr && v.Kind() == reflect.String:
+		return v.String(), true
+	case p.typ == typeInt && v.CanInt():
+		return int(v.Int()), true
+	case p.typ == typeDouble && v.CanInt():
+		return float64(v.Int()), true
+	case p.typ == typeDouble && v.CanFloat():
+		return v.Float(), true
+	}
+	return nil, false
+}
+
+// clamp limits numbers to the range of the parameter.
+func (p *param) clamp(value interface{}) interface{} {
+	switch p.typ {
+	case typeInt:
+		return int(math.Max(float64(p.min.(int)), math.Min(float64(p.max.(int)), float64(value.(int)))))
+	case typeDouble:
+		return math.Max(p.min.(float64), math.Min(p.max.(float64), value.(float64)))
+	}
+	return value
+}
+
+// constrain clamps value, or returns fallback if value is not one of the enum constants.
+func (p *param) constrain(value interface{}, fallback interface{}) interface{} {
+	value = p.clamp(value)
+	if len(p.enum) == 0 {
+		return value
+	}
+	for _, constant := range p.enum {
+		if constant.value == value {
+			return value
+		}
+	}
+	return fallback
+}
+
+// editMethod returns the edit_method of the parameter, a Python literal that
+// rqt_reconfigure evaluates to show enums as drop-down lists.
+func (p *param) editMethod() string {
+	if len(p.enum) == 0 {
+		return ""
+	}
+	ctype := map[string]string{typeBool: "bool", typeInt: "int", typeDouble: "double", typeStr: "std::string"}[p.typ]
+	constants := make([]string, len(p.enum))
+	for i, constant := range p.enum {
+		constants[i] = fmt.Sprintf("{'name': %s, 'type': %s, 'value': %s, 'description': %s, 'srcline': 0, 'srcfile': '', 'cconsttype': %s, 'ctype': %s}",
+			pythonLiteral(constant.name), pythonLiteral(p.typ), pythonLiteral(constant.value), pythonLiteral(constant.name),
+			pythonLiteral("const "+ctype), pythonLiteral(ctype))
+	}
+	return fmt.Sprintf("{'enum': [%s], 'enum_description': %s}", strings.Join(constants, ", "), pythonLiteral(p.description))
+}
+
+func pythonLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		text := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".en") {
+			text += ".0"
+		}
+		return text
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(fmt.Sprint(value)) + "'"
+}
+
+// get returns the value of the parameter in the config struct.
+func (p *param) get(config reflect.Value) interface{} {
+	value, _ := p.convert(config.Field(p.field).Interface())
+	return value
+}
+
+// set stores value, which must be of the parameter's type, in the config struct.
+func (p *param) set(config reflect.Value, value interface{}) {
+	field := config.Field(p.field)
+	switch v := value.(type) {
+	case bool:
+		field.SetBool(v)
+	case int:
+		field.SetInt(int64(v))
+	case float64:
+		field.SetFloat(v)
+	case string:
+		field.SetString(v)
+	}
+}
+
+// defaults sets all parameters of the config struct to their defaults.
+func (d *description) defaults(config reflect.Value) {
+	for _, p := range d.params {
+		p.set(config, p.dflt)
+	}
+}
+
+// constrain applies the constraints of all parameters to the config struct. Parameters
+// that are not enum constants are set to their value in previous.
+func (d *description) constrain(config reflect.Value, previous reflect.Value) {
+	for _, p := range d.params {
+		p.set(config, p.constrain(p.get(config), p.get(previous)))
+	}
+}
+
+// apply stores values by parameter name in the config struct after applying the
+// constraints, and returns the levels of the parameters that changed or'ed together.
+// Values of unknown parameters and values that are not enum constants are ignored.
+func (d *description) apply(values map[string]interface{}, config reflect.Value) (uint32, error) {
+	var level uint32
+	for name, value := range values {
+		p, ok := d.byName[name]
+		if !ok {
+			continue
+		}
+		converted, ok := p.convert(value)
+		if !ok {
+			return 0, fmt.Errorf("parameter %s is a %s but got %T", name, p.typ, value)
+		}
+		converted = p.constrain(converted, p.get(config))
+		if converted != p.get(config) {
+			level |= p.level
+			p.set(config, converted)
+		}
+	}
+	return level, nil
+}
+
+// message returns the values of the config struct as a Config message.
+func (d *description) message(config reflect.Value) msgs.Config {
+	return d.valuesMessage(func(p *param) interface{} { return p.get(config) })
+}
+
+// valuesMessage returns a Config message with the value of each parameter.
+func (d *description) valuesMessage(value func(*param) interface{}) msgs.Config {
+	var msg msgs.Config
+	for _, p := range d.params {
+		switch v := value(p).(type) {
+		case bool:
+			msg.Bools = append(msg.Bools, msgs.BoolParameter{Name: p.name, Value: v})
+		case int:
+			msg.Ints = append(msg.Ints, msgs.IntParameter{Name: p.name, Value: int32(v)})
+		case float64:
+			msg.Doubles = append(msg.Doubles, msgs.DoubleParameter{Name: p.name, Value: v})
+		case string:
+			msg.Strs = append(msg.Strs, msgs.StrParameter{Name: p.name, Value: v})
+		}
+	}
+	msg.Groups = []msgs.GroupState{{Name: defaultGroup, State: true, Id: 0, Parent: 0}}
+	return msg
+}
+
+// descriptionMessage returns the ConfigDescription of the parameters.
+func (d *description) descriptionMessage() *msgs.ConfigDescription {
+	group := msgs.Group{Name: defaultGroup, Parameters: make([]msgs.ParamDescription, 0, len(d.params))}
+	for _, p := range d.params {
+		group.Parameters = append(group.Parameters, msgs.ParamDescription{
+			Name:        p.name,
+			Type:        p.typ,
+			Level:       p.level,
+			Description: p.description,
+			EditMethod:  p.editMethod(),
+		})
+	}
+	return &msgs.ConfigDescription{
+		Groups: []msgs.Group{group},
+		Max:    d.valuesMessage(func(p *param) interface{} { return p.max }),
+		Min:    d.valuesMessage(func(p *param) interface{} { return p.min }),
+		Dflt:   d.valuesMessage(func(p *param) interface{} { return p.dflt }),
+	}
+}
+
+// configValues returns the values of a Config message by parameter name. Integers are
+// returned as int.
+func configValues(msg *msgs.Config) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, p := range msg.Bools {
+		values[p.Name] = p.Value
+	}
+	for _, p := range msg.Ints {
+		values[p.Name] = int(p.Value)
+	}
+	for _, p := range msg.Strs {
+		values[p.Name] = p.Value
+	}
+	for _, p := range msg.Doubles {
+		values[p.Name] = p.Value
+	}
+	return values
+}
+
+// joinName appends name to the namespace of a server. Names in the private namespace
+// "~" are appended without a slash.
+func joinName(namespace string, name string) string {
+	if namespace == "~" || namespace == "" {
+		return namespace + name
+	}
+	return strings.TrimSuffix(namespace, "/") + "/" + name
+}
@@ -0,0 +1,190 @@
+package dynamic_reconfigure
+
+// Messages of the dynamic_reconfigure ROS package
+//go:generate gengo -out=$GOPATH/src msg dynamic_reconfigure/BoolParameter
+//go:generate gengo -out=$GOPATH/src msg dynamic_reconfigure/IntParameter
+//go:generate gengo -out=$GOPATH/src msg dynamic_reconfigure/StrParameter
+//go:generate gengo -out=$GOPATH/src msg dynamic_reconfigure/DoubleParameter
+//go:generate gengo -out=$GOPATH/src msg dynamic_reconfigure/GroupState
+//go:generate gengo -out=$GOPATH/src msg dynamic_reconfigure/Config
+//go:generate gengo -out=$GOPATH/src msg dynamic_reconfigure/ParamDescription
+//go:generate gengo -out=$GOPATH/src msg dynamic_reconfigure/Group
+//go:generate gengo -out=$GOPATH/src msg dynamic_reconfigure/ConfigDescription
+//go:generate gengo -out=$GOPATH/src srv dynamic_reconfigure/Reconfigure
+import (
+	msgs "dynamic_reconfigure"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// Server makes the fields of a config struct of type T reconfigurable at runtime by
+// rqt_reconfigure, dynparam or a Client.
+//
+// Each exported field of a bool, integer, floating point or string type is a parameter,
+// named after its `param` tag or the field name in snake_case like LoadParams does.
+// Other field tags declare the constraints of the parameter:
+//
+//	type Config struct {
+//		Speed float64 `min:"0" max:"2.5" default:"1" level:"1" description:"Maximum speed"`
+//		Mode  int     `enum:"Slow=0,Fast=1" default:"1" description:"Driving mode"`
+//		Frame string  `param:"frame_id" default:"base_link"`
+//	}
+//
+// Numbers are clamped to [min, max]. Values of enum parameters that are not one of the
+// constants are ignored. The default of a parameter is its zero value limited to
+// [min, max], or its first enum constant, if there is no default tag. Levels of the
+// parameters that changed are or'ed together and passed to the callback.
+type Server[T any] struct {
+	node      ros.Node
+	namespace string
+	desc      *description
+	callback  func(config *T, level uint32)
+	descMsg   *msgs.ConfigDescription
+	descPub   ros.Publisher
+	updatePub ros.Publisher
+	service   ros.ServiceServer
+	mutex     sync.Mutex
+	config    T
+	shutdown  bool
+}
+
+// NewServer creates a server for the config struct type T in namespace, which is
+// usually "~" to use the private namespace of the node like dynamic_reconfigure does.
+// The server advertises the service set_parameters and publishes the topics
+// parameter_descriptions and parameter_updates in namespace. New subscribers are sent
+// the last message of each topic as if the topics were latched.
+//
+// The initial config holds the defaults overridden by the parameters set in namespace.
+// It is passed to callback with all level bits set before NewServer returns. Later
+// callback is called on the node's executor for each reconfiguration, with the new
+// config that is applied and published when callback returns. callback may modify
+// the config but must not call UpdateConfig.
+func NewServer[T any](node ros.Node, namespace string, callback func(config *T, level uint32)) (*Server[T], error) {
+	var config T
+	desc, err := newDescription(reflect.TypeOf(config))
+	if err != nil {
+		return nil, err
+	}
+	s := &Server[T]{
+		node:      node,
+		namespace: namespace,
+		desc:      desc,
+		callback:  callback,
+		descMsg:   desc.descriptionMessage(),
+	}
+
+	values := reflect.ValueOf(&s.config).Elem()
+	desc.defaults(values)
+	params := make(map[string]interface{})
+	for _, p := range desc.params {
+		name := joinName(namespace, p.name)
+		ok, err := node.HasParam(name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if params[p.name], err = node.GetParam(name); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := desc.apply(params, values); err != nil {
+		return nil, fmt.Errorf("loading parameters of %s: %v", namespace, err)
+	}
+
+	s.descPub = node.NewPublisherWithCallbacks(joinName(namespace, "parameter_descriptions"), msgs.MsgConfigDescription, s.sendDescription, nil)
+	s.updatePub = node.NewPublisherWithCallbacks(joinName(namespace, "parameter_updates"), msgs.MsgConfig, s.sendUpdate, nil)
+	s.mutex.Lock()
+	s.reconfigure(^uint32(0))
+	s.mutex.Unlock()
+	s.descPub.Publish(s.descMsg)
+
+	s.service = ros.NewTypedServiceServer(node, joinName(namespace, "set_parameters"), s.setParameters)
+	return s, nil
+}
+
+// Config returns the current config.
+func (s *Server[T]) Config() T {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.config
+}
+
+// UpdateConfig replaces the config without calling the callback, for parameters that
+// the node changes itself. The constraints are applied, the parameters are stored and
+// the new config is published.
+func (s *Server[T]) UpdateConfig(config T) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.desc.constrain(reflect.ValueOf(&config).Elem(), reflect.ValueOf(&s.config).Elem())
+	s.config = config
+	s.publish()
+}
+
+// Shutdown stops the service and the publishers of the server.
+func (s *Server[T]) Shutdown() {
+	s.mutex.Lock()
+	s.shutdown = true
+	s.mutex.Unlock()
+	s.service.Shutdown()
+	s.descPub.Shutdown()
+	s.updatePub.Shutdown()
+}
+
+func (s *Server[T]) setParameters(srv *msgs.Reconfigure) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.shutdown {
+		return fmt.Errorf("server %s is shut down", s.namespace)
+	}
+	previous := s.config
+	level, err := s.desc.apply(configValues(&srv.Request.Config), reflect.ValueOf(&s.config).Elem())
+	if err != nil {
+		s.config = previous
+		return err
+	}
+	s.reconfigure(level)
+	srv.Response.Config = s.desc.message(reflect.ValueOf(&s.config).Elem())
+	return nil
+}
+
+// reconfigure calls the callback with the config and publishes the result.
+// The caller must hold the mutex.
+func (s *Server[T]) reconfigure(level uint32) {
+	if s.callback != nil {
+		s.callback(&s.config, level)
+	}
+	s.publish()
+}
+
+// publish stores the parameters of the config and publishes them. The caller must
+// hold the mutex.
+func (s *Server[T]) publish() {
+	values := reflect.ValueOf(&s.config).Elem()
+	for _, p := range s.desc.params {
+		value := p.get(values)
+		if i, ok := value.(int); ok {
+			value = int32(i)
+		}
+		if err := s.node.SetParam(joinName(s.namespace, p.name), value); err != nil {
+			s.node.Logger().Errorf("Failed to store parameter %s: %v", p.name, err)
+		}
+	}
+	msg := s.desc.message(values)
+	s.updatePub.Publish(&msg)
+}
+
+func (s *Server[T]) sendDescription(pub ros.SingleSubscriberPublisher) {
+	pub.Publish(s.descMsg)
+}
+
+func (s *Server[T]) sendUpdate(pub ros.SingleSubscriberPublisher) {
+	s.mutex.Lock()
+	msg := s.desc.message(reflect.ValueOf(&s.config).Elem())
+	s.mutex.Unlock()
+	pub.Publish(&msg)
+}
@@ -0,0 +1,226 @@
+package dynamic_reconfigure
+
+import (
+	"bytes"
+	"context"
+	msgs "dynamic_reconfigure"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/rostest"
+)
+
+type driverConfig struct {
+	Speed   float64 `min:"0" max:"2.5" default:"1" level:"1" description:"Maximum speed"`
+	Mode    int     `enum:"Slow=0,Fast=1" default:"1" level:"2" description:"Driving mode"`
+	Frame   string  `param:"frame_id" default:"base_link" level:"4"`
+	Enabled bool    `level:"8"`
+	Retries int8    `min:"1"`
+	Ignored float64 `param:"-"`
+	private int
+}
+
+func TestDescription(t *testing.T) {
+	desc, err := newDescription(reflect.TypeOf(driverConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := desc.descriptionMessage()
+	if len(msg.Groups) != 1 || msg.Groups[0].Name != "Default" {
+		t.Fatalf("unexpected groups %+v", msg.Groups)
+	}
+	var names []string
+	for _, p := range msg.Groups[0].Parameters {
+		names = append(names, p.Name+":"+p.Type)
+	}
+	expected := []string{"speed:double", "mode:int", "frame_id:str", "enabled:bool", "retries:int"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected parameters %v but got %v", expected, names)
+	}
+	mode := msg.Groups[0].Parameters[1]
+	if !strings.HasPrefix(mode.EditMethod, "{'enum': [{'name': 'Slow', 'type': 'int', 'value': 0,") ||
+		!strings.HasSuffix(mode.EditMethod, "'enum_description': 'Driving mode'}") {
+		t.Errorf("unexpected edit method %s", mode.EditMethod)
+	}
+
+	if got := configValues(&msg.Dflt); !reflect.DeepEqual(got, map[string]interface{}{
+		"speed": 1.0, "mode": 1, "frame_id": "base_link", "enabled": false, "retries": 1,
+	}) {
+		t.Errorf("unexpected defaults %v", got)
+	}
+	if got := configValues(&msg.Max); got["speed"] != 2.5 || got["retries"] != 127 || got["mode"] != math.MaxInt32 {
+		t.Errorf("unexpected maximums %v", got)
+	}
+	if got := configValues(&msg.Min); got["speed"] != 0.0 || got["retries"] != 1 {
+		t.Errorf("unexpected minimums %v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded msgs.ConfigDescription
+	if err := decoded.Deserialize(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, msg) {
+		t.Errorf("expected %+v after a round trip but got %+v", msg, decoded)
+	}
+}
+
+func TestInvalidDescriptions(t *testing.T) {
+	for _, config := range []interface{}{
+		struct {
+			Speed float64 `min:"2" max:"1"`
+		}{},
+		struct {
+			Speed float64 `max:"1" default:"2"`
+		}{},
+		struct {
+			Mode int `enum:"Slow=0,Fast=1" default:"2"`
+		}{},
+		struct {
+			Mode int `enum:"Slow"`
+		}{},
+		struct {
+			Limits []float64
+		}{},
+	} {
+		if _, err := newDescription(reflect.TypeOf(config)); err == nil {
+			t.Errorf("expected an error for %T", config)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	node := rostest.NewNode("/driver")
+	defer node.Shutdown()
+	node.SetParam("~speed", int32(2))
+	node.SetParam("~mode", int32(5))
+
+	type call struct {
+		config driverConfig
+		level  uint32
+	}
+	var calls []call
+	server, err := NewServer(node, "~", func(config *driverConfig, level uint32) {
+		calls = append(calls, call{*config, level})
+		config.Retries++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Shutdown()
+
+	initial := driverConfig{Speed: 2, Mode: 1, Frame: "base_link", Retries: 1}
+	if len(calls) != 1 || calls[0].config != initial || calls[0].level != ^uint32(0) {
+		t.Fatalf("expected the initial config %+v but got %+v", initial, calls)
+	}
+	if retries, _ := node.GetParam("~retries"); retries != int32(2) {
+		t.Errorf("expected the retries set by the callback to be stored but got %v", retries)
+	}
+	if published := node.Published("/driver/parameter_descriptions"); len(published) != 1 {
+		t.Errorf("expected one description but got %d", len(published))
+	}
+
+	srv := &msgs.Reconfigure{}
+	srv.Request.Config.Doubles = []msgs.DoubleParameter{{Name: "speed", Value: 7}}
+	srv.Request.Config.Ints = []msgs.IntParameter{{Name: "mode", Value: 0}, {Name: "retries", Value: 2}}
+	srv.Request.Config.Strs = []msgs.StrParameter{{Name: "unknown", Value: "x"}}
+	if err := node.NewServiceClient("~set_parameters", msgs.SrvReconfigure).Call(srv); err != nil {
+		t.Fatal(err)
+	}
+	expected := driverConfig{Speed: 2.5, Mode: 0, Frame: "base_link", Retries: 2}
+	if calls[1].config != expected || calls[1].level != 3 {
+		t.Errorf("expected %+v at level 3 but got %+v at level %d", expected, calls[1].config, calls[1].level)
+	}
+	expected.Retries = 3
+	if got := server.Config(); got != expected {
+		t.Errorf("expected config %+v but got %+v", expected, got)
+	}
+	if got := configValues(&srv.Response.Config)["speed"]; got != 2.5 {
+		t.Errorf("expected the response to hold the clamped speed but got %v", got)
+	}
+	if speed, _ := node.GetParam("~speed"); speed != 2.5 {
+		t.Errorf("expected the speed to be stored but got %v", speed)
+	}
+
+	srv = &msgs.Reconfigure{}
+	srv.Request.Config.Ints = []msgs.IntParameter{{Name: "mode", Value: 3}}
+	srv.Request.Config.Bools = []msgs.BoolParameter{{Name: "enabled", Value: true}}
+	if err := node.NewServiceClient("~set_parameters", msgs.SrvReconfigure).Call(srv); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.Config(); got.Mode != 0 || !got.Enabled || calls[2].level != 8 {
+		t.Errorf("expected the invalid mode to be ignored but got %+v at level %d", got, calls[2].level)
+	}
+
+	server.UpdateConfig(driverConfig{Speed: -1, Mode: 4, Frame: "odom"})
+	if got := server.Config(); got.Speed != 0 || got.Mode != 0 || got.Frame != "odom" || len(calls) != 3 {
+		t.Errorf("unexpected config %+v after UpdateConfig", got)
+	}
+	updates := node.Published("/driver/parameter_updates")
+	if len(updates) != 4 {
+		t.Fatalf("expected 4 updates but got %d", len(updates))
+	}
+	if got := configValues(updates[3].(*msgs.Config))["frame_id"]; got != "odom" {
+		t.Errorf("expected the last update to hold the new frame but got %v", got)
+	}
+}
+
+func TestClient(t *testing.T) {
+	node := rostest.NewNode("/driver")
+	defer node.Shutdown()
+	server, err := NewServer[driverConfig](node, "~", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Shutdown()
+
+	var updates []map[string]interface{}
+	client := NewClient(node, "/driver", func(config map[string]interface{}) {
+		updates = append(updates, config)
+	})
+	defer client.Shutdown()
+	// The connect callbacks run first and queue the messages they send.
+	node.Executor().RunPending()
+	node.Executor().RunPending()
+	if len(updates) != 1 || updates[0]["speed"] != 1.0 {
+		t.Fatalf("expected the current config on connection but got %v", updates)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	desc, err := client.Description(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(desc.Groups[0].Parameters) != 5 {
+		t.Errorf("unexpected description %+v", desc)
+	}
+
+	config, err := client.UpdateConfiguration(map[string]interface{}{"speed": 2, "frame_id": "odom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config["speed"] != 2.0 || config["frame_id"] != "odom" {
+		t.Errorf("unexpected config %v", config)
+	}
+	if got := server.Config(); got.Speed != 2 || got.Frame != "odom" {
+		t.Errorf("unexpected server config %+v", got)
+	}
+	if _, err := client.UpdateConfiguration(map[string]interface{}{"unknown": 1}); err == nil {
+		t.Error("expected an error for an unknown parameter")
+	}
+	if _, err := client.UpdateConfiguration(map[string]interface{}{"enabled": "yes"}); err == nil {
+		t.Error("expected an error for a string sent to a bool parameter")
+	}
+
+	node.Executor().RunPending()
+	if got, _ := client.Configuration(ctx); got["frame_id"] != "odom" || len(updates) != 2 {
+		t.Errorf("expected the published update but got %v", got)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+//go:generate gengo msg std_msgs/String
+import (
+	"fmt"
+	"os"
+	"std_msgs"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+func callback(msg *std_msgs.String, event ros.MessageEvent) {
+	fmt.Printf("Received from %s: %s\n", event.PublisherName, msg.Data)
+}
+
+func main() {
+	node, err := ros.NewNode("/typed_listener", os.Args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	defer node.Shutdown()
+	node.Logger().SetSeverity(ros.LogLevelDebug)
+	ros.Subscribe(node, "/chatter", callback)
+	node.Spin()
+}
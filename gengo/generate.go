@@ -238,6 +238,13 @@ func (m *{{ .ShortName }}) Deserialize(buf *bytes.Reader) error {
     return err
 }
 
+{{- if .HeaderField }}
+
+func (m *{{ .ShortName }}) HeaderFields() (*uint32, *ros.Time, *string) {
+    return &m.{{ .HeaderField }}.Seq, &m.{{ .HeaderField }}.Stamp, &m.{{ .HeaderField }}.FrameId
+}
+{{- end }}
+
 {{- if .IsAction }}
 {{- range .Fields }}
 {{-     if or (eq .GoName "Goal") (eq .GoName "Feedback") (eq .GoName "Result") }} 
@@ -311,6 +318,7 @@ type {{ .ShortName }} struct {
 
 func (s *{{ .ShortName }}) ReqMessage() ros.Message { return &s.Request }
 func (s *{{ .ShortName }}) ResMessage() ros.Message { return &s.Response }
+func (s *{{ .ShortName }}) GetServiceType() ros.ServiceType { return Srv{{ .ShortName }} }
 `
 
 var actionTemplate = `
@@ -369,6 +377,20 @@ type MsgGen struct {
 	BinaryRequired bool
 	IsAction       bool
 	Imports        []string
+
+	// HeaderField is the Go name of the std_msgs/Header field of the message, for which
+	// the message implements ros.HasHeader, or empty if it has none.
+	HeaderField string
+}
+
+// findHeaderField sets HeaderField to the first field holding a single std_msgs/Header.
+func (gen *MsgGen) findHeaderField() {
+	for _, field := range gen.Fields {
+		if field.Package+Sep+field.Type == HeaderFullName && !field.IsArray {
+			gen.HeaderField = field.GoName
+			return
+		}
+	}
 }
 
 func (gen *MsgGen) analyzeImports() {
@@ -412,6 +434,7 @@ func GenerateMessage(context *MsgContext, spec *MsgSpec, isAction bool) (string,
 	gen.MD5Sum = spec.MD5Sum
 
 	gen.analyzeImports()
+	gen.findHeaderField()
 
 	tmpl, err := template.New("msg").Parse(msgTemplate)
 	if err != nil {
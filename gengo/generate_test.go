@@ -158,8 +158,38 @@ Bar[42] xfa
 		t.Errorf("Failed to parse: %v", e)
 	}
 
-	_, err := GenerateMessage(ctx, spec, false)
+	code, err := GenerateMessage(ctx, spec, false)
 	if err != nil {
 		t.Errorf("Failed to generate message: %v", err)
 	}
+	if !strings.Contains(code, "func (m *Foo) HeaderFields() (*uint32, *ros.Time, *string) {") ||
+		!strings.Contains(code, "return &m.Header.Seq, &m.Header.Stamp, &m.Header.FrameId") {
+		t.Errorf("Expected the message to implement ros.HasHeader")
+	}
+}
+
+func TestGenerateService(t *testing.T) {
+	const text string = `
+string s
+---
+bool b
+`
+	rosPkgPath := os.Getenv("ROS_PACKAGE_PATH")
+	ctx, e := NewMsgContext(strings.Split(rosPkgPath, ":"))
+	if e != nil {
+		t.Errorf("Failed to create MsgContext.")
+	}
+
+	spec, e := ctx.LoadSrvFromString(text, "foo/Foo")
+	if e != nil {
+		t.Fatalf("Failed to parse: %v", e)
+	}
+
+	code, _, _, err := GenerateService(ctx, spec)
+	if err != nil {
+		t.Errorf("Failed to generate service: %v", err)
+	}
+	if !strings.Contains(code, "func (s *Foo) GetServiceType() ros.ServiceType { return SrvFoo }") {
+		t.Errorf("Expected the service to implement ros.TypedService")
+	}
 }
@@ -0,0 +1,100 @@
+// Package nodeimpl holds the parts of a ros.Node implementation that package ros and
+// package rostest share, so that the in-memory node of rostest calls callbacks and fires
+// timers exactly as the real node does.
+//
+// The package cannot import ros, which imports it. Its functions are generic over the
+// message, event and service types of ros instead.
+package nodeimpl
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// PushMessage delivers msg to ch without blocking, as the channels of
+// ros.Node.NewSubscriberChan are fed. When ch is full it drops the oldest message
+// if dropOldest is set and msg otherwise, and returns false.
+func PushMessage[T any](ch chan T, dropOldest bool, msg T) bool {
+	select {
+	case ch <- msg:
+		return true
+	default:
+	}
+	if dropOldest {
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+	return false
+}
+
+// CheckMessageCallback reports a callback that a subscriber cannot call, following the
+// rules of ros.Node.NewSubscriber. msg is a new message of the subscribed type, or nil
+// if the type has none, and event is a ros.MessageEvent.
+func CheckMessageCallback(callback interface{}, msg interface{}, event interface{}) error {
+	fun := reflect.ValueOf(callback)
+	if fun.Kind() != reflect.Func || fun.IsNil() {
+		return fmt.Errorf("callback must be a function but got %T", callback)
+	}
+	funType := fun.Type()
+	if funType.IsVariadic() || funType.NumIn() > 2 {
+		return fmt.Errorf("callback %s must take at most a message and a MessageEvent", funType)
+	}
+	if funType.NumIn() >= 1 {
+		if msg != nil && !reflect.TypeOf(msg).AssignableTo(funType.In(0)) {
+			return fmt.Errorf("callback %s cannot receive messages of type %T", funType, msg)
+		}
+	}
+	if funType.NumIn() == 2 && funType.In(1) != reflect.TypeOf(event) {
+		return fmt.Errorf("second argument of callback %s must be a MessageEvent", funType)
+	}
+	return nil
+}
+
+// CallMessageCallback invokes a subscriber callback accepted by CheckMessageCallback
+// with msg and event. M and E are ros.Message and ros.MessageEvent. Callbacks created
+// by ros.Subscribe and the common untyped signatures are called directly, anything else
+// through reflection.
+func CallMessageCallback[M any, E any](callback interface{}, msg M, event E) {
+	switch cb := callback.(type) {
+	case func(M, E):
+		cb(msg, event)
+	case func(M):
+		cb(msg)
+	case func():
+		cb()
+	default:
+		fun := reflect.ValueOf(callback)
+		args := []reflect.Value{reflect.ValueOf(msg), reflect.ValueOf(event)}
+		fun.Call(args[0:fun.Type().NumIn()])
+	}
+}
+
+// CallServiceHandler invokes handler, a service handler accepted by
+// ros.Node.NewServiceServer, with srv. S is ros.Service. Handlers created by
+// ros.NewTypedServiceServer are called directly, anything else through reflection.
+func CallServiceHandler[S any](handler interface{}, srv S) error {
+	if h, ok := handler.(func(S) error); ok {
+		return h(srv)
+	}
+	fun := reflect.ValueOf(handler)
+	if fun.Kind() != reflect.Func || fun.IsNil() || fun.Type().NumIn() != 1 {
+		return fmt.Errorf("Service handler has invalid signature %T", handler)
+	}
+	if fun.Type().NumOut() != 1 {
+		return fmt.Errorf("Service callback return type must be 'error'")
+	}
+	results := fun.Call([]reflect.Value{reflect.ValueOf(srv)})
+	if results[0].IsNil() {
+		return nil
+	}
+	if err, ok := results[0].Interface().(error); ok {
+		return err
+	}
+	return fmt.Errorf("Service handler has invalid signature %T", handler)
+}
@@ -0,0 +1,134 @@
+package nodeimpl
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock is the part of ros.Clock a Timer uses. S is ros.ClockTimer.
+type Clock[S Stopper] interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) S
+}
+
+// Stopper is a function scheduled with Clock.AfterFunc.
+type Stopper interface {
+	Stop() bool
+}
+
+// TimerEvent has the fields of ros.TimerEvent and converts to it.
+type TimerEvent struct {
+	LastExpected    time.Time
+	LastReal        time.Time
+	CurrentExpected time.Time
+	CurrentReal     time.Time
+}
+
+// Timer fires on a clock and posts its callback as a job of an executor.
+// At most one job of a timer is queued at a time: while it waits for the executor, the
+// periods that fall due are skipped, so a node that does not spin piles up no jobs.
+type Timer[S Stopper] struct {
+	clock    Clock[S]
+	post     func(name string, run func())
+	period   time.Duration
+	callback func(TimerEvent)
+	name     string
+
+	// OnStop is called once when the timer is stopped. Set it before Start.
+	OnStop func()
+
+	mutex        sync.Mutex
+	stopped      bool
+	pending      bool
+	clockTimer   Stopper
+	nextExpected time.Time
+	lastExpected time.Time
+	lastReal     time.Time
+}
+
+// NewTimer creates a timer that calls callback every period of clock once started.
+// post queues a job called name that runs run, like ros.Executor.Post. A timer with a
+// period that is not positive would fall due again at once, forever, so it never starts.
+func NewTimer[S Stopper](clock Clock[S], post func(name string, run func()), period time.Duration, callback func(TimerEvent)) *Timer[S] {
+	return &Timer[S]{
+		clock:    clock,
+		post:     post,
+		period:   period,
+		callback: callback,
+		name:     fmt.Sprintf("timer %v", period),
+		stopped:  period <= 0,
+	}
+}
+
+// Start schedules the first period.
+func (t *Timer[S]) Start() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.stopped {
+		return
+	}
+	t.nextExpected = t.clock.Now().Add(t.period)
+	t.clockTimer = t.clock.AfterFunc(t.period, t.fire)
+}
+
+// fire schedules the next period and queues the callback unless its previous job is still
+// queued. The next period is due one period after the previous expected time, so a late
+// callback does not make the timer drift.
+func (t *Timer[S]) fire() {
+	t.mutex.Lock()
+	if t.stopped {
+		t.mutex.Unlock()
+		return
+	}
+	expected := t.nextExpected
+	t.nextExpected = expected.Add(t.period)
+	delay := t.nextExpected.Sub(t.clock.Now())
+	if delay < 0 {
+		delay = 0
+	}
+	t.clockTimer = t.clock.AfterFunc(delay, t.fire)
+	if t.pending {
+		t.mutex.Unlock()
+		return
+	}
+	t.pending = true
+	t.mutex.Unlock()
+
+	t.post(t.name, func() {
+		t.mutex.Lock()
+		t.pending = false
+		if t.stopped {
+			t.mutex.Unlock()
+			return
+		}
+		event := TimerEvent{
+			LastExpected:    t.lastExpected,
+			LastReal:        t.lastReal,
+			CurrentExpected: expected,
+			CurrentReal:     t.clock.Now(),
+		}
+		t.lastExpected = event.CurrentExpected
+		t.lastReal = event.CurrentReal
+		t.mutex.Unlock()
+		t.callback(event)
+	})
+}
+
+// Stop stops the timer. A callback already queued does not run.
+func (t *Timer[S]) Stop() {
+	t.mutex.Lock()
+	if t.stopped {
+		t.mutex.Unlock()
+		return
+	}
+	t.stopped = true
+	if t.clockTimer != nil {
+		t.clockTimer.Stop()
+	}
+	onStop := t.OnStop
+	t.mutex.Unlock()
+	if onStop != nil {
+		onStop()
+	}
+}
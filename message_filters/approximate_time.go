@@ -0,0 +1,279 @@
+package message_filters
+
+import (
+	"math"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// ApproximateTime synchronizes messages with stamps close to each other. It passes on
+// the set of messages with the smallest spread of stamps, one of each input, among
+// those it can tell are optimal; each message is passed on at most once and sets are
+// passed on in order. This is the ApproximateTime policy of message_filters.
+type ApproximateTime struct {
+	*Synchronizer
+	policy *approximateTimePolicy
+}
+
+// NewApproximateTime creates a synchronizer of inputs that holds up to queueSize
+// messages of each input.
+func NewApproximateTime(node ros.Node, queueSize int, inputs ...Source) *ApproximateTime {
+	if queueSize < 1 {
+		node.Logger().Errorf("message_filters: the queue size of an approximate time synchronizer must be positive but is %d", queueSize)
+		queueSize = 1
+	}
+	n := len(inputs)
+	p := &approximateTimePolicy{
+		queueSize:           queueSize,
+		deques:              make([][]stampedMessage, n),
+		past:                make([][]stampedMessage, n),
+		hasDroppedMessages:  make([]bool, n),
+		interMessageBounds:  make([]time.Duration, n),
+		maxIntervalDuration: math.MaxInt64,
+		pivot:               noPivot,
+	}
+	return &ApproximateTime{newSynchronizer(node, p, inputs), p}
+}
+
+// SetAgePenalty sets how much sets of older messages are preferred over sets of newer
+// messages with a smaller spread. A penalty of zero, the default, only compares the
+// spreads; higher penalties pass sets on sooner.
+func (s *ApproximateTime) SetAgePenalty(penalty float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.policy.agePenalty = penalty
+}
+
+// SetMaxIntervalDuration sets the largest spread of the stamps of a set. Sets with
+// a larger spread are not passed on.
+func (s *ApproximateTime) SetMaxIntervalDuration(d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.policy.maxIntervalDuration = d
+}
+
+// SetInterMessageLowerBound tells that the messages of input i are stamped at least
+// bound apart, which lets the synchronizer pass sets on sooner.
+func (s *ApproximateTime) SetInterMessageLowerBound(i int, bound time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if i < 0 || i >= len(s.policy.interMessageBounds) {
+		s.node.Logger().Errorf("message_filters: input %d of a synchronizer of %d inputs", i, len(s.policy.interMessageBounds))
+		return
+	}
+	s.policy.interMessageBounds[i] = bound
+}
+
+const noPivot = -1
+
+// approximateTimePolicy searches for the best set of messages as described on
+// http://wiki.ros.org/message_filters/ApproximateTime. Messages waiting for a set
+// are in deques; while a candidate set is evaluated, the messages found to be too old
+// for a better set are moved to past, and moved back if the candidate is dropped.
+type approximateTimePolicy struct {
+	queueSize           int
+	agePenalty          float64
+	maxIntervalDuration time.Duration
+	interMessageBounds  []time.Duration
+
+	deques             [][]stampedMessage
+	past               [][]stampedMessage
+	hasDroppedMessages []bool
+	nonEmptyDeques     int
+
+	candidate      []stampedMessage
+	candidateStart int64
+	candidateEnd   int64
+	pivot          int
+	pivotTime      int64
+
+	// sets collects the sets passed on by one add.
+	sets [][]stampedMessage
+}
+
+func (p *approximateTimePolicy) add(i int, m stampedMessage) [][]stampedMessage {
+	p.sets = nil
+	p.deques[i] = append(p.deques[i], m)
+	if len(p.deques[i]) == 1 {
+		p.nonEmptyDeques++
+		if p.nonEmptyDeques == len(p.deques) {
+			p.process()
+		}
+	}
+	if len(p.deques[i])+len(p.past[i]) > p.queueSize {
+		// Cancel the search for a set and drop the oldest message of the input.
+		p.nonEmptyDeques = 0
+		for j := range p.deques {
+			p.recover(j, len(p.past[j]))
+		}
+		p.deques[i] = p.deques[i][1:]
+		if len(p.deques[i]) == 0 {
+			p.nonEmptyDeques--
+		}
+		p.hasDroppedMessages[i] = true
+		if p.pivot != noPivot {
+			p.candidate = nil
+			p.pivot = noPivot
+			p.process()
+		}
+	}
+	return p.sets
+}
+
+func (p *approximateTimePolicy) process() {
+	for p.nonEmptyDeques == len(p.deques) {
+		endIndex, endTime := p.boundary(true, p.frontTime)
+		startIndex, startTime := p.boundary(false, p.frontTime)
+		for i := range p.hasDroppedMessages {
+			if i != endIndex {
+				// No dropped message could have been better to use than the ones we
+				// have, so the input can be a pivot again.
+				p.hasDroppedMessages[i] = false
+			}
+		}
+		if p.pivot == noPivot {
+			if time.Duration(endTime-startTime) > p.maxIntervalDuration || p.hasDroppedMessages[endIndex] {
+				p.deleteFront(startIndex)
+				continue
+			}
+			p.makeCandidate()
+			p.candidateStart, p.candidateEnd = startTime, endTime
+			p.pivot, p.pivotTime = endIndex, endTime
+			p.moveFrontToPast(startIndex)
+		} else {
+			if !p.better(endTime, startTime) {
+				p.moveFrontToPast(startIndex)
+			} else {
+				p.makeCandidate()
+				p.candidateStart, p.candidateEnd = startTime, endTime
+				p.moveFrontToPast(startIndex)
+			}
+		}
+
+		switch {
+		case startIndex == p.pivot:
+			// Any further set would include a later message of the pivot.
+			p.publishCandidate()
+		case !p.better(endTime, p.pivotTime):
+			// Any further set contains [pivotTime, candidateEnd], so none is better.
+			p.publishCandidate()
+		case p.nonEmptyDeques < len(p.deques):
+			p.searchVirtually()
+		}
+	}
+}
+
+// searchVirtually assumes that the next message of each empty deque comes as soon as
+// possible, to find out whether any set to come could be better than the candidate.
+// If none could, the candidate is passed on; otherwise the virtual moves are undone to
+// wait for more messages.
+func (p *approximateTimePolicy) searchVirtually() {
+	moves := make([]int, len(p.deques))
+	for {
+		_, endTime := p.boundary(true, p.virtualTime)
+		startIndex, startTime := p.boundary(false, p.virtualTime)
+		if !p.better(endTime, p.pivotTime) {
+			p.publishCandidate()
+			return
+		}
+		if p.better(endTime, startTime) || len(p.deques[startIndex]) == 0 {
+			p.nonEmptyDeques = 0
+			for i := range p.deques {
+				p.recover(i, moves[i])
+			}
+			return
+		}
+		p.moveFrontToPast(startIndex)
+		moves[startIndex]++
+	}
+}
+
+// better reports whether a set from start to end would be better than the candidate.
+func (p *approximateTimePolicy) better(end, start int64) bool {
+	return float64(end-p.candidateEnd)*(1+p.agePenalty) < float64(start-p.candidateStart)
+}
+
+// boundary returns the input with the latest time if end is set, or the earliest time
+// otherwise, and that time.
+func (p *approximateTimePolicy) boundary(end bool, timeOf func(i int) int64) (int, int64) {
+	index, t := 0, timeOf(0)
+	for i := 1; i < len(p.deques); i++ {
+		if ti := timeOf(i); (ti < t) != end {
+			index, t = i, ti
+		}
+	}
+	return index, t
+}
+
+func (p *approximateTimePolicy) frontTime(i int) int64 {
+	return p.deques[i][0].stamp.UnixNano()
+}
+
+// virtualTime is the time of the next message of input i, which is the earliest time
+// it can come at if its deque is empty.
+func (p *approximateTimePolicy) virtualTime(i int) int64 {
+	if len(p.deques[i]) > 0 {
+		return p.frontTime(i)
+	}
+	bound := p.past[i][len(p.past[i])-1].stamp.UnixNano() + p.interMessageBounds[i].Nanoseconds()
+	if bound > p.pivotTime {
+		return bound
+	}
+	return p.pivotTime
+}
+
+// makeCandidate makes the fronts of the deques the candidate and forgets the past
+// messages, which are too old for any better set.
+func (p *approximateTimePolicy) makeCandidate() {
+	p.candidate = make([]stampedMessage, len(p.deques))
+	for i := range p.deques {
+		p.candidate[i] = p.deques[i][0]
+		p.past[i] = nil
+	}
+}
+
+// publishCandidate passes the candidate on and deletes its messages.
+func (p *approximateTimePolicy) publishCandidate() {
+	p.sets = append(p.sets, p.candidate)
+	p.candidate = nil
+	p.pivot = noPivot
+	p.nonEmptyDeques = 0
+	for i := range p.deques {
+		p.restore(i, len(p.past[i]))
+		// The oldest message of each input is that of the candidate.
+		p.deques[i] = p.deques[i][1:]
+		if len(p.deques[i]) > 0 {
+			p.nonEmptyDeques++
+		}
+	}
+}
+
+// recover restores the last n past messages of input i and counts its deque if it is
+// not empty.
+func (p *approximateTimePolicy) recover(i, n int) {
+	p.restore(i, n)
+	if len(p.deques[i]) > 0 {
+		p.nonEmptyDeques++
+	}
+}
+
+// restore moves the last n past messages of input i back to the front of its deque.
+func (p *approximateTimePolicy) restore(i, n int) {
+	past := p.past[i]
+	moved := past[len(past)-n:]
+	p.deques[i] = append(append([]stampedMessage(nil), moved...), p.deques[i]...)
+	p.past[i] = past[:len(past)-n]
+}
+
+func (p *approximateTimePolicy) deleteFront(i int) {
+	p.deques[i] = p.deques[i][1:]
+	if len(p.deques[i]) == 0 {
+		p.nonEmptyDeques--
+	}
+}
+
+func (p *approximateTimePolicy) moveFrontToPast(i int) {
+	p.past[i] = append(p.past[i], p.deques[i][0])
+	p.deleteFront(i)
+}
@@ -0,0 +1,155 @@
+package message_filters
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// stampedMessage is a message with its stamp.
+type stampedMessage struct {
+	stamp ros.Time
+	msg   ros.Message
+	event ros.MessageEvent
+}
+
+// Cache keeps the latest messages of its input, sorted by stamp, to look them up by
+// time. It passes every message on.
+type Cache struct {
+	simpleFilter
+
+	mutex    sync.Mutex
+	size     int
+	messages []stampedMessage
+}
+
+// NewCache creates a cache of up to size messages.
+func NewCache(size int) *Cache {
+	c := &Cache{}
+	c.SetCacheSize(size)
+	return c
+}
+
+func (c *Cache) ConnectInput(source Source) {
+	c.connectInput(source, c.Add)
+}
+
+// SetCacheSize changes the number of messages the cache keeps, dropping the oldest ones
+// if it keeps fewer. A size below one is one.
+func (c *Cache) SetCacheSize(size int) {
+	if size < 1 {
+		size = 1
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.size = size
+	c.trim()
+}
+
+// Add adds a message to the cache, dropping the oldest message if the cache is full,
+// and passes it on.
+func (c *Cache) Add(msg ros.Message, event ros.MessageEvent) {
+	m := stampedMessage{MessageStamp(msg, event), msg, event}
+	c.mutex.Lock()
+	// Messages usually arrive in order, so search from the back.
+	i := len(c.messages)
+	for i > 0 && c.messages[i-1].stamp.Cmp(m.stamp) > 0 {
+		i--
+	}
+	c.messages = append(c.messages, stampedMessage{})
+	copy(c.messages[i+1:], c.messages[i:])
+	c.messages[i] = m
+	c.trim()
+	c.mutex.Unlock()
+	c.pass(msg, event)
+}
+
+// trim drops the oldest messages above the size. The caller must hold the mutex.
+func (c *Cache) trim() {
+	if excess := len(c.messages) - c.size; excess > 0 {
+		c.messages = append(c.messages[:0:0], c.messages[excess:]...)
+	}
+}
+
+// Interval returns the messages stamped between start and end inclusive, oldest first.
+func (c *Cache) Interval(start, end ros.Time) []ros.Message {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	first := c.search(start)
+	last := sort.Search(len(c.messages), func(i int) bool { return c.messages[i].stamp.Cmp(end) > 0 })
+	return c.slice(first, last)
+}
+
+// SurroundingInterval returns the messages of Interval and also the latest message
+// before start and the earliest message after end, if there are any.
+func (c *Cache) SurroundingInterval(start, end ros.Time) []ros.Message {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	first := sort.Search(len(c.messages), func(i int) bool { return c.messages[i].stamp.Cmp(start) > 0 })
+	if first > 0 {
+		first--
+	}
+	last := c.search(end)
+	if last < len(c.messages) {
+		last++
+	}
+	return c.slice(first, last)
+}
+
+// ElemBeforeTime returns the latest message stamped at or before t.
+func (c *Cache) ElemBeforeTime(t ros.Time) (ros.Message, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	i := sort.Search(len(c.messages), func(i int) bool { return c.messages[i].stamp.Cmp(t) > 0 })
+	if i == 0 {
+		return nil, false
+	}
+	return c.messages[i-1].msg, true
+}
+
+// ElemAfterTime returns the earliest message stamped at or after t.
+func (c *Cache) ElemAfterTime(t ros.Time) (ros.Message, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	i := c.search(t)
+	if i == len(c.messages) {
+		return nil, false
+	}
+	return c.messages[i].msg, true
+}
+
+// OldestTime returns the stamp of the oldest message, or zero if the cache is empty.
+func (c *Cache) OldestTime() ros.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.messages) == 0 {
+		return ros.Time{}
+	}
+	return c.messages[0].stamp
+}
+
+// LatestTime returns the stamp of the latest message, or zero if the cache is empty.
+func (c *Cache) LatestTime() ros.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.messages) == 0 {
+		return ros.Time{}
+	}
+	return c.messages[len(c.messages)-1].stamp
+}
+
+// search returns the index of the first message stamped at or after t. The caller must
+// hold the mutex.
+func (c *Cache) search(t ros.Time) int {
+	return sort.Search(len(c.messages), func(i int) bool { return c.messages[i].stamp.Cmp(t) >= 0 })
+}
+
+// slice returns the messages from index first up to last. The caller must hold the mutex.
+func (c *Cache) slice(first, last int) []ros.Message {
+	var messages []ros.Message
+	for i := first; i < last; i++ {
+		messages = append(messages, c.messages[i].msg)
+	}
+	return messages
+}
@@ -0,0 +1,63 @@
+package message_filters
+
+import "github.com/fetchrobotics/rosgo/ros"
+
+// The functions of this file adapt callbacks of typed messages to synchronizer callbacks,
+// e.g. sync.RegisterCallback(Callback2(func(image *sensor_msgs.Image, info *sensor_msgs.CameraInfo) {...})).
+// The types must match those of the messages of the inputs in order.
+
+// Callback2 adapts a callback of two messages.
+func Callback2[M0, M1 ros.Message](callback func(M0, M1)) func([]ros.Message) {
+	return func(msgs []ros.Message) {
+		callback(msgs[0].(M0), msgs[1].(M1))
+	}
+}
+
+// Callback3 adapts a callback of three messages.
+func Callback3[M0, M1, M2 ros.Message](callback func(M0, M1, M2)) func([]ros.Message) {
+	return func(msgs []ros.Message) {
+		callback(msgs[0].(M0), msgs[1].(M1), msgs[2].(M2))
+	}
+}
+
+// Callback4 adapts a callback of four messages.
+func Callback4[M0, M1, M2, M3 ros.Message](callback func(M0, M1, M2, M3)) func([]ros.Message) {
+	return func(msgs []ros.Message) {
+		callback(msgs[0].(M0), msgs[1].(M1), msgs[2].(M2), msgs[3].(M3))
+	}
+}
+
+// Callback5 adapts a callback of five messages.
+func Callback5[M0, M1, M2, M3, M4 ros.Message](callback func(M0, M1, M2, M3, M4)) func([]ros.Message) {
+	return func(msgs []ros.Message) {
+		callback(msgs[0].(M0), msgs[1].(M1), msgs[2].(M2), msgs[3].(M3), msgs[4].(M4))
+	}
+}
+
+// Callback6 adapts a callback of six messages.
+func Callback6[M0, M1, M2, M3, M4, M5 ros.Message](callback func(M0, M1, M2, M3, M4, M5)) func([]ros.Message) {
+	return func(msgs []ros.Message) {
+		callback(msgs[0].(M0), msgs[1].(M1), msgs[2].(M2), msgs[3].(M3), msgs[4].(M4), msgs[5].(M5))
+	}
+}
+
+// Callback7 adapts a callback of seven messages.
+func Callback7[M0, M1, M2, M3, M4, M5, M6 ros.Message](callback func(M0, M1, M2, M3, M4, M5, M6)) func([]ros.Message) {
+	return func(msgs []ros.Message) {
+		callback(msgs[0].(M0), msgs[1].(M1), msgs[2].(M2), msgs[3].(M3), msgs[4].(M4), msgs[5].(M5), msgs[6].(M6))
+	}
+}
+
+// Callback8 adapts a callback of eight messages.
+func Callback8[M0, M1, M2, M3, M4, M5, M6, M7 ros.Message](callback func(M0, M1, M2, M3, M4, M5, M6, M7)) func([]ros.Message) {
+	return func(msgs []ros.Message) {
+		callback(msgs[0].(M0), msgs[1].(M1), msgs[2].(M2), msgs[3].(M3), msgs[4].(M4), msgs[5].(M5), msgs[6].(M6), msgs[7].(M7))
+	}
+}
+
+// Callback9 adapts a callback of nine messages.
+func Callback9[M0, M1, M2, M3, M4, M5, M6, M7, M8 ros.Message](callback func(M0, M1, M2, M3, M4, M5, M6, M7, M8)) func([]ros.Message) {
+	return func(msgs []ros.Message) {
+		callback(msgs[0].(M0), msgs[1].(M1), msgs[2].(M2), msgs[3].(M3), msgs[4].(M4), msgs[5].(M5), msgs[6].(M6), msgs[7].(M7), msgs[8].(M8))
+	}
+}
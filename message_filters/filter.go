@@ -0,0 +1,168 @@
+// Package message_filters filters and synchronizes the messages of subscribers, modelled
+// on the message_filters ROS package. Filters are connected into chains: a Subscriber
+// feeds the messages of a topic to filters such as a Cache or a TimeSequencer, and
+// synchronizers such as ExactTime and ApproximateTime combine the messages of several
+// chains with matching stamps.
+//
+// Messages are stamped with the Stamp of their Header field, as in generated messages
+// with a std_msgs/Header. Messages without one are stamped with their receipt time.
+//
+// Filters pass messages on in the goroutine that adds them. Chains that start at a
+// Subscriber therefore run on the node's executor, in the job of the subscriber callback,
+// like the callbacks of a TimeSequencer.
+package message_filters
+
+import (
+	"sync"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// Callback receives the messages passed on by a filter.
+type Callback func(msg ros.Message, event ros.MessageEvent)
+
+// Source passes messages on to the callbacks registered with it.
+type Source interface {
+	// RegisterCallback adds a callback that receives the messages passed on.
+	RegisterCallback(callback Callback) *Connection
+}
+
+// Filter is a source that takes its messages from another source.
+type Filter interface {
+	Source
+
+	// ConnectInput makes the filter receive the messages of source, instead of those of
+	// the source it was connected to before.
+	ConnectInput(source Source)
+}
+
+// Chain connects each filter to the one before it, the first one to input, and returns
+// the last one.
+func Chain(input Source, filters ...Filter) Source {
+	for _, filter := range filters {
+		filter.ConnectInput(input)
+		input = filter
+	}
+	return input
+}
+
+// Connection is the registration of a callback with a source.
+type Connection struct {
+	once       sync.Once
+	disconnect func()
+}
+
+// Disconnect removes the callback from its source.
+func (c *Connection) Disconnect() {
+	if c == nil {
+		return
+	}
+	c.once.Do(c.disconnect)
+}
+
+// signal holds callbacks of type F in the order they were registered.
+type signal[F any] struct {
+	mutex sync.Mutex
+	slots []*slot[F]
+}
+
+type slot[F any] struct {
+	callback F
+}
+
+func (s *signal[F]) register(callback F) *Connection {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sl := &slot[F]{callback}
+	s.slots = append(s.slots, sl)
+	return &Connection{disconnect: func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for i, other := range s.slots {
+			if other == sl {
+				s.slots = append(s.slots[:i:i], s.slots[i+1:]...)
+				return
+			}
+		}
+	}}
+}
+
+// callbacks returns the registered callbacks, which are called without holding the mutex
+// so that they can register or disconnect callbacks.
+func (s *signal[F]) callbacks() []F {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	callbacks := make([]F, len(s.slots))
+	for i, sl := range s.slots {
+		callbacks[i] = sl.callback
+	}
+	return callbacks
+}
+
+// simpleFilter implements the callbacks and the input of a filter.
+type simpleFilter struct {
+	signal signal[Callback]
+
+	inputMutex sync.Mutex
+	input      *Connection
+}
+
+func (f *simpleFilter) RegisterCallback(callback Callback) *Connection {
+	return f.signal.register(callback)
+}
+
+// connectInput connects add to source, disconnecting the previous input.
+func (f *simpleFilter) connectInput(source Source, add Callback) {
+	f.inputMutex.Lock()
+	defer f.inputMutex.Unlock()
+	f.input.Disconnect()
+	f.input = source.RegisterCallback(add)
+}
+
+// disconnectInput disconnects the input of the filter.
+func (f *simpleFilter) disconnectInput() {
+	f.inputMutex.Lock()
+	defer f.inputMutex.Unlock()
+	f.input.Disconnect()
+	f.input = nil
+}
+
+// pass passes a message on to the callbacks.
+func (f *simpleFilter) pass(msg ros.Message, event ros.MessageEvent) {
+	for _, callback := range f.signal.callbacks() {
+		callback(msg, event)
+	}
+}
+
+// PassThrough passes its messages on unchanged. It is an input for messages that do not
+// come from another source.
+type PassThrough struct {
+	simpleFilter
+}
+
+// NewPassThrough creates a pass through filter.
+func NewPassThrough() *PassThrough {
+	return &PassThrough{}
+}
+
+func (f *PassThrough) ConnectInput(source Source) {
+	f.connectInput(source, f.Add)
+}
+
+// Add passes a message on.
+func (f *PassThrough) Add(msg ros.Message, event ros.MessageEvent) {
+	f.pass(msg, event)
+}
+
+// MessageStamp returns the stamp of the Header field of msg, or the receipt time of
+// event if msg has no header.
+func MessageStamp(msg ros.Message, event ros.MessageEvent) ros.Time {
+	if _, stamp, _, ok := ros.HeaderFields(msg); ok {
+		return *stamp
+	}
+	var stamp ros.Time
+	if !event.ReceiptTime.IsZero() {
+		stamp.FromNSec(uint64(event.ReceiptTime.UnixNano()))
+	}
+	return stamp
+}
@@ -0,0 +1,156 @@
+package message_filters
+
+//go:generate gengo -out=$GOPATH/src msg std_msgs/Header
+//go:generate gengo -out=$GOPATH/src msg geometry_msgs/Point
+//go:generate gengo -out=$GOPATH/src msg geometry_msgs/PointStamped
+
+import (
+	"geometry_msgs"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+	"github.com/fetchrobotics/rosgo/rostest"
+)
+
+// msgAt returns a point stamped sec seconds after the epoch whose X is v, which
+// identifies the message in the tests.
+func msgAt(sec uint32, v int32) *geometry_msgs.PointStamped {
+	msg := &geometry_msgs.PointStamped{}
+	msg.Header.Stamp = ros.NewTime(sec, 0)
+	msg.Point.X = float64(v)
+	return msg
+}
+
+// values returns the values of msgs.
+func values(msgs []ros.Message) []int32 {
+	var v []int32
+	for _, msg := range msgs {
+		v = append(v, value(msg))
+	}
+	return v
+}
+
+// value returns the value of msg as set by msgAt.
+func value(msg ros.Message) int32 {
+	return int32(msg.(*geometry_msgs.PointStamped).Point.X)
+}
+
+func TestMessageStamp(t *testing.T) {
+	if stamp := MessageStamp(msgAt(5, 0), ros.MessageEvent{}); stamp != ros.NewTime(5, 0) {
+		t.Errorf("expected the header stamp but got %v", stamp)
+	}
+	event := ros.MessageEvent{ReceiptTime: time.Unix(7, 500)}
+	if stamp := MessageStamp(&geometry_msgs.Point{}, event); stamp != ros.NewTime(7, 500) {
+		t.Errorf("expected the receipt time but got %v", stamp)
+	}
+}
+
+func TestCache(t *testing.T) {
+	input := NewPassThrough()
+	cache := NewCache(4)
+	var passed []int32
+	Chain(input, cache).RegisterCallback(func(msg ros.Message, event ros.MessageEvent) {
+		passed = append(passed, value(msg))
+	})
+	for _, sec := range []uint32{3, 1, 4, 2, 6, 5} {
+		input.Add(msgAt(sec, int32(sec)), ros.MessageEvent{})
+	}
+	if !reflect.DeepEqual(passed, []int32{3, 1, 4, 2, 6, 5}) {
+		t.Errorf("expected every message to be passed on but got %v", passed)
+	}
+	if cache.OldestTime() != ros.NewTime(3, 0) || cache.LatestTime() != ros.NewTime(6, 0) {
+		t.Errorf("expected the two oldest messages to be dropped but got %v to %v", cache.OldestTime(), cache.LatestTime())
+	}
+
+	if v := values(cache.Interval(ros.NewTime(4, 0), ros.NewTime(5, 500))); !reflect.DeepEqual(v, []int32{4, 5}) {
+		t.Errorf("unexpected interval %v", v)
+	}
+	if v := values(cache.SurroundingInterval(ros.NewTime(4, 500), ros.NewTime(5, 500))); !reflect.DeepEqual(v, []int32{4, 5, 6}) {
+		t.Errorf("unexpected surrounding interval %v", v)
+	}
+	if v := values(cache.SurroundingInterval(ros.NewTime(1, 0), ros.NewTime(9, 0))); !reflect.DeepEqual(v, []int32{3, 4, 5, 6}) {
+		t.Errorf("unexpected surrounding interval %v", v)
+	}
+	if msg, ok := cache.ElemBeforeTime(ros.NewTime(4, 500)); !ok || value(msg) != 4 {
+		t.Errorf("unexpected element before %v", msg)
+	}
+	if msg, ok := cache.ElemAfterTime(ros.NewTime(4, 500)); !ok || value(msg) != 5 {
+		t.Errorf("unexpected element after %v", msg)
+	}
+	if _, ok := cache.ElemBeforeTime(ros.NewTime(2, 0)); ok {
+		t.Error("expected no element before the oldest one")
+	}
+
+	cache.SetCacheSize(1)
+	if cache.OldestTime() != ros.NewTime(6, 0) {
+		t.Errorf("expected only the latest message to be kept but got %v", cache.OldestTime())
+	}
+}
+
+func TestTimeSequencer(t *testing.T) {
+	node := rostest.NewNode("/sequencer")
+	defer node.Shutdown()
+	node.Executor().Advance(10 * time.Second)
+	input := NewPassThrough()
+	sequencer := NewTimeSequencer(node, 2*time.Second, 100*time.Millisecond, 3)
+	sequencer.now = node.Executor().Clock().Now
+	defer sequencer.Shutdown()
+	var passed []int32
+	Chain(input, sequencer).RegisterCallback(func(msg ros.Message, event ros.MessageEvent) {
+		passed = append(passed, value(msg))
+	})
+
+	for _, sec := range []uint32{9, 7, 8, 10} {
+		input.Add(msgAt(sec, int32(sec)), ros.MessageEvent{})
+	}
+	node.Executor().Advance(100 * time.Millisecond)
+	if !reflect.DeepEqual(passed, []int32{8}) {
+		t.Errorf("expected the messages at least two seconds old after dropping the oldest but got %v", passed)
+	}
+	input.Add(msgAt(6, 6), ros.MessageEvent{})
+	node.Executor().Advance(2 * time.Second)
+	if !reflect.DeepEqual(passed, []int32{8, 9, 10}) {
+		t.Errorf("expected the messages in order without late ones but got %v", passed)
+	}
+}
+
+func TestSubscriber(t *testing.T) {
+	node := rostest.NewNode("/camera_fusion")
+	defer node.Shutdown()
+	left := NewSubscriber(node, "left", geometry_msgs.MsgPointStamped)
+	defer left.Shutdown()
+	right := NewSubscriber(node, "right", geometry_msgs.MsgPointStamped)
+	defer right.Shutdown()
+	leftCache := NewCache(10)
+	leftCache.ConnectInput(left)
+
+	var pairs [][2]int32
+	sync := NewExactTime(node, 10, leftCache, right)
+	defer sync.Shutdown()
+	sync.RegisterCallback(Callback2(func(l, r *geometry_msgs.PointStamped) {
+		pairs = append(pairs, [2]int32{int32(l.Point.X), int32(r.Point.X)})
+	}))
+
+	node.Inject("left", msgAt(1, 10))
+	node.Inject("right", msgAt(1, 20))
+	if len(pairs) != 0 {
+		t.Error("expected the messages to be delivered on the executor")
+	}
+	node.Executor().RunPending()
+	if !reflect.DeepEqual(pairs, [][2]int32{{10, 20}}) {
+		t.Errorf("unexpected pairs %v", pairs)
+	}
+	if leftCache.LatestTime() != ros.NewTime(1, 0) {
+		t.Errorf("expected the message in the cache but got %v", leftCache.LatestTime())
+	}
+
+	sync.Shutdown()
+	node.Inject("left", msgAt(2, 11))
+	node.Inject("right", msgAt(2, 21))
+	node.Executor().RunPending()
+	if len(pairs) != 1 {
+		t.Errorf("expected no pairs after shutdown but got %v", pairs)
+	}
+}
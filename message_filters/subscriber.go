@@ -0,0 +1,35 @@
+package message_filters
+
+import "github.com/fetchrobotics/rosgo/ros"
+
+// Subscriber is the source of a chain of filters. It subscribes to a topic with
+// Node.NewSubscriber and passes its messages on in the subscriber callback, which runs
+// on the node's executor.
+type Subscriber struct {
+	simpleFilter
+	sub ros.Subscriber
+}
+
+// NewSubscriber subscribes to topic on node.
+func NewSubscriber(node ros.Node, topic string, msgType ros.MessageType, opts ...ros.SubscriberOption) *Subscriber {
+	s := &Subscriber{}
+	s.sub = node.NewSubscriber(topic, msgType, func(msg ros.Message, event ros.MessageEvent) {
+		s.pass(msg, event)
+	}, opts...)
+	return s
+}
+
+// Add passes a message on as if it had been received.
+func (s *Subscriber) Add(msg ros.Message, event ros.MessageEvent) {
+	s.pass(msg, event)
+}
+
+// Subscriber returns the subscriber to the topic.
+func (s *Subscriber) Subscriber() ros.Subscriber {
+	return s.sub
+}
+
+// Shutdown unsubscribes from the topic.
+func (s *Subscriber) Shutdown() {
+	s.sub.Shutdown()
+}
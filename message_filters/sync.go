@@ -0,0 +1,153 @@
+package message_filters
+
+import (
+	"sync"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+const (
+	minInputs = 2
+	maxInputs = 9
+)
+
+// policy decides which messages of the inputs of a synchronizer belong together.
+type policy interface {
+	// add adds a message of input i and returns the sets of messages it completes,
+	// with one message of each input.
+	add(i int, m stampedMessage) [][]stampedMessage
+}
+
+// Synchronizer combines the messages of 2 to 9 inputs into sets of one message of each
+// input, and passes each set on to its callbacks in the order of the inputs. Use one of
+// Callback2 to Callback9 to receive the messages with their types.
+type Synchronizer struct {
+	node   ros.Node
+	signal signal[func([]ros.Message)]
+
+	// size is the number of inputs, or zero if the synchronizer was given too few
+	// or too many.
+	size int
+
+	mutex  sync.Mutex
+	policy policy
+	inputs []*Connection
+}
+
+func newSynchronizer(node ros.Node, p policy, inputs []Source) *Synchronizer {
+	s := &Synchronizer{node: node, policy: p}
+	if len(inputs) < minInputs || len(inputs) > maxInputs {
+		node.Logger().Errorf("message_filters: a synchronizer needs %d to %d inputs but got %d", minInputs, maxInputs, len(inputs))
+		return s
+	}
+	s.size = len(inputs)
+	for i, input := range inputs {
+		i := i
+		s.inputs = append(s.inputs, input.RegisterCallback(func(msg ros.Message, event ros.MessageEvent) {
+			s.Add(i, msg, event)
+		}))
+	}
+	return s
+}
+
+// RegisterCallback adds a callback that receives the sets of messages.
+func (s *Synchronizer) RegisterCallback(callback func(msgs []ros.Message)) *Connection {
+	return s.signal.register(callback)
+}
+
+// Add adds a message of input i, as if the input had passed it on.
+func (s *Synchronizer) Add(i int, msg ros.Message, event ros.MessageEvent) {
+	if i < 0 || i >= s.size {
+		s.node.Logger().Errorf("message_filters: input %d of a synchronizer of %d inputs", i, s.size)
+		return
+	}
+	s.mutex.Lock()
+	sets := s.policy.add(i, stampedMessage{MessageStamp(msg, event), msg, event})
+	s.mutex.Unlock()
+	for _, set := range sets {
+		msgs := make([]ros.Message, len(set))
+		for j, m := range set {
+			msgs[j] = m.msg
+		}
+		for _, callback := range s.signal.callbacks() {
+			callback(msgs)
+		}
+	}
+}
+
+// Shutdown disconnects the inputs.
+func (s *Synchronizer) Shutdown() {
+	for _, input := range s.inputs {
+		input.Disconnect()
+	}
+}
+
+// ExactTime synchronizes messages with the same stamp.
+type ExactTime struct {
+	*Synchronizer
+}
+
+// NewExactTime creates a synchronizer of inputs that passes on sets of messages with
+// the same stamp. It holds messages of up to queueSize stamps; once a set is passed on,
+// the incomplete sets of earlier stamps are dropped.
+func NewExactTime(node ros.Node, queueSize int, inputs ...Source) *ExactTime {
+	p := &exactTimePolicy{inputs: len(inputs), queueSize: queueSize, sets: make(map[ros.Time][]*stampedMessage)}
+	return &ExactTime{newSynchronizer(node, p, inputs)}
+}
+
+// exactTimePolicy is the ExactTime policy of message_filters.
+type exactTimePolicy struct {
+	inputs    int
+	queueSize int
+
+	// sets holds the messages of each stamp by input, and stamps the stamps in order.
+	sets   map[ros.Time][]*stampedMessage
+	stamps []ros.Time
+}
+
+func (p *exactTimePolicy) add(i int, m stampedMessage) [][]stampedMessage {
+	set, ok := p.sets[m.stamp]
+	if !ok {
+		set = make([]*stampedMessage, p.inputs)
+		p.sets[m.stamp] = set
+		j := len(p.stamps)
+		for j > 0 && p.stamps[j-1].Cmp(m.stamp) > 0 {
+			j--
+		}
+		p.stamps = append(p.stamps, ros.Time{})
+		copy(p.stamps[j+1:], p.stamps[j:])
+		p.stamps[j] = m.stamp
+	}
+	set[i] = &m
+	p.trim()
+	if _, ok := p.sets[m.stamp]; !ok {
+		return nil
+	}
+	complete := make([]stampedMessage, p.inputs)
+	for j, m := range set {
+		if m == nil {
+			return nil
+		}
+		complete[j] = *m
+	}
+
+	// Drop the set with all earlier ones, which can no longer be completed in order.
+	j := 0
+	for j < len(p.stamps) && p.stamps[j].Cmp(m.stamp) <= 0 {
+		delete(p.sets, p.stamps[j])
+		j++
+	}
+	p.stamps = p.stamps[j:]
+	return [][]stampedMessage{complete}
+}
+
+// trim drops the oldest sets above the queue size.
+func (p *exactTimePolicy) trim() {
+	if p.queueSize <= 0 {
+		return
+	}
+	for len(p.stamps) > p.queueSize {
+		delete(p.sets, p.stamps[0])
+		p.stamps = p.stamps[1:]
+	}
+}
@@ -0,0 +1,156 @@
+package message_filters
+
+import (
+	"geometry_msgs"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+	"github.com/fetchrobotics/rosgo/rostest"
+)
+
+// input is a message of a synchronizer input in a test.
+type input struct {
+	index int
+	sec   uint32
+}
+
+// synchronize adds the messages of inputs to sync and returns the stamps of the sets
+// it passes on. The value of each message is its stamp.
+func synchronize(sync *Synchronizer, inputs []input) [][]int32 {
+	var sets [][]int32
+	sync.RegisterCallback(func(msgs []ros.Message) {
+		sets = append(sets, values(msgs))
+	})
+	for _, in := range inputs {
+		sync.Add(in.index, msgAt(in.sec, int32(in.sec)), ros.MessageEvent{})
+	}
+	return sets
+}
+
+func TestExactTime(t *testing.T) {
+	node := rostest.NewNode("/sync")
+	defer node.Shutdown()
+	a, b, c := NewPassThrough(), NewPassThrough(), NewPassThrough()
+	sync := NewExactTime(node, 2, a, b, c)
+	var triples [][3]int32
+	sync.RegisterCallback(Callback3(func(a, b, c *geometry_msgs.PointStamped) {
+		triples = append(triples, [3]int32{value(a), value(b), value(c)})
+	}))
+
+	a.Add(msgAt(1, 1), ros.MessageEvent{})
+	b.Add(msgAt(2, 2), ros.MessageEvent{})
+	c.Add(msgAt(1, 3), ros.MessageEvent{})
+	// A third stamp drops the oldest incomplete set.
+	a.Add(msgAt(3, 4), ros.MessageEvent{})
+	b.Add(msgAt(1, 5), ros.MessageEvent{})
+	if len(triples) != 0 {
+		t.Errorf("expected the set of stamp 1 to be dropped but got %v", triples)
+	}
+	a.Add(msgAt(2, 6), ros.MessageEvent{})
+	c.Add(msgAt(2, 7), ros.MessageEvent{})
+	if !reflect.DeepEqual(triples, [][3]int32{{6, 2, 7}}) {
+		t.Errorf("unexpected sets %v", triples)
+	}
+	// The set of stamp 3 was kept.
+	b.Add(msgAt(3, 8), ros.MessageEvent{})
+	c.Add(msgAt(3, 9), ros.MessageEvent{})
+	if !reflect.DeepEqual(triples, [][3]int32{{6, 2, 7}, {4, 8, 9}}) {
+		t.Errorf("unexpected sets %v", triples)
+	}
+
+	sync.Shutdown()
+	a.Add(msgAt(4, 0), ros.MessageEvent{})
+	b.Add(msgAt(4, 0), ros.MessageEvent{})
+	c.Add(msgAt(4, 0), ros.MessageEvent{})
+	if len(triples) != 2 {
+		t.Errorf("expected no sets after shutdown but got %v", triples)
+	}
+}
+
+func TestSynchronizerInputs(t *testing.T) {
+	node := rostest.NewNode("/sync")
+	defer node.Shutdown()
+	inputs := make([]Source, 10)
+	for i := range inputs {
+		inputs[i] = NewPassThrough()
+	}
+	sync := NewExactTime(node, 10, inputs[:9]...)
+	sets := synchronize(sync.Synchronizer, []input{{0, 1}, {1, 1}, {2, 1}, {3, 1}, {4, 1}, {5, 1}, {6, 1}, {7, 1}, {8, 1}})
+	if !reflect.DeepEqual(sets, [][]int32{{1, 1, 1, 1, 1, 1, 1, 1, 1}}) {
+		t.Errorf("expected a set of nine messages but got %v", sets)
+	}
+	for _, n := range []int{1, 10} {
+		sync := NewExactTime(node, 10, inputs[:n]...)
+		if sets := synchronize(sync.Synchronizer, []input{{0, 1}, {0, 1}}); sets != nil {
+			t.Errorf("expected a synchronizer of %d inputs to be rejected but got %v", n, sets)
+		}
+	}
+}
+
+func TestApproximateTime(t *testing.T) {
+	// The first three cases are those of the tests of message_filters.
+	for _, test := range []struct {
+		name     string
+		inputs   []input
+		expected [][]int32
+	}{
+		{
+			// a..a..a
+			// b..b..b
+			"exact match",
+			[]input{{0, 0}, {1, 0}, {0, 3}, {1, 3}, {0, 6}, {1, 6}},
+			[][]int32{{0, 0}, {3, 3}, {6, 6}},
+		},
+		{
+			// a..a..a.
+			// .b..b..b
+			"perfect match",
+			[]input{{0, 0}, {1, 1}, {0, 3}, {1, 4}, {0, 6}, {1, 7}},
+			[][]int32{{0, 1}, {3, 4}},
+		},
+		{
+			// a.xa.a.a
+			// .b..b.b.
+			"imperfect match",
+			[]input{{0, 0}, {1, 1}, {0, 2}, {0, 3}, {1, 4}, {0, 5}, {1, 6}, {0, 7}},
+			[][]int32{{0, 1}, {3, 4}, {5, 6}},
+		},
+		{
+			// a..a...a..a
+			// .b.........b
+			// The second b is closer to the last a than any other.
+			"distant match",
+			[]input{{0, 0}, {1, 1}, {0, 3}, {0, 7}, {0, 10}, {1, 11}},
+			[][]int32{{0, 1}},
+		},
+	} {
+		node := rostest.NewNode("/sync")
+		sync := NewApproximateTime(node, 10, NewPassThrough(), NewPassThrough())
+		if sets := synchronize(sync.Synchronizer, test.inputs); !reflect.DeepEqual(sets, test.expected) {
+			t.Errorf("%s: expected %v but got %v", test.name, test.expected, sets)
+		}
+		node.Shutdown()
+	}
+}
+
+func TestApproximateTimeBounds(t *testing.T) {
+	node := rostest.NewNode("/sync")
+	defer node.Shutdown()
+
+	// Messages of b are at least three seconds apart, so the set of a at 0 and b at 1 is
+	// passed on once the next a arrives.
+	sync := NewApproximateTime(node, 10, NewPassThrough(), NewPassThrough())
+	sync.SetInterMessageLowerBound(1, 3*time.Second)
+	if sets := synchronize(sync.Synchronizer, []input{{0, 0}, {1, 1}, {0, 3}}); !reflect.DeepEqual(sets, [][]int32{{0, 1}}) {
+		t.Errorf("expected the set to be passed on early but got %v", sets)
+	}
+
+	// A set whose stamps are further apart than the maximum is not passed on.
+	sync = NewApproximateTime(node, 10, NewPassThrough(), NewPassThrough())
+	sync.SetMaxIntervalDuration(time.Second)
+	if sets := synchronize(sync.Synchronizer, []input{{0, 0}, {1, 5}, {0, 6}, {1, 8}, {0, 12}}); !reflect.DeepEqual(sets, [][]int32{{6, 5}}) {
+		t.Errorf("expected only the close set but got %v", sets)
+	}
+}
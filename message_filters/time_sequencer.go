@@ -0,0 +1,85 @@
+package message_filters
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// TimeSequencer passes messages on in the order of their stamps, each once it is delay
+// old. Messages stamped before the last message passed on are dropped. The sequencer
+// checks for messages to pass on every update period with a timer of the node, so its
+// callbacks run on the node's executor.
+type TimeSequencer struct {
+	simpleFilter
+	delay     time.Duration
+	queueSize int
+	now       func() time.Time
+
+	mutex    sync.Mutex
+	messages []stampedMessage
+	last     ros.Time
+	timer    ros.Timer
+}
+
+// NewTimeSequencer creates a time sequencer that holds up to queueSize messages, or any
+// number if queueSize is zero.
+func NewTimeSequencer(node ros.Node, delay time.Duration, updatePeriod time.Duration, queueSize int) *TimeSequencer {
+	s := &TimeSequencer{delay: delay, queueSize: queueSize, now: time.Now}
+	s.timer = node.NewTimer(updatePeriod, func(ros.TimerEvent) { s.dispatch() })
+	return s
+}
+
+func (s *TimeSequencer) ConnectInput(source Source) {
+	s.connectInput(source, s.Add)
+}
+
+// Add queues a message, dropping the oldest one if the queue is full.
+func (s *TimeSequencer) Add(msg ros.Message, event ros.MessageEvent) {
+	m := stampedMessage{MessageStamp(msg, event), msg, event}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if m.stamp.Cmp(s.last) < 0 {
+		return
+	}
+	i := len(s.messages)
+	for i > 0 && s.messages[i-1].stamp.Cmp(m.stamp) > 0 {
+		i--
+	}
+	s.messages = append(s.messages, stampedMessage{})
+	copy(s.messages[i+1:], s.messages[i:])
+	s.messages[i] = m
+	if s.queueSize > 0 && len(s.messages) > s.queueSize {
+		s.messages = s.messages[1:]
+	}
+}
+
+// dispatch passes on the messages that are old enough.
+func (s *TimeSequencer) dispatch() {
+	var due ros.Time
+	due.FromNSec(uint64(s.now().Add(-s.delay).UnixNano()))
+	s.mutex.Lock()
+	i := 0
+	for i < len(s.messages) && s.messages[i].stamp.Cmp(due) <= 0 {
+		i++
+	}
+	ready := s.messages[:i]
+	s.messages = append([]stampedMessage(nil), s.messages[i:]...)
+	if i > 0 {
+		s.last = ready[i-1].stamp
+	}
+	s.mutex.Unlock()
+	for _, m := range ready {
+		s.pass(m.msg, m.event)
+	}
+}
+
+// Shutdown stops the timer and disconnects the input. Queued messages are dropped.
+func (s *TimeSequencer) Shutdown() {
+	s.timer.Stop()
+	s.disconnectInput()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.messages = nil
+}
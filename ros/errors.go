@@ -0,0 +1,49 @@
+package ros
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SizeLimitError is returned when a remote peer announces a connection header or
+// a message that is larger than the node accepts. The connection is closed without
+// allocating a buffer for the announced size.
+type SizeLimitError struct {
+	// Kind is either "header" or "message".
+	Kind  string
+	Size  uint32
+	Limit int
+}
+
+func (e *SizeLimitError) Error() string {
+	return fmt.Sprintf("%s of %d bytes exceeds the limit of %d bytes", e.Kind, e.Size, e.Limit)
+}
+
+// MalformedHeaderError is returned when a connection header received from a remote
+// peer cannot be parsed.
+type MalformedHeaderError struct {
+	Reason string
+}
+
+func (e *MalformedHeaderError) Error() string {
+	return fmt.Sprintf("malformed connection header: %s", e.Reason)
+}
+
+// MissingParamError is returned by LoadParams when required parameters are not set.
+type MissingParamError struct {
+	// Names are the full names of the missing parameters.
+	Names []string
+}
+
+func (e *MissingParamError) Error() string {
+	return fmt.Sprintf("missing required parameters: %s", strings.Join(e.Names, ", "))
+}
+
+// InvalidNameError is returned when a name is not a valid ROS graph resource name.
+type InvalidNameError struct {
+	Name string
+}
+
+func (e *InvalidNameError) Error() string {
+	return fmt.Sprintf("invalid ROS name %q", e.Name)
+}
@@ -0,0 +1,292 @@
+package ros
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+const defaultJobQueueSize = 100
+
+// Job is a callback queued on an executor by a subscriber, service server or timer.
+type Job struct {
+	// Name tells what the job belongs to, e.g. "subscriber /chatter".
+	Name string
+
+	// Run executes the callback.
+	Run func()
+}
+
+// Executor runs the callback jobs of a node. Node.Spin and Node.SpinOnce delegate to the
+// executor, and timers are scheduled against its clock. The default executor runs jobs on
+// whichever goroutine calls Spin; tests can replace it with a ManualExecutor using WithExecutor.
+type Executor interface {
+	// Post queues a job. It may block while the executor's queue is full.
+	Post(job Job)
+
+	// SpinOnce runs jobs that are ready to run.
+	SpinOnce()
+
+	// Spin runs jobs until ok returns false.
+	Spin(ok func() bool)
+
+	// Clock returns the clock timers are scheduled against.
+	Clock() Clock
+}
+
+// Clock is a source of time that can schedule functions.
+type Clock interface {
+	// Now returns the current time of the clock.
+	Now() time.Time
+
+	// AfterFunc calls f in its own goroutine, or synchronously for a manual clock,
+	// once d has elapsed on the clock.
+	AfterFunc(d time.Duration, f func()) ClockTimer
+}
+
+// ClockTimer is a function scheduled with Clock.AfterFunc.
+type ClockTimer interface {
+	// Stop prevents the function from being called. It returns false if the
+	// function was already called or stopped.
+	Stop() bool
+}
+
+// systemClock is the wall clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	return time.AfterFunc(d, f)
+}
+
+// queueExecutor is the default executor. Jobs are buffered in a bounded channel and
+// run by the goroutine calling Spin or SpinOnce.
+type queueExecutor struct {
+	jobChan chan Job
+	logger  Logger
+}
+
+func newQueueExecutor(logger Logger) *queueExecutor {
+	return &queueExecutor{jobChan: make(chan Job, defaultJobQueueSize), logger: logger}
+}
+
+func (e *queueExecutor) Post(job Job) {
+	e.jobChan <- job
+}
+
+// SpinOnce runs one job, waiting at most 10 milliseconds for it.
+func (e *queueExecutor) SpinOnce() {
+	timeoutChan := time.After(10 * time.Millisecond)
+	select {
+	case job := <-e.jobChan:
+		job.Run()
+	case <-timeoutChan:
+		break
+	}
+}
+
+func (e *queueExecutor) Spin(ok func() bool) {
+	for ok() {
+		timeoutChan := time.After(1000 * time.Millisecond)
+		select {
+		case job := <-e.jobChan:
+			e.logger.Debugf("Execute job %s", job.Name)
+			job.Run()
+		case <-timeoutChan:
+			break
+		}
+	}
+}
+
+func (e *queueExecutor) Clock() Clock {
+	return systemClock{}
+}
+
+// ManualExecutor is an executor for tests that runs jobs only when asked to and
+// schedules timers against a ManualClock. Nothing runs in the background, so the
+// order in which callbacks run is deterministic.
+type ManualExecutor struct {
+	mutex  sync.Mutex
+	jobs   []Job
+	notify chan struct{}
+	clock  *ManualClock
+}
+
+// NewManualExecutor creates a ManualExecutor whose clock starts at start.
+func NewManualExecutor(start time.Time) *ManualExecutor {
+	return &ManualExecutor{
+		notify: make(chan struct{}, 1),
+		clock:  NewManualClock(start),
+	}
+}
+
+// Post queues a job. It never blocks.
+func (e *ManualExecutor) Post(job Job) {
+	e.mutex.Lock()
+	e.jobs = append(e.jobs, job)
+	e.mutex.Unlock()
+	select {
+	case e.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Pending returns the names of the queued jobs in the order they will run.
+func (e *ManualExecutor) Pending() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	names := make([]string, len(e.jobs))
+	for i, job := range e.jobs {
+		names[i] = job.Name
+	}
+	return names
+}
+
+// RunPending runs exactly the jobs that are queued when it is called and returns their
+// names in the order they ran. Jobs queued by those jobs are left for the next call.
+func (e *ManualExecutor) RunPending() []string {
+	e.mutex.Lock()
+	jobs := e.jobs
+	e.jobs = nil
+	e.mutex.Unlock()
+
+	names := make([]string, len(jobs))
+	for i, job := range jobs {
+		job.Run()
+		names[i] = job.Name
+	}
+	return names
+}
+
+// Advance moves the clock forward by d, firing the timers that fall due in order,
+// and then runs the pending jobs. It returns the names of the jobs that ran.
+func (e *ManualExecutor) Advance(d time.Duration) []string {
+	e.clock.Advance(d)
+	return e.RunPending()
+}
+
+// SpinOnce runs the pending jobs.
+func (e *ManualExecutor) SpinOnce() {
+	e.RunPending()
+}
+
+// Spin runs jobs as they are posted until ok returns false. The clock does not advance.
+func (e *ManualExecutor) Spin(ok func() bool) {
+	for ok() {
+		if len(e.RunPending()) > 0 {
+			continue
+		}
+		select {
+		case <-e.notify:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Clock returns the executor's ManualClock.
+func (e *ManualExecutor) Clock() Clock {
+	return e.clock
+}
+
+// ManualClock is a Clock that only moves when Advance is called.
+type ManualClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers manualTimerHeap
+	seq    uint64
+}
+
+// NewManualClock creates a ManualClock set to start.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the current time of the clock.
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// AfterFunc schedules f to be called by Advance once d has elapsed.
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &manualTimer{clock: c, when: c.now.Add(d), seq: c.seq, f: f, index: -1}
+	c.seq++
+	heap.Push(&c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d. Functions that fall due are called synchronously
+// in the order of their due time, with the clock set to that time. Functions scheduled
+// by them are called too if they fall due before the new time.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	end := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].when.After(end) {
+		t := heap.Pop(&c.timers).(*manualTimer)
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+		c.mutex.Unlock()
+		t.f()
+		c.mutex.Lock()
+	}
+	c.now = end
+	c.mutex.Unlock()
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	when  time.Time
+	seq   uint64
+	f     func()
+	index int
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	if t.index < 0 {
+		return false
+	}
+	heap.Remove(&t.clock.timers, t.index)
+	return true
+}
+
+// manualTimerHeap orders timers by due time, then by creation.
+type manualTimerHeap []*manualTimer
+
+func (h manualTimerHeap) Len() int { return len(h) }
+
+func (h manualTimerHeap) Less(i, j int) bool {
+	if h[i].when.Equal(h[j].when) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].when.Before(h[j].when)
+}
+
+func (h manualTimerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *manualTimerHeap) Push(x interface{}) {
+	t := x.(*manualTimer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *manualTimerHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,186 @@
+package ros
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewManualClock(start)
+
+	var fired []string
+	var firedAt []time.Time
+	schedule := func(name string, d time.Duration) ClockTimer {
+		return clock.AfterFunc(d, func() {
+			fired = append(fired, name)
+			firedAt = append(firedAt, clock.Now())
+		})
+	}
+	schedule("c", 3*time.Second)
+	schedule("a", time.Second)
+	schedule("b", 2*time.Second)
+	schedule("a2", time.Second)
+	stopped := schedule("stopped", time.Second)
+	if !stopped.Stop() {
+		t.Error("Expected Stop to cancel a pending function")
+	}
+	if stopped.Stop() {
+		t.Error("Expected a second Stop to return false")
+	}
+
+	clock.Advance(2500 * time.Millisecond)
+	if !reflect.DeepEqual(fired, []string{"a", "a2", "b"}) {
+		t.Errorf("Unexpected firing order %v", fired)
+	}
+	if !firedAt[2].Equal(start.Add(2 * time.Second)) {
+		t.Errorf("Expected the clock to be at the due time while firing but got %v", firedAt[2])
+	}
+	if !clock.Now().Equal(start.Add(2500 * time.Millisecond)) {
+		t.Errorf("Unexpected clock time %v", clock.Now())
+	}
+
+	clock.Advance(time.Second)
+	if len(fired) != 4 || fired[3] != "c" {
+		t.Errorf("Expected c to fire but got %v", fired)
+	}
+}
+
+func TestManualExecutorRunsExactlyPending(t *testing.T) {
+	executor := NewManualExecutor(time.Unix(0, 0))
+	executor.Post(Job{Name: "first", Run: func() {
+		executor.Post(Job{Name: "second", Run: func() {}})
+	}})
+
+	if ran := executor.RunPending(); !reflect.DeepEqual(ran, []string{"first"}) {
+		t.Errorf("Expected only the first job to run but got %v", ran)
+	}
+	if pending := executor.Pending(); !reflect.DeepEqual(pending, []string{"second"}) {
+		t.Errorf("Expected the second job to be pending but got %v", pending)
+	}
+	if ran := executor.RunPending(); !reflect.DeepEqual(ran, []string{"second"}) {
+		t.Errorf("Expected the second job to run but got %v", ran)
+	}
+	if ran := executor.RunPending(); len(ran) != 0 {
+		t.Errorf("Expected no job to run but got %v", ran)
+	}
+}
+
+func TestTimerOnManualExecutor(t *testing.T) {
+	start := time.Unix(1000, 0)
+	executor := NewManualExecutor(start)
+
+	var events []TimerEvent
+	timer := newDefaultTimer(executor, 100*time.Millisecond, func(e TimerEvent) {
+		events = append(events, e)
+	})
+	timer.Start()
+
+	if ran := executor.Advance(50 * time.Millisecond); len(ran) != 0 {
+		t.Errorf("Timer must not fire early: %v", ran)
+	}
+	for i := 0; i < 2; i++ {
+		if ran := executor.Advance(100 * time.Millisecond); !reflect.DeepEqual(ran, []string{"timer 100ms"}) {
+			t.Fatalf("Expected a timer callback but got %v", ran)
+		}
+	}
+	if !events[0].CurrentExpected.Equal(start.Add(100*time.Millisecond)) ||
+		!events[1].CurrentExpected.Equal(start.Add(200*time.Millisecond)) {
+		t.Errorf("Unexpected expected times %v %v", events[0].CurrentExpected, events[1].CurrentExpected)
+	}
+	if !events[1].LastExpected.Equal(events[0].CurrentExpected) {
+		t.Errorf("Expected LastExpected to be the previous expected time")
+	}
+	// Callbacks ran after Advance returned from the clock, so they see the new time.
+	if !events[1].CurrentReal.Equal(start.Add(250 * time.Millisecond)) {
+		t.Errorf("Unexpected real time %v", events[1].CurrentReal)
+	}
+
+	timer.Stop()
+	if ran := executor.Advance(time.Second); len(ran) != 0 {
+		t.Errorf("Stopped timer must not fire: %v", ran)
+	}
+}
+
+func TestTimerSkipsPeriodsWhileQueued(t *testing.T) {
+	start := time.Unix(1000, 0)
+	executor := NewManualExecutor(start)
+
+	var events []TimerEvent
+	timer := newDefaultTimer(executor, 100*time.Millisecond, func(e TimerEvent) {
+		events = append(events, e)
+	})
+	timer.Start()
+	defer timer.Stop()
+
+	executor.Clock().(*ManualClock).Advance(350 * time.Millisecond)
+	if pending := executor.Pending(); !reflect.DeepEqual(pending, []string{"timer 100ms"}) {
+		t.Fatalf("Expected one queued timer callback but got %v", pending)
+	}
+	executor.RunPending()
+	if ran := executor.Advance(50 * time.Millisecond); !reflect.DeepEqual(ran, []string{"timer 100ms"}) {
+		t.Fatalf("Expected a timer callback but got %v", ran)
+	}
+	if len(events) != 2 || !events[0].CurrentExpected.Equal(start.Add(100*time.Millisecond)) ||
+		!events[1].CurrentExpected.Equal(start.Add(400*time.Millisecond)) {
+		t.Errorf("Expected the periods in between to be skipped but got %v", events)
+	}
+}
+
+func TestTimerRejectsNonPositivePeriods(t *testing.T) {
+	executor := NewManualExecutor(time.Unix(0, 0))
+	for _, period := range []time.Duration{0, -time.Second} {
+		timer := newDefaultTimer(executor, period, func(TimerEvent) {
+			t.Errorf("Timer with period %v must not fire", period)
+		})
+		timer.Start()
+	}
+	if ran := executor.Advance(time.Second); len(ran) != 0 {
+		t.Errorf("Expected no timer callbacks but got %v", ran)
+	}
+}
+
+func TestNodeWithManualExecutor(t *testing.T) {
+	executor := NewManualExecutor(time.Unix(0, 0))
+	node, err := newDefaultNode("/test_executor_node", []string{}, WithExecutor(executor))
+	if err != nil {
+		t.Fatalf("Error starting new test node: %v", err)
+	}
+	defer node.Shutdown()
+	if node.Clock() != executor.Clock() {
+		t.Error("Expected the node to use the clock of its executor")
+	}
+
+	var ticks int
+	node.NewTimer(time.Second, func(TimerEvent) { ticks++ })
+
+	topic := "/test_executor"
+	var received []string
+	sub := newDefaultSubscriber(topic, &rawMessageType{}, func(msg *rawMessage) {
+		received = append(received, string(msg.data))
+	})
+	node.subscribers[topic] = sub
+	node.waitGroup.Add(1)
+	go sub.start(&node.waitGroup, node.qualifiedName, node.xmlrpcURI, "", node.executor, node.logger)
+	sub.msgChan <- messageEvent{bytes: []byte("hello")}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(executor.Pending()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Subscriber callback was not queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(received) != 0 {
+		t.Error("Callback must not run before the executor runs it")
+	}
+
+	ran := executor.Advance(time.Second)
+	if !reflect.DeepEqual(ran, []string{"subscriber " + topic, "timer 1s"}) {
+		t.Errorf("Unexpected jobs %v", ran)
+	}
+	if ticks != 1 || !reflect.DeepEqual(received, []string{"hello"}) {
+		t.Errorf("Unexpected callbacks: %d ticks, received %v", ticks, received)
+	}
+}
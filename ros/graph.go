@@ -0,0 +1,202 @@
+package ros
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SystemState is a snapshot of the ROS graph as reported by the master's getSystemState.
+type SystemState struct {
+	// Publishers maps each published topic to the nodes publishing it.
+	Publishers map[string][]string
+
+	// Subscribers maps each subscribed topic to the nodes subscribing to it.
+	Subscribers map[string][]string
+
+	// Services maps each service to the nodes providing it.
+	Services map[string][]string
+}
+
+// Nodes returns the sorted names of all nodes that publish, subscribe or provide a service.
+func (s *SystemState) Nodes() []string {
+	var nodes []string
+	for _, m := range []map[string][]string{s.Publishers, s.Subscribers, s.Services} {
+		for _, names := range m {
+			nodes = setUnion(nodes, names)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Topics returns the sorted names of all topics that are published or subscribed to.
+func (s *SystemState) Topics() []string {
+	topics := setUnion(mapKeys(s.Publishers), mapKeys(s.Subscribers))
+	sort.Strings(topics)
+	return topics
+}
+
+// ServiceNames returns the sorted names of all services.
+func (s *SystemState) ServiceNames() []string {
+	services := mapKeys(s.Services)
+	sort.Strings(services)
+	return services
+}
+
+func mapKeys(m map[string][]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// TopicInfo is the name and message type of a topic.
+type TopicInfo struct {
+	Name string
+	Type string
+}
+
+// GraphChange describes how the graph changed between two polls of Graph.Watch.
+// All name lists are sorted.
+type GraphChange struct {
+	NodesAdded      []string
+	NodesRemoved    []string
+	TopicsAdded     []string
+	TopicsRemoved   []string
+	ServicesAdded   []string
+	ServicesRemoved []string
+
+	// State is the graph after the change.
+	State *SystemState
+
+	// Err is set instead of the other fields when the master could not be polled.
+	Err error
+}
+
+// Empty reports whether nothing appeared or disappeared.
+func (c *GraphChange) Empty() bool {
+	return c.Err == nil &&
+		len(c.NodesAdded) == 0 && len(c.NodesRemoved) == 0 &&
+		len(c.TopicsAdded) == 0 && len(c.TopicsRemoved) == 0 &&
+		len(c.ServicesAdded) == 0 && len(c.ServicesRemoved) == 0
+}
+
+// diffSystemState computes the change from old to new. A nil old state is empty.
+func diffSystemState(old *SystemState, new *SystemState) GraphChange {
+	if old == nil {
+		old = &SystemState{}
+	}
+	diff := func(lhs, rhs []string) []string {
+		result := setDifference(lhs, rhs)
+		sort.Strings(result)
+		return result
+	}
+	return GraphChange{
+		NodesAdded:      diff(new.Nodes(), old.Nodes()),
+		NodesRemoved:    diff(old.Nodes(), new.Nodes()),
+		TopicsAdded:     diff(new.Topics(), old.Topics()),
+		TopicsRemoved:   diff(old.Topics(), new.Topics()),
+		ServicesAdded:   diff(new.ServiceNames(), old.ServiceNames()),
+		ServicesRemoved: diff(old.ServiceNames(), new.ServiceNames()),
+		State:           new,
+	}
+}
+
+// Graph queries the master about the nodes, topics and services of the ROS graph.
+type Graph struct {
+	master *MasterClient
+}
+
+// NewGraph creates a Graph that asks the master at masterURI on behalf of callerID.
+// An empty masterURI means the value of ROS_MASTER_URI.
+func NewGraph(masterURI string, callerID string, opts ...MasterClientOption) *Graph {
+	return &Graph{master: NewMasterClient(masterURI, callerID, opts...)}
+}
+
+// SystemState returns the publishers, subscribers and services known to the master.
+func (g *Graph) SystemState() (*SystemState, error) {
+	return g.master.GetSystemState(context.Background())
+}
+
+// PublishedTopics returns the topics that are published in subgraph, a namespace.
+// An empty subgraph means all topics.
+func (g *Graph) PublishedTopics(subgraph string) ([]TopicInfo, error) {
+	return g.master.GetPublishedTopics(context.Background(), subgraph)
+}
+
+// TopicTypes returns the type of every topic known to the master, published or not.
+func (g *Graph) TopicTypes() ([]TopicInfo, error) {
+	return g.master.GetTopicTypes(context.Background())
+}
+
+// TopicType returns the message type of topic.
+func (g *Graph) TopicType(topic string) (string, error) {
+	topics, err := g.TopicTypes()
+	if err != nil {
+		return "", err
+	}
+	for _, info := range topics {
+		if info.Name == topic {
+			return info.Type, nil
+		}
+	}
+	return "", fmt.Errorf("unknown topic %s", topic)
+}
+
+// LookupNode returns the XML-RPC URI of the node called name.
+func (g *Graph) LookupNode(name string) (string, error) {
+	return g.master.LookupNode(context.Background(), name)
+}
+
+// LookupService returns the ROSRPC URI of the server providing service.
+func (g *Graph) LookupService(service string) (string, error) {
+	return g.master.LookupService(context.Background(), service)
+}
+
+// MasterURI returns the URI of the master as reported by the master itself.
+func (g *Graph) MasterURI() (string, error) {
+	return g.master.GetURI(context.Background())
+}
+
+// Watch polls the master every interval and sends the changes of the graph to the
+// returned channel. The first change lists everything that exists when watching starts.
+// Polls that change nothing are not sent; failed polls are sent with Err set.
+// The channel is closed once ctx is done.
+func (g *Graph) Watch(ctx context.Context, interval time.Duration) <-chan GraphChange {
+	changes := make(chan GraphChange)
+	go func() {
+		defer close(changes)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var last *SystemState
+		for {
+			var change GraphChange
+			state, err := g.master.GetSystemState(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				change.Err = err
+			} else {
+				change = diffSystemState(last, state)
+				last = state
+			}
+			if last == nil || !change.Empty() {
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes
+}
@@ -0,0 +1,144 @@
+package ros
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/xmlrpc"
+)
+
+// graphMaster is a stand-in master that serves the graph API from a mutable state.
+type graphMaster struct {
+	mutex       sync.Mutex
+	publishers  []interface{}
+	subscribers []interface{}
+	services    []interface{}
+}
+
+func (m *graphMaster) setState(publishers, subscribers, services []interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.publishers, m.subscribers, m.services = publishers, subscribers, services
+}
+
+func (m *graphMaster) start(t *testing.T) *httptest.Server {
+	success := func(value interface{}) (interface{}, error) {
+		return buildRosAPIResult(successStatus, "", value), nil
+	}
+	server := httptest.NewServer(xmlrpc.NewHandler(map[string]xmlrpc.Method{
+		"getSystemState": func(callerID string) (interface{}, error) {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+			return success([]interface{}{m.publishers, m.subscribers, m.services})
+		},
+		"getPublishedTopics": func(callerID string, subgraph string) (interface{}, error) {
+			return success([]interface{}{[]interface{}{"/chatter", "std_msgs/String"}})
+		},
+		"getTopicTypes": func(callerID string) (interface{}, error) {
+			return success([]interface{}{
+				[]interface{}{"/chatter", "std_msgs/String"},
+				[]interface{}{"/odom", "nav_msgs/Odometry"},
+			})
+		},
+		"lookupNode": func(callerID string, name string) (interface{}, error) {
+			if name != "/talker" {
+				return buildRosAPIResult(errorStatus, "unknown node", ""), nil
+			}
+			return success("http://talker:1234")
+		},
+		"lookupService": func(callerID string, service string) (interface{}, error) {
+			return success("rosrpc://server:5678")
+		},
+		"getUri": func(callerID string) (interface{}, error) {
+			return success("http://master:11311")
+		},
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func entry(name string, nodes ...string) interface{} {
+	list := make([]interface{}, len(nodes))
+	for i, node := range nodes {
+		list[i] = node
+	}
+	return []interface{}{name, list}
+}
+
+func TestGraphQueries(t *testing.T) {
+	master := &graphMaster{}
+	master.setState(
+		[]interface{}{entry("/chatter", "/talker")},
+		[]interface{}{entry("/chatter", "/listener"), entry("/odom", "/listener")},
+		[]interface{}{entry("/talker/get_loggers", "/talker")})
+	graph := NewGraph(master.start(t).URL, "/graph_test")
+
+	state, err := graph.SystemState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodes := state.Nodes(); !reflect.DeepEqual(nodes, []string{"/listener", "/talker"}) {
+		t.Errorf("Unexpected nodes %v", nodes)
+	}
+	if topics := state.Topics(); !reflect.DeepEqual(topics, []string{"/chatter", "/odom"}) {
+		t.Errorf("Unexpected topics %v", topics)
+	}
+	if services := state.ServiceNames(); !reflect.DeepEqual(services, []string{"/talker/get_loggers"}) {
+		t.Errorf("Unexpected services %v", services)
+	}
+
+	if topics, err := graph.PublishedTopics(""); err != nil || !reflect.DeepEqual(topics, []TopicInfo{{"/chatter", "std_msgs/String"}}) {
+		t.Errorf("Unexpected published topics %v, %v", topics, err)
+	}
+	if msgType, err := graph.TopicType("/odom"); err != nil || msgType != "nav_msgs/Odometry" {
+		t.Errorf("Unexpected topic type %q, %v", msgType, err)
+	}
+	if _, err := graph.TopicType("/unknown"); err == nil {
+		t.Error("Expected an error for an unknown topic")
+	}
+	if uri, err := graph.LookupNode("/talker"); err != nil || uri != "http://talker:1234" {
+		t.Errorf("Unexpected node URI %q, %v", uri, err)
+	}
+	if _, err := graph.LookupNode("/nobody"); err == nil {
+		t.Error("Expected an error for an unknown node")
+	}
+	if uri, err := graph.LookupService("/talker/get_loggers"); err != nil || uri != "rosrpc://server:5678" {
+		t.Errorf("Unexpected service URI %q, %v", uri, err)
+	}
+	if uri, err := graph.MasterURI(); err != nil || uri != "http://master:11311" {
+		t.Errorf("Unexpected master URI %q, %v", uri, err)
+	}
+}
+
+func TestGraphWatch(t *testing.T) {
+	master := &graphMaster{}
+	master.setState([]interface{}{entry("/chatter", "/talker")}, []interface{}{}, []interface{}{})
+	graph := NewGraph(master.start(t).URL, "/graph_test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := graph.Watch(ctx, 10*time.Millisecond)
+
+	initial := <-changes
+	if initial.Err != nil || !reflect.DeepEqual(initial.NodesAdded, []string{"/talker"}) ||
+		!reflect.DeepEqual(initial.TopicsAdded, []string{"/chatter"}) {
+		t.Errorf("Unexpected initial change %+v", initial)
+	}
+
+	master.setState([]interface{}{}, []interface{}{}, []interface{}{entry("/add_two_ints", "/server")})
+	change := <-changes
+	if !reflect.DeepEqual(change.NodesAdded, []string{"/server"}) ||
+		!reflect.DeepEqual(change.NodesRemoved, []string{"/talker"}) ||
+		!reflect.DeepEqual(change.TopicsRemoved, []string{"/chatter"}) ||
+		!reflect.DeepEqual(change.ServicesAdded, []string{"/add_two_ints"}) ||
+		len(change.TopicsAdded) != 0 || len(change.ServicesRemoved) != 0 {
+		t.Errorf("Unexpected change %+v", change)
+	}
+
+	cancel()
+	for range changes {
+	}
+}
@@ -3,7 +3,6 @@ package ros
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"io"
 )
 
@@ -16,48 +15,98 @@ type connHeader struct {
 	headers map[string]string
 }
 
-func readConnectionHeader(r io.Reader) ([]header, error) {
+// readConnectionHeader reads a length-prefixed connection header. A header larger than
+// maxSize bytes is rejected before its body is read.
+func readConnectionHeader(r io.Reader, maxSize int) ([]header, error) {
 	buf := make([]byte, 4)
-	_, err := io.ReadAtLeast(r, buf, 4)
+	_, err := io.ReadFull(r, buf)
 	if err != nil {
 		return nil, err
 	}
-	var headerSize uint32
-	bufReader := bytes.NewBuffer(buf)
-	err = binary.Read(bufReader, binary.LittleEndian, &headerSize)
-	if err != nil {
+	headerSize := binary.LittleEndian.Uint32(buf)
+	if err := checkSizeLimit("header", headerSize, maxSize); err != nil {
 		return nil, err
 	}
 	buf = make([]byte, int(headerSize))
-	_, err = io.ReadAtLeast(r, buf, int(headerSize))
+	_, err = io.ReadFull(r, buf)
 	if err != nil {
 		return nil, err
 	}
+	return decodeHeaderFields(buf)
+}
 
-	var done uint32
+// decodeHeaderFields parses the length-prefixed `key=value` fields of a connection header.
+// The buffer must not include the leading total header size.
+func decodeHeaderFields(buf []byte) ([]header, error) {
 	var headers []header
-	bufReader = bytes.NewBuffer(buf)
-	for {
-		if done == headerSize {
-			break
-		} else if done > headerSize {
-			return nil, fmt.Errorf("header length overrun")
+	for len(buf) > 0 {
+		if len(buf) < 4 {
+			return nil, &MalformedHeaderError{"truncated field length"}
 		}
-		var size uint32
-		err := binary.Read(bufReader, binary.LittleEndian, &size)
-		if err != nil {
-			return nil, err
+		size := binary.LittleEndian.Uint32(buf)
+		buf = buf[4:]
+		if uint64(size) > uint64(len(buf)) {
+			return nil, &MalformedHeaderError{"header length overrun"}
 		}
-		line := bufReader.Next(int(size))
+		line := buf[:size]
+		buf = buf[size:]
 		sep := bytes.IndexByte(line, '=')
+		if sep < 0 {
+			return nil, &MalformedHeaderError{"field without '='"}
+		}
 		key := string(line[0:sep])
 		value := string(line[sep+1:])
 		headers = append(headers, header{key, value})
-		done += 4 + size
 	}
 	return headers, nil
 }
 
+// readMessageFrame reads a 4 byte little endian length followed by that many bytes.
+// A frame larger than maxSize bytes is rejected before its body is read.
+func readMessageFrame(r io.Reader, maxSize int) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+	if err := checkSizeLimit("message", size, maxSize); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, int(size))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// checkSizeLimit returns a SizeLimitError if size exceeds a positive limit.
+func checkSizeLimit(kind string, size uint32, limit int) error {
+	if limit > 0 && uint64(size) > uint64(limit) {
+		return &SizeLimitError{Kind: kind, Size: size, Limit: limit}
+	}
+	return nil
+}
+
+// encodeHeaderFields serializes headers without the leading total header size,
+// as embedded in the XML-RPC negotiation of UDPROS.
+func encodeHeaderFields(headers []header) []byte {
+	var buf bytes.Buffer
+	for _, h := range headers {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(h.key)+len(h.value)+1))
+		buf.WriteString(h.key)
+		buf.WriteString("=")
+		buf.WriteString(h.value)
+	}
+	return buf.Bytes()
+}
+
+func headersToMap(headers []header) map[string]string {
+	m := make(map[string]string)
+	for _, h := range headers {
+		m[h.key] = h.value
+	}
+	return m
+}
+
 func writeConnectionHeader(headers []header, w io.Writer) error {
 	var headerSize int
 	var sizeList []int
@@ -0,0 +1,89 @@
+package ros
+
+import (
+	"reflect"
+	"sync"
+)
+
+// HasHeader is implemented by messages with a std_msgs/Header field, which messages
+// generated by gengo do.
+type HasHeader interface {
+	Message
+
+	// HeaderFields returns pointers to the seq, stamp and frame_id fields of the header.
+	HeaderFields() (seq *uint32, stamp *Time, frameID *string)
+}
+
+// HeaderFields returns pointers to the fields of the header of msg. Messages that do not
+// implement HasHeader are looked up for a Header field holding Seq, Stamp and FrameId
+// or FrameID fields, as generated messages from before HasHeader have. It reports
+// whether msg has a header.
+func HeaderFields(msg Message) (seq *uint32, stamp *Time, frameID *string, ok bool) {
+	if m, ok := msg.(HasHeader); ok {
+		seq, stamp, frameID = m.HeaderFields()
+		return seq, stamp, frameID, true
+	}
+	v := reflect.ValueOf(msg)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, nil, nil, false
+	}
+	index, ok := headerIndex(v.Elem().Type())
+	if !ok {
+		return nil, nil, nil, false
+	}
+	header := v.Elem().Field(index.header)
+	return header.Field(index.seq).Addr().Interface().(*uint32),
+		header.Field(index.stamp).Addr().Interface().(*Time),
+		header.Field(index.frameID).Addr().Interface().(*string),
+		true
+}
+
+// headerFieldIndex holds the indices of the header of a message type and of its fields.
+type headerFieldIndex struct {
+	header, seq, stamp, frameID int
+}
+
+var (
+	headerIndicesMutex sync.Mutex
+	headerIndices      = make(map[reflect.Type]*headerFieldIndex)
+	timeType           = reflect.TypeOf(Time{})
+)
+
+// headerIndex returns where the header fields of messages of type t are, caching the
+// result per type.
+func headerIndex(t reflect.Type) (headerFieldIndex, bool) {
+	headerIndicesMutex.Lock()
+	defer headerIndicesMutex.Unlock()
+	index, ok := headerIndices[t]
+	if !ok {
+		index = findHeaderIndex(t)
+		headerIndices[t] = index
+	}
+	if index == nil {
+		return headerFieldIndex{}, false
+	}
+	return *index, true
+}
+
+func findHeaderIndex(t reflect.Type) *headerFieldIndex {
+	field, ok := t.FieldByName("Header")
+	if !ok || len(field.Index) != 1 || field.Type.Kind() != reflect.Struct {
+		return nil
+	}
+	index := &headerFieldIndex{header: field.Index[0], seq: -1, stamp: -1, frameID: -1}
+	for i := 0; i < field.Type.NumField(); i++ {
+		f := field.Type.Field(i)
+		switch {
+		case f.Name == "Seq" && f.Type.Kind() == reflect.Uint32:
+			index.seq = i
+		case f.Name == "Stamp" && f.Type == timeType:
+			index.stamp = i
+		case (f.Name == "FrameId" || f.Name == "FrameID") && f.Type.Kind() == reflect.String:
+			index.frameID = i
+		}
+	}
+	if index.seq < 0 || index.stamp < 0 || index.frameID < 0 {
+		return nil
+	}
+	return index
+}
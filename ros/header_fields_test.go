@@ -0,0 +1,40 @@
+package ros
+
+import "testing"
+
+// headerMessage implements HasHeader with fields outside of its Header field.
+type headerMessage struct {
+	stampedValue
+	seq     uint32
+	stamp   Time
+	frameID string
+}
+
+func (m *headerMessage) HeaderFields() (*uint32, *Time, *string) {
+	return &m.seq, &m.stamp, &m.frameID
+}
+
+func TestHeaderFields(t *testing.T) {
+	msg := &stampedValue{}
+	seq, stamp, frameID, ok := HeaderFields(msg)
+	if !ok {
+		t.Fatal("Expected the header of a stamped value")
+	}
+	*seq, *stamp, *frameID = 3, NewTime(1, 2), "map"
+	if msg.Header.Seq != 3 || msg.Header.Stamp != NewTime(1, 2) || msg.Header.FrameId != "map" {
+		t.Errorf("Expected the fields to point into the header but got %+v", msg.Header)
+	}
+
+	m := &headerMessage{}
+	seq, stamp, frameID, ok = HeaderFields(m)
+	if !ok || seq != &m.seq || stamp != &m.stamp || frameID != &m.frameID {
+		t.Error("Expected the fields returned by HeaderFields")
+	}
+
+	var nilValue *stampedValue
+	for _, m := range []Message{nil, nilValue, &rawMessage{}} {
+		if _, _, _, ok := HeaderFields(m); ok {
+			t.Errorf("Expected no header in %#v", m)
+		}
+	}
+}
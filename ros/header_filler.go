@@ -0,0 +1,54 @@
+package ros
+
+import (
+	"sync"
+	"time"
+)
+
+// HeaderFiller fills in the headers of the messages of a publisher as set by the options
+// WithHeaderSequence, WithHeaderStamp and WithHeaderFrameID. Node implementations call
+// Fill for each message before it is serialized. A nil filler fills in nothing.
+type HeaderFiller struct {
+	sequence bool
+	stamp    bool
+	frameID  string
+
+	mutex sync.Mutex
+	seq   uint32
+}
+
+// NewHeaderFiller returns the header filler set by opts, or nil if they set none.
+func NewHeaderFiller(opts ...PublisherOption) *HeaderFiller {
+	options := newPublisherOptions(opts)
+	if !options.headerSequence && !options.headerStamp && options.headerFrameID == "" {
+		return nil
+	}
+	return &HeaderFiller{
+		sequence: options.headerSequence,
+		stamp:    options.headerStamp,
+		frameID:  options.headerFrameID,
+	}
+}
+
+// Fill fills in the header of msg, if it has one, in place. now is the time of the node.
+func (f *HeaderFiller) Fill(msg Message, now time.Time) {
+	if f == nil {
+		return
+	}
+	seq, stamp, frameID, ok := HeaderFields(msg)
+	if !ok {
+		return
+	}
+	if f.sequence {
+		f.mutex.Lock()
+		*seq = f.seq
+		f.seq++
+		f.mutex.Unlock()
+	}
+	if f.stamp && stamp.IsZero() {
+		stamp.FromNSec(uint64(now.UnixNano()))
+	}
+	if f.frameID != "" && *frameID == "" {
+		*frameID = f.frameID
+	}
+}
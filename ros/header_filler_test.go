@@ -0,0 +1,53 @@
+package ros
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// generatedHeader is laid out like the std_msgs/Header of gengo.
+type generatedHeader struct {
+	Seq     uint32
+	Stamp   Time
+	FrameId string
+}
+
+// legacyMessage is a generated message from before HasHeader.
+type legacyMessage struct {
+	Header generatedHeader
+	Data   string
+}
+
+func (m *legacyMessage) GetType() MessageType                { return &dummyMessage{} }
+func (m *legacyMessage) Serialize(buf *bytes.Buffer) error   { return nil }
+func (m *legacyMessage) Deserialize(buf *bytes.Reader) error { return nil }
+
+func TestHeaderFiller(t *testing.T) {
+	if NewHeaderFiller(WithQueueSize(1)) != nil {
+		t.Error("expected no filler without header options")
+	}
+	var none *HeaderFiller
+	none.Fill(&legacyMessage{}, time.Unix(1, 0))
+
+	now := time.Unix(100, 5)
+	filler := NewHeaderFiller(WithHeaderSequence(), WithHeaderStamp(), WithHeaderFrameID("base_link"))
+	first := &legacyMessage{}
+	filler.Fill(first, now)
+	if first.Header != (generatedHeader{0, NewTime(100, 5), "base_link"}) {
+		t.Errorf("unexpected header %+v", first.Header)
+	}
+	second := &legacyMessage{Header: generatedHeader{Seq: 42, Stamp: NewTime(7, 0), FrameId: "map"}}
+	filler.Fill(second, now)
+	if second.Header != (generatedHeader{1, NewTime(7, 0), "map"}) {
+		t.Errorf("expected only the seq to be set but got %+v", second.Header)
+	}
+	filler.Fill(&rawMessage{}, now)
+
+	stampOnly := NewHeaderFiller(WithHeaderStamp())
+	third := &legacyMessage{Header: generatedHeader{Seq: 42}}
+	stampOnly.Fill(third, now)
+	if third.Header != (generatedHeader{42, NewTime(100, 5), ""}) {
+		t.Errorf("expected only the stamp to be set but got %+v", third.Header)
+	}
+}
@@ -39,7 +39,7 @@ func TestReadConnectionHeader(t *testing.T) {
 
 	buf := bytes.NewReader(data)
 	t.Log(buf.Len())
-	headers, err := readConnectionHeader(buf)
+	headers, err := readConnectionHeader(buf, defaultMaxHeaderSize)
 	if err != nil {
 		t.Error(err)
 	}
@@ -133,3 +133,120 @@ func TestWriteConnectionHeader(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestEncodeDecodeHeaderFields(t *testing.T) {
+	headers := []header{
+		{"topic", "/chatter"},
+		{"md5sum", "992ce8a1687cec8c8bd883ec73ca41d1"},
+		{"callerid", "/talker"},
+		{"empty", ""},
+	}
+	buf := encodeHeaderFields(headers)
+
+	var framed bytes.Buffer
+	if err := writeConnectionHeader(headers, &framed); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(framed.Bytes()[4:], buf) {
+		t.Error("Encoded fields should match a connection header without its size prefix")
+	}
+
+	decoded, err := decodeHeaderFields(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(headers) {
+		t.Fatalf("Expected %d headers but got %d", len(headers), len(decoded))
+	}
+	for i := range headers {
+		if decoded[i] != headers[i] {
+			t.Errorf("Expected %v but got %v", headers[i], decoded[i])
+		}
+	}
+}
+
+func TestReadConnectionHeaderSizeLimit(t *testing.T) {
+	// Announce a 4 GiB header; it must be rejected before any allocation.
+	data := []byte{0xff, 0xff, 0xff, 0xff}
+	_, err := readConnectionHeader(bytes.NewReader(data), defaultMaxHeaderSize)
+	sizeErr, ok := err.(*SizeLimitError)
+	if !ok {
+		t.Fatalf("Expected a SizeLimitError but got %v", err)
+	}
+	if sizeErr.Kind != "header" || sizeErr.Size != 0xffffffff || sizeErr.Limit != defaultMaxHeaderSize {
+		t.Errorf("Unexpected error fields: %+v", sizeErr)
+	}
+}
+
+func TestReadMessageFrameSizeLimit(t *testing.T) {
+	data := []byte{0x05, 0x00, 0x00, 0x00, 'h', 'e', 'l', 'l', 'o'}
+	if _, err := readMessageFrame(bytes.NewReader(data), 4); err == nil {
+		t.Error("Expected the frame to exceed the limit")
+	} else if _, ok := err.(*SizeLimitError); !ok {
+		t.Errorf("Expected a SizeLimitError but got %v", err)
+	}
+
+	msg, err := readMessageFrame(bytes.NewReader(data), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg) != "hello" {
+		t.Errorf("Expected hello but got %q", msg)
+	}
+}
+
+func TestDecodeHeaderFieldsMalformed(t *testing.T) {
+	cases := map[string][]byte{
+		"truncated length": {0x01, 0x00},
+		"length overrun":   {0x10, 0x00, 0x00, 0x00, 'a', '=', 'b'},
+		"missing equals":   {0x03, 0x00, 0x00, 0x00, 'a', 'b', 'c'},
+	}
+	for name, buf := range cases {
+		if _, err := decodeHeaderFields(buf); err == nil {
+			t.Errorf("%s: expected an error", name)
+		} else if _, ok := err.(*MalformedHeaderError); !ok {
+			t.Errorf("%s: expected a MalformedHeaderError but got %v", name, err)
+		}
+	}
+}
+
+func FuzzDecodeHeaderFields(f *testing.F) {
+	f.Add(encodeHeaderFields([]header{{"topic", "/chatter"}, {"type", "std_msgs/String"}}))
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{0x01, 0x00, 0x00, 0x00, '='})
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		headers, err := decodeHeaderFields(buf)
+		if err != nil {
+			return
+		}
+		// Anything that decodes must survive a round trip.
+		decoded, err := decodeHeaderFields(encodeHeaderFields(headers))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded) != len(headers) {
+			t.Fatalf("Expected %d headers but got %d", len(headers), len(decoded))
+		}
+	})
+}
+
+func FuzzReadConnectionHeader(f *testing.F) {
+	var buffer bytes.Buffer
+	writeConnectionHeader([]header{{"callerid", "/talker"}, {"latching", "1"}}, &buffer)
+	f.Add(buffer.Bytes())
+	f.Add([]byte{0xff, 0xff, 0xff, 0x7f})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		readConnectionHeader(bytes.NewReader(data), 1024)
+	})
+}
+
+func FuzzReadMessageFrame(f *testing.F) {
+	f.Add([]byte{0x05, 0x00, 0x00, 0x00, 'h', 'e', 'l', 'l', 'o'})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := readMessageFrame(bytes.NewReader(data), 1024)
+		if err == nil && len(msg) > 1024 {
+			t.Fatalf("Frame of %d bytes exceeds the limit", len(msg))
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package ros
+
+import (
+	"sync"
+	"time"
+)
+
+// LatestSubscriber keeps the last message received on a topic, for nodes that only need
+// the most recent value of a topic when they get to it, such as a battery state read by
+// a timer. It neither needs Spin nor a callback. Its methods are safe for concurrent use.
+type LatestSubscriber struct {
+	ch    <-chan ReceivedMessage
+	sub   Subscriber
+	clock Clock
+
+	mutex    sync.Mutex
+	latest   ReceivedMessage
+	received bool
+}
+
+// NewLatestSubscriber creates a subscriber to topic that keeps the last message received.
+// The age of a message is the time since its ReceiptTime on the clock of node, so ages
+// follow simulated time in tests.
+func NewLatestSubscriber(node Node, topic string, msgType MessageType, opts ...SubscriberOption) *LatestSubscriber {
+	ch, sub := node.NewSubscriberChan(topic, msgType, 1, DropOldest, opts...)
+	return &LatestSubscriber{ch: ch, sub: sub, clock: node.Clock()}
+}
+
+// Latest returns the last message received and its event. ok is false if no message has
+// been received yet.
+func (s *LatestSubscriber) Latest() (msg Message, event MessageEvent, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.update()
+	return s.latest.Message, s.latest.Event, s.received
+}
+
+// LatestWithin returns the last message received and its event if it was received at most
+// maxAge ago. ok is false if no message has been received yet or the last one is older.
+func (s *LatestSubscriber) LatestWithin(maxAge time.Duration) (msg Message, event MessageEvent, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.update()
+	if !s.received || s.clock.Now().Sub(s.latest.Event.ReceiptTime) > maxAge {
+		return nil, MessageEvent{}, false
+	}
+	return s.latest.Message, s.latest.Event, true
+}
+
+// HasReceived reports whether a message has ever been received. It stays true after
+// Shutdown.
+func (s *LatestSubscriber) HasReceived() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.update()
+	return s.received
+}
+
+// Shutdown stops the subscriber. The last message received is kept.
+func (s *LatestSubscriber) Shutdown() {
+	s.sub.Shutdown()
+}
+
+// update takes the messages delivered since the last call off the channel. The channel
+// holds one message and drops the oldest, so it holds the latest one. The caller must
+// hold the mutex.
+func (s *LatestSubscriber) update() {
+	for {
+		select {
+		case received, ok := <-s.ch:
+			if !ok {
+				return
+			}
+			s.latest = received
+			s.received = true
+		default:
+			return
+		}
+	}
+}
@@ -1,13 +1,47 @@
 package ros
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/fetchrobotics/rosgo/xmlrpc"
 )
 
+// APIError is returned when a ROS API call reached its callee but did not succeed.
+// Errors of the transport, such as a refused connection or a timeout, are not APIErrors.
+type APIError struct {
+	// Method is the name of the API method that was called.
+	Method string
+
+	// Code is the status code of the result, -1 for ERROR or 0 for FAILURE.
+	Code int32
+
+	// Message is the status message of the result.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("ROS API call %s failed with code %d: %s", e.Method, e.Code, e.Message)
+}
+
+// IsError reports whether the callee rejected the call as erroneous, e.g. a malformed
+// argument or an unknown name.
+func (e *APIError) IsError() bool {
+	return e.Code == errorStatus
+}
+
+// IsFailure reports whether the call was valid but the callee failed to carry it out.
+func (e *APIError) IsFailure() bool {
+	return e.Code == failureStatus
+}
+
 func callRosAPI(calleeURI string, method string, args ...interface{}) (interface{}, error) {
-	result, err := xmlrpc.Call(calleeURI, method, args...)
+	return callRosAPIContext(context.Background(), http.DefaultClient, calleeURI, method, args...)
+}
+
+func callRosAPIContext(ctx context.Context, client *http.Client, calleeURI string, method string, args ...interface{}) (interface{}, error) {
+	result, err := xmlrpc.CallContext(ctx, client, calleeURI, method, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -32,7 +66,7 @@ func callRosAPI(calleeURI string, method string, args ...interface{}) (interface
 
 	value = xs[2]
 	if code != successStatus {
-		return nil, fmt.Errorf("ROS Master API call failed with code %d: %s", code, message)
+		return nil, &APIError{Method: method, Code: code, Message: message}
 	}
 	return value, nil
 }
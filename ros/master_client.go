@@ -0,0 +1,285 @@
+package ros
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// MasterClient calls the Master and Parameter Server API of a ROS master with typed
+// arguments and results. Calls that reach the master but do not succeed return an *APIError.
+// See https://wiki.ros.org/ROS/Master_API and https://wiki.ros.org/ROS/Parameter%20Server%20API
+type MasterClient struct {
+	masterURI string
+	callerID  string
+	options   masterClientOptions
+}
+
+// NewMasterClient creates a client that calls the master at masterURI on behalf of callerID.
+// An empty masterURI means the value of ROS_MASTER_URI.
+func NewMasterClient(masterURI string, callerID string, opts ...MasterClientOption) *MasterClient {
+	if masterURI == "" {
+		masterURI = os.Getenv("ROS_MASTER_URI")
+	}
+	return &MasterClient{
+		masterURI: masterURI,
+		callerID:  callerID,
+		options:   newMasterClientOptions(opts),
+	}
+}
+
+// URI returns the URI of the master the client calls.
+func (c *MasterClient) URI() string {
+	return c.masterURI
+}
+
+// CallerID returns the name the client calls the master as.
+func (c *MasterClient) CallerID() string {
+	return c.callerID
+}
+
+func (c *MasterClient) call(ctx context.Context, method string, args ...interface{}) (interface{}, error) {
+	if c.options.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.options.timeout)
+		defer cancel()
+	}
+	args = append([]interface{}{c.callerID}, args...)
+	return callRosAPIContext(ctx, c.options.httpClient, c.masterURI, method, args...)
+}
+
+func (c *MasterClient) callForString(ctx context.Context, method string, args ...interface{}) (string, error) {
+	result, err := c.call(ctx, method, args...)
+	if err != nil {
+		return "", err
+	}
+	value, ok := result.(string)
+	if !ok {
+		return "", fmt.Errorf("malformed %s result %v", method, result)
+	}
+	return value, nil
+}
+
+func (c *MasterClient) callForInt(ctx context.Context, method string, args ...interface{}) (int, error) {
+	result, err := c.call(ctx, method, args...)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := result.(int32)
+	if !ok {
+		return 0, fmt.Errorf("malformed %s result %v", method, result)
+	}
+	return int(value), nil
+}
+
+func (c *MasterClient) callForStrings(ctx context.Context, method string, args ...interface{}) ([]string, error) {
+	result, err := c.call(ctx, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseStringList(result)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %s result: %v", method, err)
+	}
+	return values, nil
+}
+
+// RegisterService registers the caller as the provider of service. serviceAPI is the
+// ROSRPC URI of the service and callerAPI the XML-RPC URI of the caller.
+func (c *MasterClient) RegisterService(ctx context.Context, service string, serviceAPI string, callerAPI string) error {
+	_, err := c.call(ctx, "registerService", service, serviceAPI, callerAPI)
+	return err
+}
+
+// UnregisterService unregisters the caller as the provider of service. It returns the
+// number of registrations removed, which is zero if the caller was not registered.
+func (c *MasterClient) UnregisterService(ctx context.Context, service string, serviceAPI string) (int, error) {
+	return c.callForInt(ctx, "unregisterService", service, serviceAPI)
+}
+
+// RegisterSubscriber subscribes the caller to topic and returns the XML-RPC URIs of the
+// current publishers of the topic.
+func (c *MasterClient) RegisterSubscriber(ctx context.Context, topic string, topicType string, callerAPI string) ([]string, error) {
+	return c.callForStrings(ctx, "registerSubscriber", topic, topicType, callerAPI)
+}
+
+// UnregisterSubscriber unsubscribes the caller from topic. It returns the number of
+// registrations removed.
+func (c *MasterClient) UnregisterSubscriber(ctx context.Context, topic string, callerAPI string) (int, error) {
+	return c.callForInt(ctx, "unregisterSubscriber", topic, callerAPI)
+}
+
+// RegisterPublisher registers the caller as a publisher of topic and returns the XML-RPC
+// URIs of the current subscribers of the topic.
+func (c *MasterClient) RegisterPublisher(ctx context.Context, topic string, topicType string, callerAPI string) ([]string, error) {
+	return c.callForStrings(ctx, "registerPublisher", topic, topicType, callerAPI)
+}
+
+// UnregisterPublisher unregisters the caller as a publisher of topic. It returns the
+// number of registrations removed.
+func (c *MasterClient) UnregisterPublisher(ctx context.Context, topic string, callerAPI string) (int, error) {
+	return c.callForInt(ctx, "unregisterPublisher", topic, callerAPI)
+}
+
+// LookupNode returns the XML-RPC URI of the node called name.
+func (c *MasterClient) LookupNode(ctx context.Context, name string) (string, error) {
+	return c.callForString(ctx, "lookupNode", name)
+}
+
+// GetPublishedTopics returns the topics that are published in subgraph, a namespace.
+// An empty subgraph means all topics.
+func (c *MasterClient) GetPublishedTopics(ctx context.Context, subgraph string) ([]TopicInfo, error) {
+	result, err := c.call(ctx, "getPublishedTopics", subgraph)
+	if err != nil {
+		return nil, err
+	}
+	return parseTopicInfoList(result)
+}
+
+// GetTopicTypes returns the type of every topic known to the master, published or not.
+func (c *MasterClient) GetTopicTypes(ctx context.Context) ([]TopicInfo, error) {
+	result, err := c.call(ctx, "getTopicTypes")
+	if err != nil {
+		return nil, err
+	}
+	return parseTopicInfoList(result)
+}
+
+// GetSystemState returns the publishers, subscribers and services known to the master.
+func (c *MasterClient) GetSystemState(ctx context.Context) (*SystemState, error) {
+	result, err := c.call(ctx, "getSystemState")
+	if err != nil {
+		return nil, err
+	}
+	lists, ok := result.([]interface{})
+	if !ok || len(lists) != 3 {
+		return nil, fmt.Errorf("malformed getSystemState result %v", result)
+	}
+	state := &SystemState{}
+	for i, m := range []*map[string][]string{&state.Publishers, &state.Subscribers, &state.Services} {
+		if *m, err = parseNameNodesList(lists[i]); err != nil {
+			return nil, fmt.Errorf("malformed getSystemState result: %v", err)
+		}
+	}
+	return state, nil
+}
+
+// GetURI returns the URI of the master as reported by the master itself.
+func (c *MasterClient) GetURI(ctx context.Context) (string, error) {
+	return c.callForString(ctx, "getUri")
+}
+
+// LookupService returns the ROSRPC URI of the server providing service.
+func (c *MasterClient) LookupService(ctx context.Context, service string) (string, error) {
+	return c.callForString(ctx, "lookupService", service)
+}
+
+// DeleteParam deletes the parameter key.
+func (c *MasterClient) DeleteParam(ctx context.Context, key string) error {
+	_, err := c.call(ctx, "deleteParam", key)
+	return err
+}
+
+// SetParam sets the parameter key to value. A map value sets a namespace of parameters.
+func (c *MasterClient) SetParam(ctx context.Context, key string, value interface{}) error {
+	_, err := c.call(ctx, "setParam", key, value)
+	return err
+}
+
+// GetParam returns the value of the parameter key. A namespace is returned as a
+// map[string]interface{}.
+func (c *MasterClient) GetParam(ctx context.Context, key string) (interface{}, error) {
+	return c.call(ctx, "getParam", key)
+}
+
+// SearchParam searches for key in the caller's namespace and then in each parent
+// namespace, and returns the full name of the closest parameter found.
+func (c *MasterClient) SearchParam(ctx context.Context, key string) (string, error) {
+	return c.callForString(ctx, "searchParam", key)
+}
+
+// SubscribeParam asks the master to call paramUpdate on callerAPI when key changes.
+// It returns the current value of the parameter, or an empty map if it is not set.
+func (c *MasterClient) SubscribeParam(ctx context.Context, callerAPI string, key string) (interface{}, error) {
+	return c.call(ctx, "subscribeParam", callerAPI, key)
+}
+
+// UnsubscribeParam stops the updates requested with SubscribeParam. It returns the number
+// of subscriptions removed.
+func (c *MasterClient) UnsubscribeParam(ctx context.Context, callerAPI string, key string) (int, error) {
+	return c.callForInt(ctx, "unsubscribeParam", callerAPI, key)
+}
+
+// HasParam reports whether the parameter key is set.
+func (c *MasterClient) HasParam(ctx context.Context, key string) (bool, error) {
+	result, err := c.call(ctx, "hasParam", key)
+	if err != nil {
+		return false, err
+	}
+	value, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("malformed hasParam result %v", result)
+	}
+	return value, nil
+}
+
+// GetParamNames returns the names of all parameters.
+func (c *MasterClient) GetParamNames(ctx context.Context) ([]string, error) {
+	return c.callForStrings(ctx, "getParamNames")
+}
+
+// parseNameNodesList parses a list of [name, [node...]] pairs.
+func parseNameNodesList(value interface{}) (map[string][]string, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list but got %T", value)
+	}
+	result := make(map[string][]string, len(items))
+	for _, item := range items {
+		pair, ok := item.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("expected a [name, nodes] pair but got %v", item)
+		}
+		name, ok := pair[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a name but got %v", pair[0])
+		}
+		nodes, err := parseStringList(pair[1])
+		if err != nil {
+			return nil, err
+		}
+		result[name] = nodes
+	}
+	return result, nil
+}
+
+// parseTopicInfoList parses a list of [topic, type] pairs.
+func parseTopicInfoList(value interface{}) ([]TopicInfo, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list of topics but got %T", value)
+	}
+	topics := make([]TopicInfo, 0, len(items))
+	for _, item := range items {
+		pair, err := parseStringList(item)
+		if err != nil || len(pair) != 2 {
+			return nil, fmt.Errorf("expected a [topic, type] pair but got %v", item)
+		}
+		topics = append(topics, TopicInfo{Name: pair[0], Type: pair[1]})
+	}
+	return topics, nil
+}
+
+func parseStringList(value interface{}) ([]string, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list of strings but got %T", value)
+	}
+	result := make([]string, len(items))
+	for i, item := range items {
+		if result[i], ok = item.(string); !ok {
+			return nil, fmt.Errorf("expected a string but got %v", item)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,288 @@
+package ros
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/xmlrpc"
+)
+
+// standInMaster implements the registration and parameter parts of the master API in memory.
+type standInMaster struct {
+	mutex       sync.Mutex
+	params      map[string]interface{}
+	publishers  map[string][]string
+	subscribers map[string][]string
+	services    map[string]string
+	callers     []string
+	delay       time.Duration
+}
+
+func newStandInMaster(t *testing.T) (*standInMaster, *httptest.Server) {
+	m := &standInMaster{
+		params:      make(map[string]interface{}),
+		publishers:  make(map[string][]string),
+		subscribers: make(map[string][]string),
+		services:    make(map[string]string),
+	}
+	success := func(value interface{}) (interface{}, error) {
+		return buildRosAPIResult(successStatus, "", value), nil
+	}
+	strings := func(values []string) []interface{} {
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		return list
+	}
+	locked := func(callerID string) func() {
+		m.mutex.Lock()
+		m.callers = append(m.callers, callerID)
+		return m.mutex.Unlock
+	}
+	server := httptest.NewServer(xmlrpc.NewHandler(map[string]xmlrpc.Method{
+		"registerService": func(callerID, service, serviceAPI, callerAPI string) (interface{}, error) {
+			defer locked(callerID)()
+			m.services[service] = serviceAPI
+			return success(1)
+		},
+		"unregisterService": func(callerID, service, serviceAPI string) (interface{}, error) {
+			defer locked(callerID)()
+			if _, ok := m.services[service]; !ok {
+				return success(0)
+			}
+			delete(m.services, service)
+			return success(1)
+		},
+		"registerSubscriber": func(callerID, topic, topicType, callerAPI string) (interface{}, error) {
+			defer locked(callerID)()
+			m.subscribers[topic] = append(m.subscribers[topic], callerAPI)
+			return success(strings(m.publishers[topic]))
+		},
+		"unregisterSubscriber": func(callerID, topic, callerAPI string) (interface{}, error) {
+			defer locked(callerID)()
+			delete(m.subscribers, topic)
+			return success(1)
+		},
+		"registerPublisher": func(callerID, topic, topicType, callerAPI string) (interface{}, error) {
+			defer locked(callerID)()
+			m.publishers[topic] = append(m.publishers[topic], callerAPI)
+			return success(strings(m.subscribers[topic]))
+		},
+		"unregisterPublisher": func(callerID, topic, callerAPI string) (interface{}, error) {
+			defer locked(callerID)()
+			delete(m.publishers, topic)
+			return success(1)
+		},
+		"lookupService": func(callerID, service string) (interface{}, error) {
+			defer locked(callerID)()
+			uri, ok := m.services[service]
+			if !ok {
+				return buildRosAPIResult(errorStatus, "no provider", ""), nil
+			}
+			return success(uri)
+		},
+		"getUri": func(callerID string) (interface{}, error) {
+			defer locked(callerID)()
+			time.Sleep(m.delay)
+			return success("http://master:11311")
+		},
+		"setParam": func(callerID, key string, value interface{}) (interface{}, error) {
+			defer locked(callerID)()
+			m.params[key] = value
+			return success(0)
+		},
+		"getParam": func(callerID, key string) (interface{}, error) {
+			defer locked(callerID)()
+			value, ok := m.params[key]
+			if !ok {
+				return buildRosAPIResult(errorStatus, "Parameter ["+key+"] is not set", 0), nil
+			}
+			return success(value)
+		},
+		"hasParam": func(callerID, key string) (interface{}, error) {
+			defer locked(callerID)()
+			_, ok := m.params[key]
+			return success(ok)
+		},
+		"searchParam": func(callerID, key string) (interface{}, error) {
+			defer locked(callerID)()
+			if _, ok := m.params["/"+key]; !ok {
+				return buildRosAPIResult(errorStatus, "not found", ""), nil
+			}
+			return success("/" + key)
+		},
+		"deleteParam": func(callerID, key string) (interface{}, error) {
+			defer locked(callerID)()
+			delete(m.params, key)
+			return success(0)
+		},
+		"subscribeParam": func(callerID, callerAPI, key string) (interface{}, error) {
+			defer locked(callerID)()
+			return success(m.params[key])
+		},
+		"unsubscribeParam": func(callerID, callerAPI, key string) (interface{}, error) {
+			defer locked(callerID)()
+			return success(1)
+		},
+		"getParamNames": func(callerID string) (interface{}, error) {
+			defer locked(callerID)()
+			var names []string
+			for name := range m.params {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return success(strings(names))
+		},
+		"shutdown": func(callerID string) (interface{}, error) {
+			return buildRosAPIResult(failureStatus, "cannot shut down", 0), nil
+		},
+	}))
+	t.Cleanup(server.Close)
+	return m, server
+}
+
+func TestMasterClientRegistration(t *testing.T) {
+	master, server := newStandInMaster(t)
+	client := NewMasterClient(server.URL, "/client")
+	ctx := context.Background()
+
+	if subscribers, err := client.RegisterPublisher(ctx, "/chatter", "std_msgs/String", "http://talker:1"); err != nil || len(subscribers) != 0 {
+		t.Errorf("Unexpected registerPublisher result %v, %v", subscribers, err)
+	}
+	publishers, err := client.RegisterSubscriber(ctx, "/chatter", "std_msgs/String", "http://listener:2")
+	if err != nil || !reflect.DeepEqual(publishers, []string{"http://talker:1"}) {
+		t.Errorf("Unexpected registerSubscriber result %v, %v", publishers, err)
+	}
+	if n, err := client.UnregisterSubscriber(ctx, "/chatter", "http://listener:2"); err != nil || n != 1 {
+		t.Errorf("Unexpected unregisterSubscriber result %d, %v", n, err)
+	}
+	if n, err := client.UnregisterPublisher(ctx, "/chatter", "http://talker:1"); err != nil || n != 1 {
+		t.Errorf("Unexpected unregisterPublisher result %d, %v", n, err)
+	}
+
+	if err := client.RegisterService(ctx, "/add", "rosrpc://server:3", "http://server:4"); err != nil {
+		t.Error(err)
+	}
+	if uri, err := client.LookupService(ctx, "/add"); err != nil || uri != "rosrpc://server:3" {
+		t.Errorf("Unexpected lookupService result %q, %v", uri, err)
+	}
+	if n, err := client.UnregisterService(ctx, "/add", "rosrpc://server:3"); err != nil || n != 1 {
+		t.Errorf("Unexpected unregisterService result %d, %v", n, err)
+	}
+	if n, err := client.UnregisterService(ctx, "/add", "rosrpc://server:3"); err != nil || n != 0 {
+		t.Errorf("Expected nothing to unregister but got %d, %v", n, err)
+	}
+	if uri, err := client.GetURI(ctx); err != nil || uri != "http://master:11311" {
+		t.Errorf("Unexpected getUri result %q, %v", uri, err)
+	}
+
+	master.mutex.Lock()
+	defer master.mutex.Unlock()
+	for _, caller := range master.callers {
+		if caller != "/client" {
+			t.Errorf("Expected every call to pass the caller ID but got %q", caller)
+		}
+	}
+}
+
+func TestMasterClientParams(t *testing.T) {
+	_, server := newStandInMaster(t)
+	client := NewMasterClient(server.URL, "/client")
+	ctx := context.Background()
+
+	if err := client.SetParam(ctx, "/rate", int32(10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetParam(ctx, "/frame", "map"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := client.GetParam(ctx, "/rate"); err != nil || value != int32(10) {
+		t.Errorf("Unexpected getParam result %v, %v", value, err)
+	}
+	if ok, err := client.HasParam(ctx, "/frame"); err != nil || !ok {
+		t.Errorf("Unexpected hasParam result %v, %v", ok, err)
+	}
+	if name, err := client.SearchParam(ctx, "frame"); err != nil || name != "/frame" {
+		t.Errorf("Unexpected searchParam result %q, %v", name, err)
+	}
+	if names, err := client.GetParamNames(ctx); err != nil || !reflect.DeepEqual(names, []string{"/frame", "/rate"}) {
+		t.Errorf("Unexpected getParamNames result %v, %v", names, err)
+	}
+	if value, err := client.SubscribeParam(ctx, "http://client:1", "/frame"); err != nil || value != "map" {
+		t.Errorf("Unexpected subscribeParam result %v, %v", value, err)
+	}
+	if n, err := client.UnsubscribeParam(ctx, "http://client:1", "/frame"); err != nil || n != 1 {
+		t.Errorf("Unexpected unsubscribeParam result %d, %v", n, err)
+	}
+	if err := client.DeleteParam(ctx, "/frame"); err != nil {
+		t.Error(err)
+	}
+	if ok, err := client.HasParam(ctx, "/frame"); err != nil || ok {
+		t.Errorf("Expected /frame to be deleted but got %v, %v", ok, err)
+	}
+}
+
+func TestMasterClientErrors(t *testing.T) {
+	_, server := newStandInMaster(t)
+	client := NewMasterClient(server.URL, "/client")
+	ctx := context.Background()
+
+	_, err := client.GetParam(ctx, "/missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.IsError() || apiErr.IsFailure() || apiErr.Method != "getParam" {
+		t.Errorf("Expected an ERROR status but got %v", err)
+	}
+	if err != nil && !strings.HasPrefix(err.Error(), "ROS API call getParam failed") {
+		t.Errorf("Unexpected error message %q", err)
+	}
+
+	_, err = client.call(ctx, "shutdown")
+	if !errors.As(err, &apiErr) || !apiErr.IsFailure() {
+		t.Errorf("Expected a FAILURE status but got %v", err)
+	}
+
+	unreachable := NewMasterClient("http://127.0.0.1:1", "/client")
+	if _, err := unreachable.GetURI(ctx); err == nil || errors.As(err, &apiErr) {
+		t.Errorf("Expected a transport error but got %v", err)
+	}
+}
+
+type countingTransport struct {
+	count int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.count++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestMasterClientTimeouts(t *testing.T) {
+	master, server := newStandInMaster(t)
+	master.delay = 200 * time.Millisecond
+
+	transport := &countingTransport{}
+	client := NewMasterClient(server.URL, "/client",
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithCallTimeout(20*time.Millisecond))
+	if _, err := client.GetURI(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the call to time out but got %v", err)
+	}
+	if transport.count != 1 {
+		t.Errorf("Expected the call to use the configured HTTP client")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewMasterClient(server.URL, "/client").GetURI(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the call to be canceled but got %v", err)
+	}
+}
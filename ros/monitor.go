@@ -0,0 +1,221 @@
+package ros
+
+import (
+	"sync"
+	"time"
+)
+
+// SubscriptionMonitor watches a subscription for the deadline and liveliness set by the
+// options WithDeadline and WithLiveliness, and queues their callbacks on an executor.
+// Node implementations create one per subscriber handle and tell it about received
+// messages and the number of connected publishers. A nil monitor watches nothing.
+type SubscriptionMonitor struct {
+	topic      string
+	executor   Executor
+	clock      Clock
+	deadline   *deadlineOptions
+	liveliness *livelinessOptions
+
+	mutex       sync.Mutex
+	stopped     bool
+	timer       ClockTimer
+	generation  uint64
+	lastReceipt time.Time
+	missed      int
+	publishers  int
+	alive       bool
+	everAlive   bool
+
+	// pendingMissed is the event of the queued missed callback, if there is one.
+	pendingMissed *DeadlineEvent
+}
+
+// NewSubscriptionMonitor returns the monitor of topic set by opts, or nil if they set
+// neither a deadline nor liveliness. Its callbacks run as jobs of executor and the
+// deadline follows the executor's clock. The first deadline is one period from now.
+func NewSubscriptionMonitor(topic string, executor Executor, opts ...SubscriberOption) *SubscriptionMonitor {
+	options := newSubscriberOptions(opts)
+	if options.deadline == nil && options.liveliness == nil {
+		return nil
+	}
+	m := &SubscriptionMonitor{
+		topic:      topic,
+		executor:   executor,
+		clock:      executor.Clock(),
+		deadline:   options.deadline,
+		liveliness: options.liveliness,
+	}
+	if m.deadline != nil {
+		m.mutex.Lock()
+		m.schedule()
+		m.mutex.Unlock()
+	}
+	return m
+}
+
+// MessageReceived tells the monitor that a message was received, which meets the current
+// deadline. The recovered callback is queued if deadlines were missed.
+func (m *SubscriptionMonitor) MessageReceived() {
+	if m == nil || m.deadline == nil {
+		return
+	}
+	m.mutex.Lock()
+	if m.stopped {
+		m.mutex.Unlock()
+		return
+	}
+	m.lastReceipt = m.clock.Now()
+	var job *Job
+	if m.missed > 0 {
+		job = m.deadlineJob(m.deadline.recovered)
+		m.missed = 0
+		m.pendingMissed = nil
+	}
+	m.timer.Stop()
+	m.schedule()
+	m.mutex.Unlock()
+	m.post(job)
+}
+
+// PublishersChanged tells the monitor the number of publishers now connected. The lost
+// callback is queued when the number falls below the minimum after having reached it,
+// and the recovered callback when it reaches the minimum again.
+func (m *SubscriptionMonitor) PublishersChanged(publishers int) {
+	if m == nil || m.liveliness == nil {
+		return
+	}
+	m.mutex.Lock()
+	if m.stopped || publishers == m.publishers {
+		m.mutex.Unlock()
+		return
+	}
+	m.publishers = publishers
+	var job *Job
+	switch alive := publishers >= m.liveliness.minPublishers; {
+	case alive && !m.alive:
+		if m.everAlive {
+			job = m.livelinessJob(m.liveliness.recovered)
+		}
+		m.alive, m.everAlive = true, true
+	case !alive && m.alive:
+		m.alive = false
+		job = m.livelinessJob(m.liveliness.lost)
+	}
+	m.mutex.Unlock()
+	m.post(job)
+}
+
+// Stop stops the monitor. Callbacks already queued do not run.
+func (m *SubscriptionMonitor) Stop() {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stopped = true
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+}
+
+// schedule sets the timer to the next deadline. The caller must hold the mutex.
+func (m *SubscriptionMonitor) schedule() {
+	m.generation++
+	generation := m.generation
+	m.timer = m.clock.AfterFunc(m.deadline.period, func() {
+		m.expire(generation)
+	})
+}
+
+// expire sets the timer to the next deadline and queues the missed callback for the
+// deadline that passed without a message. While that callback is still queued, further
+// missed deadlines update its event instead of queueing more jobs, so a node that does
+// not spin piles up no jobs, as with timers.
+func (m *SubscriptionMonitor) expire(generation uint64) {
+	m.mutex.Lock()
+	if m.stopped || generation != m.generation {
+		// A message was received since the timer was set.
+		m.mutex.Unlock()
+		return
+	}
+	m.missed++
+	m.schedule()
+	callback := m.deadline.missed
+	if callback == nil {
+		m.mutex.Unlock()
+		return
+	}
+	if m.pendingMissed != nil {
+		*m.pendingMissed = m.deadlineEvent()
+		m.mutex.Unlock()
+		return
+	}
+	event := m.deadlineEvent()
+	pending := &event
+	m.pendingMissed = pending
+	job := m.job(func() {
+		m.mutex.Lock()
+		event := *pending
+		if m.pendingMissed == pending {
+			m.pendingMissed = nil
+		}
+		m.mutex.Unlock()
+		callback(event)
+	})
+	m.mutex.Unlock()
+	m.post(job)
+}
+
+// deadlineJob returns the job calling callback with the current deadline state, or nil
+// if callback is nil. The caller must hold the mutex.
+func (m *SubscriptionMonitor) deadlineJob(callback func(DeadlineEvent)) *Job {
+	if callback == nil {
+		return nil
+	}
+	event := m.deadlineEvent()
+	return m.job(func() { callback(event) })
+}
+
+// deadlineEvent returns the current deadline state. The caller must hold the mutex.
+func (m *SubscriptionMonitor) deadlineEvent() DeadlineEvent {
+	return DeadlineEvent{
+		Topic:       m.topic,
+		Period:      m.deadline.period,
+		LastReceipt: m.lastReceipt,
+		Missed:      m.missed,
+	}
+}
+
+// livelinessJob returns the job calling callback with the current liveliness state, or
+// nil if callback is nil. The caller must hold the mutex.
+func (m *SubscriptionMonitor) livelinessJob(callback func(LivelinessEvent)) *Job {
+	if callback == nil {
+		return nil
+	}
+	event := LivelinessEvent{
+		Topic:         m.topic,
+		Publishers:    m.publishers,
+		MinPublishers: m.liveliness.minPublishers,
+	}
+	return m.job(func() { callback(event) })
+}
+
+// job returns a job that calls run unless the monitor was stopped in the meantime.
+func (m *SubscriptionMonitor) job(run func()) *Job {
+	return &Job{Name: "monitor " + m.topic, Run: func() {
+		m.mutex.Lock()
+		stopped := m.stopped
+		m.mutex.Unlock()
+		if !stopped {
+			run()
+		}
+	}}
+}
+
+// post queues job if it is not nil. It is called without holding the mutex, as Post may
+// block while the queue of the executor is full.
+func (m *SubscriptionMonitor) post(job *Job) {
+	if job != nil {
+		m.executor.Post(*job)
+	}
+}
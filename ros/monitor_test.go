@@ -0,0 +1,95 @@
+package ros
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionMonitorDeadline(t *testing.T) {
+	start := time.Unix(1000, 0)
+	executor := NewManualExecutor(start)
+	var missed, recovered []DeadlineEvent
+	monitor := NewSubscriptionMonitor("/cmd_vel", executor, WithDeadline(time.Second,
+		func(e DeadlineEvent) { missed = append(missed, e) },
+		func(e DeadlineEvent) { recovered = append(recovered, e) }))
+
+	executor.Advance(900 * time.Millisecond)
+	monitor.MessageReceived()
+	executor.Advance(900 * time.Millisecond)
+	if len(missed) != 0 {
+		t.Errorf("Expected no missed deadline while messages arrive but got %+v", missed)
+	}
+
+	// The deadline is missed every period without a message. Deadlines missed while the
+	// node does not spin queue a single job.
+	if ran := executor.Advance(1100 * time.Millisecond); !reflect.DeepEqual(ran, []string{"monitor /cmd_vel"}) {
+		t.Fatalf("Expected one job for two missed deadlines but got %v", ran)
+	}
+	lastReceipt := start.Add(900 * time.Millisecond)
+	if missed[0] != (DeadlineEvent{Topic: "/cmd_vel", Period: time.Second, LastReceipt: lastReceipt, Missed: 2}) {
+		t.Errorf("Unexpected event %+v", missed[0])
+	}
+
+	monitor.MessageReceived()
+	executor.RunPending()
+	if len(recovered) != 1 || recovered[0].Missed != 2 || !recovered[0].LastReceipt.Equal(start.Add(2900*time.Millisecond)) {
+		t.Errorf("Expected the deadline to recover but got %+v", recovered)
+	}
+	monitor.MessageReceived()
+	executor.RunPending()
+	if len(recovered) != 1 {
+		t.Errorf("Expected a single recovery but got %+v", recovered)
+	}
+
+	// Callbacks queued before Stop do not run.
+	executor.Clock().(*ManualClock).Advance(time.Second)
+	monitor.Stop()
+	if ran := executor.Advance(time.Hour); len(ran) != 1 || len(missed) != 1 {
+		t.Errorf("Expected no callbacks after Stop but got %v", missed)
+	}
+}
+
+func TestSubscriptionMonitorMissedWhileQueued(t *testing.T) {
+	executor := NewManualExecutor(time.Unix(1000, 0))
+	var events []DeadlineEvent
+	monitor := NewSubscriptionMonitor("/cmd_vel", executor, WithDeadline(time.Second,
+		func(e DeadlineEvent) { events = append(events, e) },
+		func(e DeadlineEvent) { events = append(events, e) }))
+
+	// A message received while a missed callback is queued is reported after it, and
+	// deadlines missed after the message queue a new job.
+	clock := executor.Clock().(*ManualClock)
+	clock.Advance(3500 * time.Millisecond)
+	monitor.MessageReceived()
+	clock.Advance(1500 * time.Millisecond)
+	if ran := executor.RunPending(); len(ran) != 3 || len(events) != 3 || events[0].Missed != 3 || events[1].Missed != 3 || events[2].Missed != 1 {
+		t.Errorf("Expected missed, recovered and a new missed callback but got %v %+v", ran, events)
+	}
+	monitor.Stop()
+}
+
+func TestSubscriptionMonitorLiveliness(t *testing.T) {
+	executor := NewManualExecutor(time.Unix(0, 0))
+	var events []string
+	monitor := NewSubscriptionMonitor("/scan", executor, WithLiveliness(2,
+		func(e LivelinessEvent) { events = append(events, "lost", e.Topic) },
+		func(e LivelinessEvent) { events = append(events, "recovered", e.Topic) }))
+
+	for _, publishers := range []int{0, 1, 2, 3, 1, 0, 2} {
+		monitor.PublishersChanged(publishers)
+	}
+	executor.RunPending()
+	// Nothing is lost before the minimum was reached once.
+	if !reflect.DeepEqual(events, []string{"lost", "/scan", "recovered", "/scan"}) {
+		t.Errorf("Unexpected events %v", events)
+	}
+
+	if NewSubscriptionMonitor("/scan", executor, WithTransportHints(NewTransportHints())) != nil {
+		t.Error("Expected no monitor without deadline or liveliness")
+	}
+	var none *SubscriptionMonitor
+	none.MessageReceived()
+	none.PublishersChanged(1)
+	none.Stop()
+}
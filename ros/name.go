@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 const (
@@ -57,6 +58,16 @@ func qualifyNodeName(nodeName string) (string, string, error) {
 	return namespace, components[len(components)-1], nil
 }
 
+// ValidateName returns an *InvalidNameError if name is not a valid ROS graph resource
+// name. Valid names are global, relative or private names of alphanumeric components
+// that start with a letter, like "/robot/scan", "scan" or "~rate".
+func ValidateName(name string) error {
+	if !isValidName(name) {
+		return &InvalidNameError{Name: name}
+	}
+	return nil
+}
+
 func isValidName(name string) bool {
 	if len(name) == 0 {
 		return true
@@ -100,6 +111,9 @@ func canonicalizeName(name string) string {
 type nameResolver struct {
 	nodeName        string
 	namespace       string
+	privateNS       string
+	parent          *nameResolver
+	mutex           sync.RWMutex
 	mapping         NameMap
 	resolvedMapping NameMap
 }
@@ -109,13 +123,13 @@ func newNameResolver(namespace string, nodeName string, remapping NameMap) *name
 
 	n.nodeName = nodeName
 	n.namespace = canonicalizeName(namespace)
-	n.mapping = remapping
+	n.privateNS = canonicalizeName(n.namespace + Sep + nodeName)
+	n.mapping = make(NameMap)
 	n.resolvedMapping = make(NameMap)
 
-	for k, v := range n.mapping {
-		newKey := n.resolve(k)
-		newValue := n.resolve(v)
-		n.resolvedMapping[newKey] = newValue
+	for k, v := range remapping {
+		n.mapping[k] = v
+		n.resolvedMapping[n.resolve(k)] = n.resolve(v)
 	}
 
 	return n
@@ -132,7 +146,7 @@ func (n *nameResolver) resolve(name string) string {
 	if isGlobalName(canonName) {
 		resolvedName = canonName
 	} else if isPrivateName(canonName) {
-		resolvedName = canonicalizeName(n.namespace + Sep + n.nodeName + Sep + canonName[1:])
+		resolvedName = canonicalizeName(n.privateNS + Sep + canonName[1:])
 	} else {
 		resolvedName = canonicalizeName(n.namespace + Sep + canonName)
 	}
@@ -140,12 +154,85 @@ func (n *nameResolver) resolve(name string) string {
 	return resolvedName
 }
 
+// child returns a resolver for the namespace ns, which is resolved against n. Private
+// names still resolve against the node name. Names are looked up in the remappings of
+// the child first and then in those of n, including remappings added to n later.
+func (n *nameResolver) child(ns string, remapping NameMap) *nameResolver {
+	c := new(nameResolver)
+
+	c.nodeName = n.nodeName
+	c.namespace = n.resolve(ns)
+	c.privateNS = n.privateNS
+	c.parent = n
+	c.mapping = make(NameMap)
+	c.resolvedMapping = make(NameMap)
+
+	for k, v := range remapping {
+		c.mapping[k] = v
+		c.resolvedMapping[c.resolve(k)] = c.resolve(v)
+	}
+
+	return c
+}
+
+// addRemapping remaps the name from to the name to, both resolved in the namespace of n.
+func (n *nameResolver) addRemapping(from string, to string) error {
+	if err := ValidateName(from); err != nil {
+		return err
+	}
+	if err := ValidateName(to); err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.mapping[from] = to
+	n.resolvedMapping[n.resolve(from)] = n.resolve(to)
+	return nil
+}
+
+// resolveName validates name and resolves it to a global name, applying the remappings
+// if remap is set. It is the one path by which nodes resolve the names they are given.
+func (n *nameResolver) resolveName(name string, remap bool) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	if remap {
+		return n.remap(name), nil
+	}
+	return n.resolve(name), nil
+}
+
 // Resolve a ROS name with remapping
 func (n *nameResolver) remap(name string) string {
-	key := n.resolve(name)
-	if value, ok := n.resolvedMapping[key]; ok {
+	return n.remapResolved(n.resolve(name))
+}
+
+func (n *nameResolver) remapResolved(key string) string {
+	n.mutex.RLock()
+	value, ok := n.resolvedMapping[key]
+	n.mutex.RUnlock()
+	if ok {
 		return value
 	}
+	if n.parent != nil {
+		return n.parent.remapResolved(key)
+	}
 
 	return key
 }
+
+// searchKey returns the key to search the parameter server for. Searches are remapped
+// by the unresolved names of the remappings, as in roscpp, because the resolved name of
+// a search is not known before the search.
+func (n *nameResolver) searchKey(key string) string {
+	n.mutex.RLock()
+	value, ok := n.mapping[key]
+	n.mutex.RUnlock()
+	if ok {
+		return value
+	}
+	if n.parent != nil {
+		return n.parent.searchKey(key)
+	}
+	return key
+}
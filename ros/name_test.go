@@ -1,6 +1,7 @@
 package ros
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -298,3 +299,70 @@ func TestProcessArguments(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestChildResolver(t *testing.T) {
+	remapping := NameMap{
+		"scan": "base_scan",
+	}
+	resolver := newNameResolver("/robot", "driver", remapping)
+	arm := resolver.child("arm_left", NameMap{"joint_states": "/joints", "scan": "arm_scan"})
+	gripper := arm.child("gripper", nil)
+	private := resolver.child("~", nil)
+
+	cases := []struct {
+		resolver *nameResolver
+		name     string
+		expected string
+	}{
+		{arm, "cmd", "/robot/arm_left/cmd"},
+		{arm, "/cmd", "/cmd"},
+		{arm, "~cmd", "/robot/driver/cmd"},
+		{arm, "joint_states", "/joints"},
+		{arm, "scan", "/robot/arm_left/arm_scan"},
+		{arm, "/robot/scan", "/robot/base_scan"},
+		{gripper, "cmd", "/robot/arm_left/gripper/cmd"},
+		{gripper, "/robot/arm_left/joint_states", "/joints"},
+		{private, "rate", "/robot/driver/rate"},
+		{resolver.child("/tools", nil), "rate", "/tools/rate"},
+	}
+	for _, c := range cases {
+		if result := c.resolver.remap(c.name); result != c.expected {
+			t.Errorf("%s in %s: expected %s but got %s", c.name, c.resolver.namespace, c.expected, result)
+		}
+	}
+}
+
+func TestResolveName(t *testing.T) {
+	resolver := newNameResolver("/robot", "driver", NameMap{"scan": "base_scan"})
+	arm := resolver.child("arm", nil)
+
+	if name, err := resolver.resolveName("scan", true); err != nil || name != "/robot/base_scan" {
+		t.Errorf("expected /robot/base_scan but got %q, %v", name, err)
+	}
+	if name, err := resolver.resolveName("scan", false); err != nil || name != "/robot/scan" {
+		t.Errorf("expected /robot/scan but got %q, %v", name, err)
+	}
+	var invalid *InvalidNameError
+	if _, err := resolver.resolveName("1scan", true); !errors.As(err, &invalid) || invalid.Name != "1scan" {
+		t.Errorf("expected an InvalidNameError but got %v", err)
+	}
+
+	if err := resolver.addRemapping("/robot/arm/cmd", "~arm_cmd"); err != nil {
+		t.Fatal(err)
+	}
+	if name := arm.remap("cmd"); name != "/robot/driver/arm_cmd" {
+		t.Errorf("expected existing children to use a new remapping but got %s", name)
+	}
+	if err := arm.addRemapping("rate", "/rate"); err != nil {
+		t.Fatal(err)
+	}
+	if name := resolver.remap("arm/rate"); name != "/robot/arm/rate" {
+		t.Errorf("expected the remapping of a child not to apply to its parent but got %s", name)
+	}
+	if key := arm.searchKey("rate"); key != "/rate" {
+		t.Errorf("expected the search key /rate but got %s", key)
+	}
+	if err := resolver.addRemapping("scan", "base scan"); err == nil {
+		t.Error("expected an error for an invalid remapping")
+	}
+}
@@ -0,0 +1,236 @@
+package ros
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/internal/nodeimpl"
+)
+
+// namespacedNode is a view of a defaultNode that resolves names with its own resolver.
+// Everything it creates is owned by the node; the view only remembers how to release it.
+type namespacedNode struct {
+	node     *defaultNode
+	resolver *nameResolver
+	master   *MasterClient
+	mutex    sync.Mutex
+	shutdown bool
+	releases map[interface{}]func()
+}
+
+func newNamespacedNode(node *defaultNode, resolver *nameResolver) *namespacedNode {
+	// The master searches parameters upwards from the caller ID, so the view searches
+	// from its namespace like a roscpp NodeHandle does.
+	master := &MasterClient{masterURI: node.master.masterURI, callerID: resolver.namespace, options: node.master.options}
+	return &namespacedNode{node: node, resolver: resolver, master: master, releases: make(map[interface{}]func())}
+}
+
+// track remembers release to be called for handle when the view is shut down.
+func (v *namespacedNode) track(handle interface{}, release func()) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.releases[handle] = release
+}
+
+// untrack forgets a handle that was shut down by itself.
+func (v *namespacedNode) untrack(handle interface{}) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	delete(v.releases, handle)
+}
+
+// The handles a view returns wrap those of the node, so that a handle shut down before
+// the view stops being tracked by it.
+
+type viewPublisher struct {
+	Publisher
+	view *namespacedNode
+}
+
+func (p *viewPublisher) Shutdown() {
+	p.view.untrack(p)
+	p.Publisher.Shutdown()
+}
+
+type viewSubscriber struct {
+	Subscriber
+	view *namespacedNode
+}
+
+func (s *viewSubscriber) Shutdown() {
+	s.view.untrack(s)
+	s.Subscriber.Shutdown()
+}
+
+type viewServiceClient struct {
+	ServiceClient
+	view *namespacedNode
+}
+
+func (c *viewServiceClient) Shutdown() {
+	c.view.untrack(c)
+	c.ServiceClient.Shutdown()
+}
+
+type viewServiceServer struct {
+	ServiceServer
+	view *namespacedNode
+}
+
+func (s *viewServiceServer) Shutdown() {
+	s.view.untrack(s)
+	s.ServiceServer.Shutdown()
+}
+
+type viewTimer struct {
+	Timer
+	view *namespacedNode
+}
+
+func (t *viewTimer) Stop() {
+	t.view.untrack(t)
+	t.Timer.Stop()
+}
+
+func (v *namespacedNode) NewPublisher(topic string, msgType MessageType, opts ...PublisherOption) Publisher {
+	return v.NewPublisherWithCallbacks(topic, msgType, nil, nil, opts...)
+}
+
+func (v *namespacedNode) NewPublisherWithCallbacks(topic string, msgType MessageType, connectCallback, disconnectCallback func(SingleSubscriberPublisher), opts ...PublisherOption) Publisher {
+	pub := &viewPublisher{v.node.advertise(v.node.remapName(v.resolver, topic), msgType, connectCallback, disconnectCallback, opts), v}
+	v.track(pub, pub.Publisher.Shutdown)
+	return pub
+}
+
+func (v *namespacedNode) NewSubscriber(topic string, msgType MessageType, callback interface{}, opts ...SubscriberOption) Subscriber {
+	if err := nodeimpl.CheckMessageCallback(callback, msgType.NewMessage(), MessageEvent{}); err != nil {
+		v.node.logger.Errorf("NewSubscriber(%s): %v", topic, err)
+		callback = nil
+	}
+	sub := &viewSubscriber{v.node.subscribe(v.node.remapName(v.resolver, topic), msgType, callback, nil, opts), v}
+	v.track(sub, sub.Subscriber.Shutdown)
+	return sub
+}
+
+func (v *namespacedNode) NewSubscriberChan(topic string, msgType MessageType, bufferSize int, policy DropPolicy, opts ...SubscriberOption) (<-chan ReceivedMessage, Subscriber) {
+	channel := newMessageChannel(bufferSize, policy)
+	sub := &viewSubscriber{v.node.subscribe(v.node.remapName(v.resolver, topic), msgType, nil, channel, opts), v}
+	v.track(sub, sub.Subscriber.Shutdown)
+	return channel.ch, sub
+}
+
+func (v *namespacedNode) NewServiceClient(service string, srvType ServiceType) ServiceClient {
+	client := &viewServiceClient{v.node.newServiceClient(v.node.remapName(v.resolver, service), srvType), v}
+	v.track(client, client.ServiceClient.Shutdown)
+	return client
+}
+
+func (v *namespacedNode) NewServiceServer(service string, srvType ServiceType, handler interface{}) ServiceServer {
+	server := v.node.newServiceServer(v.node.remapName(v.resolver, service), srvType, handler)
+	if server == nil {
+		return nil
+	}
+	handle := &viewServiceServer{server, v}
+	v.track(handle, server.Shutdown)
+	return handle
+}
+
+func (v *namespacedNode) NewTimer(period time.Duration, callback func(TimerEvent)) Timer {
+	timer := &viewTimer{v.node.NewTimer(period, callback), v}
+	v.track(timer, timer.Timer.Stop)
+	return timer
+}
+
+// OK reports whether both the node and the view are running.
+func (v *namespacedNode) OK() bool {
+	v.mutex.Lock()
+	shutdown := v.shutdown
+	v.mutex.Unlock()
+	return !shutdown && v.node.OK()
+}
+
+// Clock returns the clock of the node's executor.
+func (v *namespacedNode) Clock() Clock {
+	return v.node.Clock()
+}
+
+func (v *namespacedNode) SpinOnce() {
+	v.node.SpinOnce()
+}
+
+// Spin runs the node's executor until the node or the view is shut down.
+func (v *namespacedNode) Spin() {
+	v.node.executor.Spin(v.OK)
+}
+
+// Shutdown releases everything created through the view. The node keeps running.
+func (v *namespacedNode) Shutdown() {
+	v.mutex.Lock()
+	v.shutdown = true
+	releases := v.releases
+	v.releases = make(map[interface{}]func())
+	v.mutex.Unlock()
+	for _, release := range releases {
+		release()
+	}
+}
+
+func (v *namespacedNode) GetParam(key string) (interface{}, error) {
+	return v.node.getParam(v.resolver, key)
+}
+
+func (v *namespacedNode) SetParam(key string, value interface{}) error {
+	return v.node.setParam(v.resolver, key, value)
+}
+
+func (v *namespacedNode) HasParam(key string) (bool, error) {
+	return v.node.hasParam(v.resolver, key)
+}
+
+func (v *namespacedNode) SearchParam(key string) (string, error) {
+	return searchParam(v.master, v.resolver, key)
+}
+
+func (v *namespacedNode) DeleteParam(key string) error {
+	return v.node.deleteParam(v.resolver, key)
+}
+
+func (v *namespacedNode) Logger() Logger {
+	return v.node.Logger()
+}
+
+func (v *namespacedNode) SetLogger(logger Logger) {
+	v.node.SetLogger(logger)
+}
+
+func (v *namespacedNode) NonRosArgs() []string {
+	return v.node.NonRosArgs()
+}
+
+func (v *namespacedNode) Name() string {
+	return v.node.Name()
+}
+
+func (v *namespacedNode) Namespace(ns string, remappings NameMap) Node {
+	return newNamespacedNode(v.node, v.node.childResolver(v.resolver, ns, remappings))
+}
+
+func (v *namespacedNode) ResolveName(name string, remap bool) (string, error) {
+	return v.resolver.resolveName(name, remap)
+}
+
+// AddRemapping remaps names resolved through the view and the views created from it.
+func (v *namespacedNode) AddRemapping(from string, to string) error {
+	return v.resolver.addRemapping(from, to)
+}
+
+// WaitForService blocks until service, resolved in the namespace of the view, is
+// registered with the master and its server accepts connections, or until ctx is done.
+func (v *namespacedNode) WaitForService(ctx context.Context, service string) error {
+	name, err := v.resolver.resolveName(service, true)
+	if err != nil {
+		return err
+	}
+	return waitForService(ctx, v.node.master, name, waitForServicePollInterval)
+}
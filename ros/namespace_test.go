@@ -0,0 +1,28 @@
+package ros
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNamespaceForgetsStoppedTimers(t *testing.T) {
+	node, err := newDefaultNode("/test_namespace_node", []string{}, WithExecutor(NewManualExecutor(time.Unix(0, 0))))
+	if err != nil {
+		t.Fatalf("Error starting new test node: %v", err)
+	}
+	defer node.Shutdown()
+	view := node.Namespace("arm", nil).(*namespacedNode)
+
+	for i := 0; i < 3; i++ {
+		view.NewTimer(time.Second, func(TimerEvent) {}).Stop()
+	}
+	if n := len(view.releases); n != 0 {
+		t.Errorf("Expected the view to forget the stopped timers but it tracks %d", n)
+	}
+
+	view.NewTimer(time.Second, func(TimerEvent) {})
+	view.Shutdown()
+	if n := len(node.timers); n != 0 {
+		t.Errorf("Expected the view to stop its timer but the node has %d", n)
+	}
+}
@@ -1,19 +1,19 @@
 package ros
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"reflect"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/fetchrobotics/rosgo/internal/nodeimpl"
 	"github.com/fetchrobotics/rosgo/xmlrpc"
 )
 
@@ -65,6 +65,7 @@ type defaultNode struct {
 	namespace        string
 	qualifiedName    string
 	masterURI        string
+	master           *MasterClient
 	xmlrpcURI        string
 	xmlrpcListener   net.Listener
 	xmlrpcHandler    *xmlrpc.Handler
@@ -72,9 +73,14 @@ type defaultNode struct {
 	subscribersMutex sync.RWMutex
 	publishers       map[string]*defaultPublisher
 	publishersMutex  sync.RWMutex
+	statisticsPub    Publisher
+	statisticsOnce   sync.Once
+	statistics       *statisticsConfig
 	servers          map[string]*defaultServiceServer
 	serversMutex     sync.RWMutex
-	jobChan          chan func()
+	executor         Executor
+	timers           map[*defaultTimer]struct{}
+	timersMutex      sync.Mutex
 	interruptChan    chan os.Signal
 	logger           Logger
 	ok               bool
@@ -86,10 +92,12 @@ type defaultNode struct {
 	homeDir          string
 	resolver         *nameResolver
 	nonRosArgs       []string
+	options          nodeOptions
 }
 
-func newDefaultNode(name string, args []string) (*defaultNode, error) {
+func newDefaultNode(name string, args []string, opts ...NodeOption) (*defaultNode, error) {
 	node := new(defaultNode)
+	node.options = newNodeOptions(opts)
 
 	namespace, nodeName, err := qualifyNodeName(name)
 	if err != nil {
@@ -97,6 +105,14 @@ func newDefaultNode(name string, args []string) (*defaultNode, error) {
 	}
 
 	remapping, params, specials, rest := processArguments(args)
+	for from, to := range remapping {
+		if err := ValidateName(from); err != nil {
+			return nil, fmt.Errorf("remapping %s:=%s: %w", from, to, err)
+		}
+		if err := ValidateName(to); err != nil {
+			return nil, fmt.Errorf("remapping %s:=%s: %w", from, to, err)
+		}
+	}
 
 	node.homeDir = filepath.Join(os.Getenv("HOME"), ".ros")
 	if homeDir := os.Getenv("ROS_HOME"); len(homeDir) > 0 {
@@ -151,6 +167,8 @@ func newDefaultNode(name string, args []string) (*defaultNode, error) {
 		node.qualifiedName = node.namespace + node.name
 	}
 
+	node.master = NewMasterClient(node.masterURI, node.qualifiedName)
+
 	node.subscribers = make(map[string]*defaultSubscriber)
 	node.publishers = make(map[string]*defaultPublisher)
 	node.servers = make(map[string]*defaultServiceServer)
@@ -170,14 +188,23 @@ func newDefaultNode(name string, args []string) (*defaultNode, error) {
 		node.okMutex.Unlock()
 	}()
 
-	node.jobChan = make(chan func(), 100)
+	node.executor = node.options.executor
+	if node.executor == nil {
+		node.executor = newQueueExecutor(logger)
+	}
+	node.timers = make(map[*defaultTimer]struct{})
 
 	logger.Debugf("Master URI = %s", node.masterURI)
 
-	// Set parameters set by arguments
+	// Load the parameter file and then the private parameters set by arguments,
+	// whose values are YAML like with roscpp.
+	if path, ok := specials["__params"]; ok {
+		if err := LoadParamFile(node, "~", path); err != nil {
+			return nil, err
+		}
+	}
 	for k, v := range params {
-		_, err := callRosAPI(node.masterURI, "setParam", node.qualifiedName, k, v)
-		if err != nil {
+		if err := node.SetParam("~"+k, parseParamArgument(v)); err != nil {
 			return nil, err
 		}
 	}
@@ -282,6 +309,8 @@ func (node *defaultNode) publisherUpdate(callerID string, topic string, publishe
 	node.logger.Debug("Slave API publisherUpdate() called.")
 	var code int32
 	var message string
+	node.subscribersMutex.RLock()
+	defer node.subscribersMutex.RUnlock()
 	if sub, ok := node.subscribers[topic]; !ok {
 		node.logger.Debug("publisherUpdate() called without subscribing topic.")
 		code = failureStatus
@@ -309,110 +338,194 @@ func (node *defaultNode) requestTopic(callerID string, topic string, protocols [
 		return buildRosAPIResult(failureStatus, "No such topic", 0), nil
 	}
 
-	selectedProtocol := make([]interface{}, 0)
+	// Protocols are listed in the subscriber's order of preference.
 	for _, v := range protocols {
-		protocolParams := v.([]interface{})
-		protocolName := protocolParams[0].(string)
-		if protocolName == "TCPROS" {
+		protocolParams, ok := v.([]interface{})
+		if !ok || len(protocolParams) == 0 {
+			continue
+		}
+		protocolName, _ := protocolParams[0].(string)
+		switch protocolName {
+		case tcpROSProtocol:
 			node.logger.Debug("TCPROS requested")
-			selectedProtocol = append(selectedProtocol, "TCPROS")
 			host, portStr := pub.hostAndPort()
 			p, err := strconv.ParseInt(portStr, 10, 32)
 			if err != nil {
 				return nil, err
 			}
 			port := int(p)
-			selectedProtocol = append(selectedProtocol, host)
-			selectedProtocol = append(selectedProtocol, port)
-			break
+			selectedProtocol := []interface{}{tcpROSProtocol, host, port}
+			return buildRosAPIResult(successStatus, "Success", selectedProtocol), nil
+
+		case udpROSProtocol:
+			node.logger.Debug("UDPROS requested")
+			selectedProtocol, err := pub.acceptUDPSubscriber(protocolParams)
+			if err != nil {
+				node.logger.Warnf("requestTopic(%s) from %s: %v", topic, callerID, err)
+				return buildRosAPIResult(failureStatus, err.Error(), 0), nil
+			}
+			return buildRosAPIResult(successStatus, "Success", selectedProtocol), nil
 		}
 	}
-	return buildRosAPIResult(successStatus, "Success", selectedProtocol), nil
+	return buildRosAPIResult(failureStatus, "No supported protocol", 0), nil
+}
+
+func (node *defaultNode) NewPublisher(topic string, msgType MessageType, opts ...PublisherOption) Publisher {
+	return node.NewPublisherWithCallbacks(topic, msgType, nil, nil, opts...)
 }
 
-func (node *defaultNode) NewPublisher(topic string, msgType MessageType) Publisher {
-	name := node.resolver.remap(topic)
-	return node.NewPublisherWithCallbacks(name, msgType, nil, nil)
+func (node *defaultNode) NewPublisherWithCallbacks(topic string, msgType MessageType, connectCallback, disconnectCallback func(SingleSubscriberPublisher), opts ...PublisherOption) Publisher {
+	return node.advertise(node.remapName(node.resolver, topic), msgType, connectCallback, disconnectCallback, opts)
 }
 
-func (node *defaultNode) NewPublisherWithCallbacks(topic string, msgType MessageType, connectCallback, disconnectCallback func(SingleSubscriberPublisher)) Publisher {
+// advertise adds a handle to the publisher of the resolved topic name, creating the
+// publisher if it does not exist yet.
+func (node *defaultNode) advertise(name string, msgType MessageType, connectCallback, disconnectCallback func(SingleSubscriberPublisher), opts []PublisherOption) Publisher {
 	node.publishersMutex.Lock()
 	defer node.publishersMutex.Unlock()
 
-	name := node.resolver.remap(topic)
-	pub, ok := node.publishers[topic]
+	pub, ok := node.publishers[name]
 	if !ok {
-		_, err := callRosAPI(node.masterURI, "registerPublisher",
-			node.qualifiedName,
-			name, msgType.Name(),
-			node.xmlrpcURI)
+		_, err := node.master.RegisterPublisher(context.Background(), name, msgType.Name(), node.xmlrpcURI)
 		if err != nil {
 			node.logger.Fatalf("Failed to call registerPublisher(): %s", err)
 		}
 
-		pub = newDefaultPublisher(node, name, msgType, connectCallback, disconnectCallback)
+		pub = newDefaultPublisher(node, name, msgType, opts...)
 		node.publishers[name] = pub
+		node.waitGroup.Add(1)
 		go pub.start(&node.waitGroup)
 	}
 
-	return pub
+	id := pub.addCallbacks(connectCallback, disconnectCallback)
+	return &publisherHandle{node: node, pub: pub, id: id}
+}
+
+// unadvertise releases a publisher handle. The publisher is shut down with its last handle.
+func (node *defaultNode) unadvertise(pub *defaultPublisher, id int) {
+	node.publishersMutex.Lock()
+	defer node.publishersMutex.Unlock()
+
+	if node.publishers[pub.topic] != pub {
+		// Already shut down with the node.
+		return
+	}
+	if pub.removeCallbacks(id) == 0 {
+		delete(node.publishers, pub.topic)
+		pub.Shutdown()
+	}
+}
+
+func (node *defaultNode) NewSubscriber(topic string, msgType MessageType, callback interface{}, opts ...SubscriberOption) Subscriber {
+	if err := nodeimpl.CheckMessageCallback(callback, msgType.NewMessage(), MessageEvent{}); err != nil {
+		node.logger.Errorf("NewSubscriber(%s): %v", topic, err)
+		callback = nil
+	}
+	return node.subscribe(node.remapName(node.resolver, topic), msgType, callback, nil, opts)
 }
 
-func (node *defaultNode) NewSubscriber(topic string, msgType MessageType, callback interface{}) Subscriber {
+func (node *defaultNode) NewSubscriberChan(topic string, msgType MessageType, bufferSize int, policy DropPolicy, opts ...SubscriberOption) (<-chan ReceivedMessage, Subscriber) {
+	channel := newMessageChannel(bufferSize, policy)
+	return channel.ch, node.subscribe(node.remapName(node.resolver, topic), msgType, nil, channel, opts)
+}
+
+// subscribe adds a callback or a channel to the subscriber of the resolved topic name,
+// creating the subscriber if it does not exist yet. Each call returns its own handle.
+func (node *defaultNode) subscribe(name string, msgType MessageType, callback interface{}, channel *messageChannel, opts []SubscriberOption) Subscriber {
+	statistics := node.statisticsConfig()
 	node.subscribersMutex.Lock()
 	defer node.subscribersMutex.Unlock()
 
-	name := node.resolver.remap(topic)
 	logger := node.logger
 
 	sub, ok := node.subscribers[name]
 	if !ok {
 		logger.Debug("Call Master API registerSubscriber")
-		result, err := callRosAPI(node.masterURI, "registerSubscriber", node.qualifiedName, name, msgType.Name(), node.xmlrpcURI)
+		publishers, err := node.master.RegisterSubscriber(context.Background(), name, msgType.Name(), node.xmlrpcURI)
 		if err != nil {
 			logger.Fatalf("Failed to call registerSubscriber() for %s.", err)
 		}
-		list, ok := result.([]interface{})
-		if !ok {
-			logger.Fatalf("result is not []string but %s.", reflect.TypeOf(result).String())
-		}
-		var publishers []string
-		for _, item := range list {
-			s, ok := item.(string)
-			if !ok {
-				logger.Fatal("Publisher list contains no string object")
-			}
-			publishers = append(publishers, s)
-		}
 
 		logger.Debugf("Publisher URI list: %+v", publishers)
 
-		sub = newDefaultSubscriber(name, msgType, callback)
+		sub = newDefaultSubscriber(name, msgType, callback, opts...)
+		if channel != nil {
+			sub.channels = append(sub.channels, channel)
+		}
+		if monitor := NewSubscriptionMonitor(name, node.executor, opts...); monitor != nil {
+			sub.monitors = append(sub.monitors, handleMonitor{0, monitor})
+		}
+		sub.hostname = node.hostname
+		sub.listenIP = node.listenIP
+		sub.maxHeaderSize = node.options.maxHeaderSize
+		sub.maxMessageSize = node.options.maxMessageSize
+		sub.statistics = node.newStatisticsLogger(name, msgType, statistics)
 		node.subscribers[name] = sub
 
 		logger.Debugf("Start subscriber goroutine for topic '%s'", sub.topic)
-		go sub.start(&node.waitGroup, node.qualifiedName, node.xmlrpcURI, node.masterURI, node.jobChan, logger)
+		node.waitGroup.Add(1)
+		go sub.start(&node.waitGroup, node.qualifiedName, node.xmlrpcURI, node.masterURI, node.executor, logger)
 		logger.Debugf("Done")
 		sub.pubListChan <- publishers
 		logger.Debugf("Update publisher list for topic '%s'", sub.topic)
-	} else {
-		sub.callbacks = append(sub.callbacks, callback)
+		return &subscriberHandle{node: node, sub: sub, id: 0}
 	}
 
-	return sub
+	id := sub.handleIDCount
+	sub.handleIDCount++
+	sub.numHandles++
+	update := subscriberHandleUpdate{handleID: id, callback: callback, channel: channel}
+	if channel != nil {
+		channel.handleID = id
+	}
+	if options := newSubscriberOptions(opts); options.publisherCallbacks != nil {
+		update.publisherCallbacks = options.publisherCallbacks
+		update.publisherCallbacks.handleID = id
+	}
+	update.monitor = NewSubscriptionMonitor(name, node.executor, opts...)
+	sub.queueHandleUpdate(update)
+	return &subscriberHandle{node: node, sub: sub, id: id}
+}
+
+// unsubscribe releases a subscriber handle. The subscriber is shut down with its last handle.
+func (node *defaultNode) unsubscribe(sub *defaultSubscriber, id int) {
+	node.subscribersMutex.Lock()
+	defer node.subscribersMutex.Unlock()
+
+	if node.subscribers[sub.topic] != sub {
+		// Already shut down with the node.
+		return
+	}
+	sub.numHandles--
+	if sub.numHandles == 0 {
+		delete(node.subscribers, sub.topic)
+		sub.Shutdown()
+	} else {
+		sub.queueHandleUpdate(subscriberHandleUpdate{handleID: id, remove: true})
+	}
 }
 
 func (node *defaultNode) NewServiceClient(service string, srvType ServiceType) ServiceClient {
-	name := node.resolver.remap(service)
+	return node.newServiceClient(node.remapName(node.resolver, service), srvType)
+}
+
+func (node *defaultNode) newServiceClient(name string, srvType ServiceType) ServiceClient {
 	client := newDefaultServiceClient(node.logger, node.qualifiedName, node.masterURI, name, srvType)
+	client.maxHeaderSize = node.options.maxHeaderSize
+	client.maxMessageSize = node.options.maxMessageSize
 	return client
 }
 
 func (node *defaultNode) NewServiceServer(service string, srvType ServiceType, handler interface{}) ServiceServer {
+	return node.newServiceServer(node.remapName(node.resolver, service), srvType, handler)
+}
+
+// newServiceServer advertises the resolved service name, replacing the server that
+// advertised it before.
+func (node *defaultNode) newServiceServer(name string, srvType ServiceType, handler interface{}) ServiceServer {
 	node.serversMutex.Lock()
 	defer node.serversMutex.Unlock()
 
-	name := node.resolver.remap(service)
 	server, ok := node.servers[name]
 	if ok {
 		server.Shutdown()
@@ -427,28 +540,35 @@ func (node *defaultNode) NewServiceServer(service string, srvType ServiceType, h
 	return server
 }
 
-func (node *defaultNode) SpinOnce() {
-	timeoutChan := time.After(10 * time.Millisecond)
-	select {
-	case job := <-node.jobChan:
-		job()
-	case <-timeoutChan:
-		break
+// Clock returns the clock of the node's executor.
+func (node *defaultNode) Clock() Clock {
+	return node.executor.Clock()
+}
+
+func (node *defaultNode) NewTimer(period time.Duration, callback func(TimerEvent)) Timer {
+	timer := newDefaultTimer(node.executor, period, callback)
+	if period <= 0 {
+		node.logger.Errorf("NewTimer: the period must be positive but is %v", period)
+		return timer
+	}
+	timer.OnStop = func() {
+		node.timersMutex.Lock()
+		delete(node.timers, timer)
+		node.timersMutex.Unlock()
 	}
+	node.timersMutex.Lock()
+	node.timers[timer] = struct{}{}
+	node.timersMutex.Unlock()
+	timer.Start()
+	return timer
+}
+
+func (node *defaultNode) SpinOnce() {
+	node.executor.SpinOnce()
 }
 
 func (node *defaultNode) Spin() {
-	logger := node.logger
-	for node.OK() {
-		timeoutChan := time.After(1000 * time.Millisecond)
-		select {
-		case job := <-node.jobChan:
-			logger.Debug("Execute job")
-			job()
-		case <-timeoutChan:
-			break
-		}
-	}
+	node.executor.Spin(node.OK)
 }
 
 func (node *defaultNode) Shutdown() {
@@ -456,15 +576,29 @@ func (node *defaultNode) Shutdown() {
 	node.okMutex.Lock()
 	node.ok = false
 	node.okMutex.Unlock()
+	node.logger.Debug("Stop timers")
+	node.timersMutex.Lock()
+	timers := node.timers
+	node.timers = make(map[*defaultTimer]struct{})
+	node.timersMutex.Unlock()
+	for t := range timers {
+		t.Stop()
+	}
 	node.logger.Debug("Shutdown subscribers")
-	for _, s := range node.subscribers {
+	node.subscribersMutex.Lock()
+	for t, s := range node.subscribers {
 		s.Shutdown()
+		delete(node.subscribers, t)
 	}
+	node.subscribersMutex.Unlock()
 	node.logger.Debug("Shutdown subscribers...done")
 	node.logger.Debug("Shutdown publishers")
-	for _, p := range node.publishers {
+	node.publishersMutex.Lock()
+	for t, p := range node.publishers {
 		p.Shutdown()
+		delete(node.publishers, t)
 	}
+	node.publishersMutex.Unlock()
 	node.logger.Debug("Shutdown publishers...done")
 	node.logger.Debug("Shutdown servers")
 	for _, s := range node.servers {
@@ -485,39 +619,63 @@ func (node *defaultNode) Shutdown() {
 }
 
 func (node *defaultNode) GetParam(key string) (interface{}, error) {
-	name := node.resolver.remap(key)
-	return callRosAPI(node.masterURI, "getParam", node.qualifiedName, name)
+	return node.getParam(node.resolver, key)
 }
 
 func (node *defaultNode) SetParam(key string, value interface{}) error {
-	name := node.resolver.remap(key)
-	_, e := callRosAPI(node.masterURI, "setParam", node.qualifiedName, name, value)
-	return e
+	return node.setParam(node.resolver, key, value)
 }
 
 func (node *defaultNode) HasParam(key string) (bool, error) {
-	name := node.resolver.remap(key)
-	result, err := callRosAPI(node.masterURI, "hasParam", node.qualifiedName, name)
+	return node.hasParam(node.resolver, key)
+}
+
+func (node *defaultNode) SearchParam(key string) (string, error) {
+	return searchParam(node.master, node.resolver, key)
+}
+
+func (node *defaultNode) DeleteParam(key string) error {
+	return node.deleteParam(node.resolver, key)
+}
+
+func (node *defaultNode) getParam(resolver *nameResolver, key string) (interface{}, error) {
+	name, err := resolver.resolveName(key, true)
+	if err != nil {
+		return nil, err
+	}
+	return node.master.GetParam(context.Background(), name)
+}
+
+func (node *defaultNode) setParam(resolver *nameResolver, key string, value interface{}) error {
+	name, err := resolver.resolveName(key, true)
+	if err != nil {
+		return err
+	}
+	return node.master.SetParam(context.Background(), name, value)
+}
+
+func (node *defaultNode) hasParam(resolver *nameResolver, key string) (bool, error) {
+	name, err := resolver.resolveName(key, true)
 	if err != nil {
 		return false, err
 	}
-	hasParam := result.(bool)
-	return hasParam, nil
+	return node.master.HasParam(context.Background(), name)
 }
 
-func (node *defaultNode) SearchParam(key string) (string, error) {
-	result, err := callRosAPI(node.masterURI, "searchParam", node.qualifiedName, key)
+func (node *defaultNode) deleteParam(resolver *nameResolver, key string) error {
+	name, err := resolver.resolveName(key, true)
 	if err != nil {
-		return "", err
+		return err
 	}
-	foundKey := result.(string)
-	return foundKey, nil
+	return node.master.DeleteParam(context.Background(), name)
 }
 
-func (node *defaultNode) DeleteParam(key string) error {
-	name := node.resolver.remap(key)
-	_, err := callRosAPI(node.masterURI, "deleteParam", node.qualifiedName, name)
-	return err
+// searchParam asks master to search key upwards from the namespace of its caller ID.
+func searchParam(master *MasterClient, resolver *nameResolver, key string) (string, error) {
+	if err := ValidateName(key); err != nil {
+		return "", err
+	}
+	return master.SearchParam(context.Background(), resolver.searchKey(key))
 }
 
 func (node *defaultNode) Logger() Logger {
@@ -536,12 +694,40 @@ func (node *defaultNode) Name() string {
 	return node.name
 }
 
-func loadParamFromString(s string) (interface{}, error) {
-	decoder := json.NewDecoder(strings.NewReader(s))
-	var value interface{}
-	err := decoder.Decode(&value)
+func (node *defaultNode) Namespace(ns string, remappings NameMap) Node {
+	return newNamespacedNode(node, node.childResolver(node.resolver, ns, remappings))
+}
+
+func (node *defaultNode) ResolveName(name string, remap bool) (string, error) {
+	return node.resolver.resolveName(name, remap)
+}
+
+func (node *defaultNode) AddRemapping(from string, to string) error {
+	return node.resolver.addRemapping(from, to)
+}
+
+// remapName resolves and remaps a name passed to a method that cannot return an error.
+// Invalid names are logged and resolved nonetheless.
+func (node *defaultNode) remapName(resolver *nameResolver, name string) string {
+	resolved, err := resolver.resolveName(name, true)
 	if err != nil {
-		return nil, err
+		node.logger.Errorf("Resolving %s: %v", name, err)
+		return resolver.remap(name)
+	}
+	return resolved
+}
+
+// childResolver returns the resolver of a namespace view. Invalid names are logged and
+// their remappings are skipped.
+func (node *defaultNode) childResolver(resolver *nameResolver, ns string, remappings NameMap) *nameResolver {
+	if err := ValidateName(ns); err != nil {
+		node.logger.Errorf("Namespace %s: %v", ns, err)
+	}
+	child := resolver.child(ns, nil)
+	for from, to := range remappings {
+		if err := child.addRemapping(from, to); err != nil {
+			node.logger.Errorf("Namespace %s: remapping %s:=%s: %v", ns, from, to, err)
+		}
 	}
-	return value, err
+	return child
 }
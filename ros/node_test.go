@@ -2,21 +2,30 @@ package ros
 
 import (
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
 
-func TestLoadJsonFromString(t *testing.T) {
-	value, err := loadParamFromString("42")
-	if err != nil {
-		t.Error(err)
-	}
-	i, ok := value.(float64)
-	if !ok {
-		t.Fail()
+func TestParseParamArgument(t *testing.T) {
+	cases := []struct {
+		arg      string
+		expected interface{}
+	}{
+		{"42", int32(42)},
+		{"4.2", 4.2},
+		{"true", true},
+		{"hello", "hello"},
+		{"'42'", "42"},
+		{"", ""},
+		{"[1, 2]", []interface{}{int32(1), int32(2)}},
+		{"{a: 1}", map[string]interface{}{"a": int32(1)}},
+		{"[unclosed", "[unclosed"},
 	}
-	if i != 42.0 {
-		t.Error(i)
+	for _, c := range cases {
+		if value := parseParamArgument(c.arg); !reflect.DeepEqual(value, c.expected) {
+			t.Errorf("parseParamArgument(%q) = %#v, expected %#v", c.arg, value, c.expected)
+		}
 	}
 }
 
@@ -371,7 +380,7 @@ func TestNodeAPI(t *testing.T) {
 			msgType := &dummyMessage{}
 			valueWanted := []interface{}{[]interface{}{topic, msgType.Name()}}
 
-			pub := newDefaultPublisher(node, topic, msgType, nil, nil)
+			pub := newDefaultPublisher(node, topic, msgType)
 			node.publishers["/test_topic"] = pub
 
 			result, err := node.getPublications("test_caller")
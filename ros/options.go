@@ -0,0 +1,270 @@
+package ros
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	defaultPublisherQueueSize = 100
+	defaultPublishTimeout     = 100 * time.Millisecond
+	defaultMaxHeaderSize      = 1 << 20
+	defaultMaxMessageSize     = 256 << 20
+)
+
+// DropPolicy defines which message is discarded when a bounded message queue is full.
+type DropPolicy int
+
+const (
+	// DropOldest discards the oldest queued message to make room for the new one.
+	DropOldest DropPolicy = iota
+	// DropNewest discards the new message and keeps the queue as it is.
+	DropNewest
+)
+
+// NodeOption configures a node created by NewNode.
+type NodeOption func(*nodeOptions)
+
+type nodeOptions struct {
+	maxHeaderSize  int
+	maxMessageSize int
+	executor       Executor
+}
+
+func newNodeOptions(opts []NodeOption) nodeOptions {
+	options := nodeOptions{
+		maxHeaderSize:  defaultMaxHeaderSize,
+		maxMessageSize: defaultMaxMessageSize,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithMaxHeaderSize sets the largest connection header, in bytes, the node accepts from
+// remote publishers, subscribers and service peers. Zero or a negative size disables the limit.
+func WithMaxHeaderSize(size int) NodeOption {
+	return func(o *nodeOptions) {
+		o.maxHeaderSize = size
+	}
+}
+
+// WithMaxMessageSize sets the largest message, service request or service response, in bytes,
+// the node accepts from remote peers. Zero or a negative size disables the limit.
+func WithMaxMessageSize(size int) NodeOption {
+	return func(o *nodeOptions) {
+		o.maxMessageSize = size
+	}
+}
+
+// WithExecutor replaces the executor that runs the node's subscriber, service and timer
+// callbacks. Tests can pass a ManualExecutor to run callbacks step by step.
+func WithExecutor(executor Executor) NodeOption {
+	return func(o *nodeOptions) {
+		o.executor = executor
+	}
+}
+
+// PublisherOption configures a publisher created by Node.NewPublisher or
+// Node.NewPublisherWithCallbacks.
+type PublisherOption func(*publisherOptions)
+
+type publisherOptions struct {
+	queueSize      int
+	dropPolicy     DropPolicy
+	publishTimeout time.Duration
+	headerSequence bool
+	headerStamp    bool
+	headerFrameID  string
+}
+
+func newPublisherOptions(opts []PublisherOption) publisherOptions {
+	options := publisherOptions{
+		queueSize:      defaultPublisherQueueSize,
+		dropPolicy:     DropOldest,
+		publishTimeout: defaultPublishTimeout,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.queueSize < 1 {
+		options.queueSize = 1
+	}
+	if options.publishTimeout < 0 {
+		options.publishTimeout = 0
+	}
+	return options
+}
+
+// WithQueueSize sets the number of outgoing messages buffered for each remote subscriber.
+// When a subscriber falls behind, messages are dropped according to the drop policy.
+func WithQueueSize(size int) PublisherOption {
+	return func(o *publisherOptions) {
+		o.queueSize = size
+	}
+}
+
+// WithDropPolicy sets which message is discarded when a remote subscriber's queue is full.
+func WithDropPolicy(policy DropPolicy) PublisherOption {
+	return func(o *publisherOptions) {
+		o.dropPolicy = policy
+	}
+}
+
+// WithPublishTimeout sets the longest time Publish may block handing a message over to
+// the publisher goroutine. The message is dropped once the timeout expires. A zero timeout
+// makes Publish never block.
+func WithPublishTimeout(timeout time.Duration) PublisherOption {
+	return func(o *publisherOptions) {
+		o.publishTimeout = timeout
+	}
+}
+
+// WithHeaderSequence makes Publish number the messages with a std_msgs/Header, like
+// roscpp publishers do: the seq of the header is set to the number of messages published
+// before, counting from zero. Publish sets the field of the message itself. Handles of
+// the same topic share the publisher set up by the first one, and with it the count.
+func WithHeaderSequence() PublisherOption {
+	return func(o *publisherOptions) {
+		o.headerSequence = true
+	}
+}
+
+// WithHeaderStamp makes Publish set the stamp of messages with a std_msgs/Header to the
+// time of the node's clock if it is zero.
+func WithHeaderStamp() PublisherOption {
+	return func(o *publisherOptions) {
+		o.headerStamp = true
+	}
+}
+
+// WithHeaderFrameID makes Publish set the frame_id of messages with a std_msgs/Header
+// to frameID if it is empty.
+func WithHeaderFrameID(frameID string) PublisherOption {
+	return func(o *publisherOptions) {
+		o.headerFrameID = frameID
+	}
+}
+
+// SubscriberOption configures a subscriber created by Node.NewSubscriber.
+// Options only take effect when the first subscriber to a topic is created.
+type SubscriberOption func(*subscriberOptions)
+
+type subscriberOptions struct {
+	transportHints     *TransportHints
+	publisherCallbacks *publisherCallbacks
+	deadline           *deadlineOptions
+	liveliness         *livelinessOptions
+}
+
+type deadlineOptions struct {
+	period            time.Duration
+	missed, recovered func(DeadlineEvent)
+}
+
+type livelinessOptions struct {
+	minPublishers   int
+	lost, recovered func(LivelinessEvent)
+}
+
+func newSubscriberOptions(opts []SubscriberOption) subscriberOptions {
+	options := subscriberOptions{
+		transportHints: NewTransportHints(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithTransportHints sets the transports the subscriber asks its publishers for.
+func WithTransportHints(hints *TransportHints) SubscriberOption {
+	return func(o *subscriberOptions) {
+		if hints != nil {
+			o.transportHints = hints
+		}
+	}
+}
+
+// WithPublisherCallbacks sets callbacks that are called when a connection to a publisher of
+// the topic is established, when an established connection is lost and when a publisher
+// cannot be connected to, e.g. because of an md5sum mismatch. Any of the callbacks may be nil.
+// The callbacks are called in their own goroutines. Unlike other options, callbacks passed to
+// later subscribers of the same topic are registered as well and are told about the publishers
+// that are already connected.
+func WithPublisherCallbacks(connected, disconnected, rejected func(PublisherEvent)) SubscriberOption {
+	return func(o *subscriberOptions) {
+		o.publisherCallbacks = &publisherCallbacks{connected: connected, disconnected: disconnected, rejected: rejected}
+	}
+}
+
+// WithDeadline sets the longest time expected between two messages of the topic, like
+// the deadline QoS policy of ROS 2. missed is called every period that passes without
+// a message, starting one period after the subscriber is created, and recovered with
+// the next message after that. Either callback may be nil. The callbacks run on the
+// executor of the node like subscriber callbacks, and periods follow its clock. Like a
+// timer callback, missed is queued at most once: periods missed while it is queued are
+// reported by that call.
+// A period that is not positive disables the deadline. Like WithPublisherCallbacks,
+// the option takes effect for every subscriber of a topic it is passed to.
+func WithDeadline(period time.Duration, missed, recovered func(DeadlineEvent)) SubscriberOption {
+	return func(o *subscriberOptions) {
+		if period > 0 {
+			o.deadline = &deadlineOptions{period: period, missed: missed, recovered: recovered}
+		} else {
+			o.deadline = nil
+		}
+	}
+}
+
+// WithLiveliness sets the number of publishers the topic is expected to have, like the
+// liveliness QoS events of ROS 2. lost is called when fewer publishers than
+// minPublishers are connected after the minimum was reached, and recovered when it is
+// reached again. Either callback may be nil. The callbacks run on the executor of the
+// node like subscriber callbacks. A minimum below one is taken as one. Like
+// WithPublisherCallbacks, the option takes effect for every subscriber of a topic it is
+// passed to.
+func WithLiveliness(minPublishers int, lost, recovered func(LivelinessEvent)) SubscriberOption {
+	return func(o *subscriberOptions) {
+		if minPublishers < 1 {
+			minPublishers = 1
+		}
+		o.liveliness = &livelinessOptions{minPublishers: minPublishers, lost: lost, recovered: recovered}
+	}
+}
+
+// MasterClientOption configures a client created by NewMasterClient.
+type MasterClientOption func(*masterClientOptions)
+
+type masterClientOptions struct {
+	httpClient *http.Client
+	timeout    time.Duration
+}
+
+func newMasterClientOptions(opts []MasterClientOption) masterClientOptions {
+	options := masterClientOptions{
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithHTTPClient sets the HTTP client used to call the master, e.g. to configure the transport.
+func WithHTTPClient(client *http.Client) MasterClientOption {
+	return func(o *masterClientOptions) {
+		if client != nil {
+			o.httpClient = client
+		}
+	}
+}
+
+// WithCallTimeout bounds the duration of every call to the master, in addition to the deadline
+// of the call's context. Zero or a negative timeout disables the limit.
+func WithCallTimeout(timeout time.Duration) MasterClientOption {
+	return func(o *masterClientOptions) {
+		o.timeout = timeout
+	}
+}
@@ -0,0 +1,461 @@
+package ros
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// GetParamInt returns the integer parameter name, or def if the parameter is not set.
+func GetParamInt(node Node, name string, def int) (int, error) {
+	return getTypedParam(node, name, def)
+}
+
+// GetParamFloat returns the floating point parameter name, or def if the parameter is not set.
+// Integer parameters are converted.
+func GetParamFloat(node Node, name string, def float64) (float64, error) {
+	return getTypedParam(node, name, def)
+}
+
+// GetParamString returns the string parameter name, or def if the parameter is not set.
+func GetParamString(node Node, name string, def string) (string, error) {
+	return getTypedParam(node, name, def)
+}
+
+// GetParamBool returns the boolean parameter name, or def if the parameter is not set.
+func GetParamBool(node Node, name string, def bool) (bool, error) {
+	return getTypedParam(node, name, def)
+}
+
+// GetParamStringSlice returns the list of strings name, or def if the parameter is not set.
+func GetParamStringSlice(node Node, name string, def []string) ([]string, error) {
+	return getTypedParam(node, name, def)
+}
+
+// GetParamFloatSlice returns the list of numbers name, or def if the parameter is not set.
+func GetParamFloatSlice(node Node, name string, def []float64) ([]float64, error) {
+	return getTypedParam(node, name, def)
+}
+
+// GetParamMap returns the parameter namespace name as a map, or def if it is not set.
+// Nested namespaces are nested maps.
+func GetParamMap(node Node, name string, def map[string]interface{}) (map[string]interface{}, error) {
+	return getTypedParam(node, name, def)
+}
+
+// getTypedParam returns the parameter name converted to T. An error is returned along
+// with def if the parameter cannot be converted.
+func getTypedParam[T any](node Node, name string, def T) (T, error) {
+	value, err := node.GetParam(name)
+	if isParamNotSet(err) {
+		return def, nil
+	}
+	if err != nil {
+		return def, err
+	}
+	var result T
+	if err := decodeParam(name, value, reflect.ValueOf(&result).Elem(), nil); err != nil {
+		return def, err
+	}
+	return result, nil
+}
+
+// isParamNotSet reports whether err is how the master answers getParam for a parameter
+// that is not set. rosmaster reports it with an ERROR status, which a malformed call gets
+// too, so the status message tells them apart.
+func isParamNotSet(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.IsFailure() || strings.HasSuffix(apiErr.Message, "is not set")
+}
+
+// LoadParams binds the parameter namespace to the struct v points to.
+//
+// Each exported field is read from the parameter named by its `param` tag, or by the
+// field name in snake_case if there is no tag. A tag of "-" skips the field, and a
+// ",required" option makes the field mandatory. Nested structs are read from nested
+// namespaces, slices from lists and maps from namespaces. A time.Duration is read
+// as a number of seconds, and integer fields also accept whole float64 values, as
+// integers outside the int32 range of XML-RPC are stored. Fields whose parameter is
+// not set keep their value, so v may hold defaults.
+//
+// Missing required parameters are reported together in a *MissingParamError after
+// all other fields are loaded.
+func LoadParams(node Node, namespace string, v interface{}) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("LoadParams needs a pointer to a struct but got %T", v)
+	}
+	values := map[string]interface{}{}
+	value, err := node.GetParam(namespace)
+	if err != nil && !isParamNotSet(err) {
+		return err
+	}
+	if err == nil {
+		var ok bool
+		if values, ok = value.(map[string]interface{}); !ok {
+			return fmt.Errorf("parameter %s is not a namespace", namespace)
+		}
+	}
+	var missing []string
+	if err := decodeStruct(namespace, values, target.Elem(), &missing); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return &MissingParamError{Names: missing}
+	}
+	return nil
+}
+
+// StoreParams sets the parameters of namespace from the struct v, or the pointer to a
+// struct, following the rules of LoadParams. Integers that do not fit the int32 of
+// XML-RPC are stored as float64, as ParseParamYAML does.
+func StoreParams(node Node, namespace string, v interface{}) error {
+	source := reflect.ValueOf(v)
+	if source.Kind() == reflect.Ptr {
+		source = source.Elem()
+	}
+	if source.Kind() != reflect.Struct {
+		return fmt.Errorf("StoreParams needs a struct but got %T", v)
+	}
+	values := map[string]interface{}{}
+	if err := encodeStruct(namespace, source, values); err != nil {
+		return err
+	}
+	return node.SetParam(namespace, values)
+}
+
+// paramField describes how a struct field is bound to a parameter.
+type paramField struct {
+	name     string
+	required bool
+	skip     bool
+	inline   bool
+}
+
+func parseParamField(field reflect.StructField) paramField {
+	tag, hasTag := field.Tag.Lookup("param")
+	embedded := field.Anonymous && field.Type.Kind() == reflect.Struct
+	if tag == "-" || field.PkgPath != "" && !embedded {
+		return paramField{skip: true}
+	}
+	if embedded && !hasTag {
+		return paramField{inline: true}
+	}
+	parts := strings.Split(tag, ",")
+	result := paramField{name: parts[0]}
+	if result.name == "" {
+		result.name = snakeCase(field.Name)
+	}
+	for _, option := range parts[1:] {
+		if option == "required" {
+			result.required = true
+		}
+	}
+	return result
+}
+
+// ParamFieldName returns the name of the parameter that LoadParams and StoreParams bind
+// the struct field to, or "" if the field is skipped or an inlined embedded struct.
+func ParamFieldName(field reflect.StructField) string {
+	return parseParamField(field).name
+}
+
+// snakeCase converts a Go identifier such as MaxSpeed or HTTPPort to max_speed or http_port.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// joinParamName appends name to namespace. Names in the private namespace "~" are
+// appended without a slash, as "~/name" is not a valid name.
+func joinParamName(namespace string, name string) string {
+	if namespace == "" || namespace == PrivateNS {
+		return namespace + name
+	}
+	return strings.TrimSuffix(namespace, "/") + "/" + name
+}
+
+func decodeStruct(namespace string, values map[string]interface{}, target reflect.Value, missing *[]string) error {
+	t := target.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := parseParamField(t.Field(i))
+		switch {
+		case field.skip:
+			continue
+		case field.inline:
+			if err := decodeStruct(namespace, values, target.Field(i), missing); err != nil {
+				return err
+			}
+			continue
+		}
+		name := joinParamName(namespace, field.name)
+		value, ok := values[field.name]
+		if !ok {
+			if field.required {
+				*missing = append(*missing, name)
+			}
+			if target.Field(i).Kind() == reflect.Struct && t.Field(i).Type != reflect.TypeOf(time.Time{}) {
+				// Report the required fields of a missing nested namespace too.
+				if err := decodeStruct(name, map[string]interface{}{}, target.Field(i), missing); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := decodeParam(name, value, target.Field(i), missing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeParam stores the parameter value into target, converting XML-RPC's int32 and
+// float64 values to the kind of target.
+func decodeParam(name string, value interface{}, target reflect.Value, missing *[]string) error {
+	mismatch := func() error {
+		return fmt.Errorf("parameter %s is %T and cannot be stored in %s", name, value, target.Type())
+	}
+	if target.Type() == reflect.TypeOf(time.Duration(0)) {
+		seconds, ok := toFloat(value)
+		if !ok {
+			return mismatch()
+		}
+		target.SetInt(int64(seconds * float64(time.Second)))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		if value == nil {
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+		if !reflect.TypeOf(value).AssignableTo(target.Type()) {
+			return mismatch()
+		}
+		target.Set(reflect.ValueOf(value))
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch()
+		}
+		target.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := toWholeInt(value)
+		if !ok || target.OverflowInt(i) {
+			return mismatch()
+		}
+		target.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ok := toWholeInt(value)
+		if !ok || i < 0 || target.OverflowUint(uint64(i)) {
+			return mismatch()
+		}
+		target.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat(value)
+		if !ok {
+			return mismatch()
+		}
+		target.SetFloat(f)
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch()
+		}
+		target.SetString(s)
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeParam(fmt.Sprintf("%s[%d]", name, i), item, slice.Index(i), missing); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+	case reflect.Map:
+		values, ok := value.(map[string]interface{})
+		if !ok || target.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		m := reflect.MakeMapWithSize(target.Type(), len(values))
+		for key, item := range values {
+			elem := reflect.New(target.Type().Elem()).Elem()
+			if err := decodeParam(joinParamName(name, key), item, elem, missing); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(target.Type().Key()), elem)
+		}
+		target.Set(m)
+	case reflect.Struct:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		if missing == nil {
+			missing = &[]string{}
+		}
+		return decodeStruct(name, values, target, missing)
+	case reflect.Ptr:
+		elem := reflect.New(target.Type().Elem())
+		if err := decodeParam(name, value, elem.Elem(), missing); err != nil {
+			return err
+		}
+		target.Set(elem)
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+func toInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int32:
+		return int64(v), true
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+// toWholeInt is toInt that also accepts a float64 holding a whole number, the encoding of
+// integers outside the int32 range.
+func toWholeInt(value interface{}) (int64, bool) {
+	if f, ok := value.(float64); ok {
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(f), true
+	}
+	return toInt(value)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	if i, ok := toInt(value); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+func encodeStruct(namespace string, source reflect.Value, values map[string]interface{}) error {
+	t := source.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := parseParamField(t.Field(i))
+		switch {
+		case field.skip:
+			continue
+		case field.inline:
+			if err := encodeStruct(namespace, source.Field(i), values); err != nil {
+				return err
+			}
+			continue
+		}
+		name := joinParamName(namespace, field.name)
+		value, err := encodeParam(name, source.Field(i))
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			values[field.name] = value
+		}
+	}
+	return nil
+}
+
+// encodeParam converts source to a value the parameter server accepts. Nil pointers,
+// slices and maps yield nil and are not stored, and integers outside the int32 range
+// are converted to float64.
+func encodeParam(name string, source reflect.Value) (interface{}, error) {
+	if source.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(source.Int()).Seconds(), nil
+	}
+	switch source.Kind() {
+	case reflect.Bool:
+		return source.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := source.Int(); i < math.MinInt32 || i > math.MaxInt32 {
+			return float64(i), nil
+		}
+		return int(source.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i := source.Uint(); i > math.MaxInt32 {
+			return float64(i), nil
+		}
+		return int(source.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return source.Float(), nil
+	case reflect.String:
+		return source.String(), nil
+	case reflect.Interface, reflect.Ptr:
+		if source.IsNil() {
+			return nil, nil
+		}
+		return encodeParam(name, source.Elem())
+	case reflect.Slice, reflect.Array:
+		if source.Kind() == reflect.Slice && source.IsNil() {
+			return nil, nil
+		}
+		items := make([]interface{}, source.Len())
+		for i := range items {
+			item, err := encodeParam(fmt.Sprintf("%s[%d]", name, i), source.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Map:
+		if source.IsNil() {
+			return nil, nil
+		}
+		if source.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("parameter %s must have string keys", name)
+		}
+		values := make(map[string]interface{}, source.Len())
+		iter := source.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			value, err := encodeParam(joinParamName(name, key), iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			values[key] = value
+		}
+		return values, nil
+	case reflect.Struct:
+		values := map[string]interface{}{}
+		if err := encodeStruct(name, source, values); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("parameter %s has unsupported type %s", name, source.Type())
+}
@@ -0,0 +1,98 @@
+package ros
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"Rate":       "rate",
+		"MaxSpeed":   "max_speed",
+		"HTTPPort":   "http_port",
+		"FrameID":    "frame_id",
+		"Use3DScans": "use3d_scans",
+	}
+	for name, expected := range cases {
+		if actual := snakeCase(name); actual != expected {
+			t.Errorf("snakeCase(%s) = %s, expected %s", name, actual, expected)
+		}
+	}
+}
+
+func TestIsParamNotSet(t *testing.T) {
+	_, server := newStandInMaster(t)
+	client := NewMasterClient(server.URL, "/client")
+	if _, err := client.GetParam(context.Background(), "/missing"); !isParamNotSet(err) {
+		t.Errorf("Expected %v to tell that the parameter is not set", err)
+	}
+	malformed := &APIError{Method: "getParam", Code: errorStatus, Message: "ERROR: parameter [key] must be a non-empty string"}
+	for _, err := range []error{nil, malformed, errors.New("connection refused")} {
+		if isParamNotSet(err) {
+			t.Errorf("Expected %v not to tell that the parameter is not set", err)
+		}
+	}
+}
+
+func TestDecodeParamConversions(t *testing.T) {
+	var i int
+	if err := decodeParam("/i", int32(7), reflect.ValueOf(&i).Elem(), nil); err != nil || i != 7 {
+		t.Errorf("Expected int32 to convert to int: %d, %v", i, err)
+	}
+	var small int8
+	if err := decodeParam("/small", int32(1000), reflect.ValueOf(&small).Elem(), nil); err == nil {
+		t.Error("Expected an overflow to be rejected")
+	}
+	var f float32
+	if err := decodeParam("/f", int32(2), reflect.ValueOf(&f).Elem(), nil); err != nil || f != 2 {
+		t.Errorf("Expected int32 to convert to float32: %v, %v", f, err)
+	}
+	if err := decodeParam("/i", 1.5, reflect.ValueOf(&i).Elem(), nil); err == nil {
+		t.Error("Expected a float not to convert to int")
+	}
+	var d time.Duration
+	if err := decodeParam("/d", 0.25, reflect.ValueOf(&d).Elem(), nil); err != nil || d != 250*time.Millisecond {
+		t.Errorf("Expected seconds to convert to a duration: %v, %v", d, err)
+	}
+	var list []float64
+	if err := decodeParam("/list", []interface{}{int32(1), 2.5}, reflect.ValueOf(&list).Elem(), nil); err != nil ||
+		!reflect.DeepEqual(list, []float64{1, 2.5}) {
+		t.Errorf("Unexpected list %v, %v", list, err)
+	}
+	var m map[string]int
+	if err := decodeParam("/m", map[string]interface{}{"a": int32(1)}, reflect.ValueOf(&m).Elem(), nil); err != nil ||
+		!reflect.DeepEqual(m, map[string]int{"a": 1}) {
+		t.Errorf("Unexpected map %v, %v", m, err)
+	}
+}
+
+func TestEncodeParamLargeIntegers(t *testing.T) {
+	for _, c := range []struct {
+		source interface{}
+		want   interface{}
+	}{
+		{int64(math.MaxInt32), math.MaxInt32},
+		{int64(math.MaxInt32) + 1, float64(math.MaxInt32) + 1},
+		{int64(math.MinInt32) - 1, float64(math.MinInt32) - 1},
+		{uint32(math.MaxUint32), float64(math.MaxUint32)},
+		{uint64(5), 5},
+	} {
+		value, err := encodeParam("/i", reflect.ValueOf(c.source))
+		if err != nil || value != c.want {
+			t.Errorf("Expected %v to encode as %#v but got %#v, %v", c.source, c.want, value, err)
+		}
+	}
+
+	var i int64
+	if err := decodeParam("/i", float64(1<<40), reflect.ValueOf(&i).Elem(), nil); err != nil || i != 1<<40 {
+		t.Errorf("Expected a whole float to convert to int64: %d, %v", i, err)
+	}
+	var u uint32
+	if err := decodeParam("/u", float64(math.MaxUint32), reflect.ValueOf(&u).Elem(), nil); err != nil || u != math.MaxUint32 {
+		t.Errorf("Expected a whole float to convert to uint32: %d, %v", u, err)
+	}
+}
@@ -3,11 +3,11 @@ package ros
 import (
 	"bytes"
 	"encoding/binary"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -23,36 +23,50 @@ func (e *remoteSubscriberSessionError) Error() string {
 }
 
 type defaultPublisher struct {
-	node               *defaultNode
-	topic              string
-	msgType            MessageType
-	msgChan            chan []byte
-	shutdownChan       chan struct{}
-	sessionIDCount     int
-	sessions           map[int]*remoteSubscriberSession
-	sessionChan        chan *remoteSubscriberSession
-	sessionErrorChan   chan error
-	listenerErrorChan  chan error
-	listener           net.Listener
-	connectCallback    func(SingleSubscriberPublisher)
-	disconnectCallback func(SingleSubscriberPublisher)
-}
-
-func newDefaultPublisher(node *defaultNode, topic string, msgType MessageType,
-	connectCallback, disconnectCallback func(SingleSubscriberPublisher)) *defaultPublisher {
+	node              *defaultNode
+	topic             string
+	msgType           MessageType
+	options           publisherOptions
+	msgChan           chan []byte
+	shutdownChan      chan struct{}
+	doneChan          chan struct{}
+	sessionIDCount    int
+	sessionIDMutex    sync.Mutex
+	sessions          map[int]*remoteSubscriberSession
+	sessionsMutex     sync.RWMutex
+	sessionChan       chan *remoteSubscriberSession
+	sessionErrorChan  chan error
+	listenerErrorChan chan error
+	listener          net.Listener
+	callbacks         map[int]subscriberCallbacks
+	callbackIDCount   int
+	callbacksMutex    sync.Mutex
+	headers           *HeaderFiller
+}
+
+// subscriberCallbacks are the connect and disconnect callbacks of one publisher handle.
+type subscriberCallbacks struct {
+	connect    func(SingleSubscriberPublisher)
+	disconnect func(SingleSubscriberPublisher)
+}
+
+func newDefaultPublisher(node *defaultNode, topic string, msgType MessageType, opts ...PublisherOption) *defaultPublisher {
 
+	options := newPublisherOptions(opts)
 	pub := &defaultPublisher{
-		node:               node,
-		topic:              topic,
-		msgType:            msgType,
-		shutdownChan:       make(chan struct{}, 10),
-		sessions:           make(map[int]*remoteSubscriberSession),
-		msgChan:            make(chan []byte, 10),
-		listenerErrorChan:  make(chan error, 10),
-		sessionChan:        make(chan *remoteSubscriberSession, 10),
-		sessionErrorChan:   make(chan error, 10),
-		connectCallback:    connectCallback,
-		disconnectCallback: disconnectCallback}
+		node:              node,
+		topic:             topic,
+		msgType:           msgType,
+		options:           options,
+		shutdownChan:      make(chan struct{}, 10),
+		doneChan:          make(chan struct{}),
+		sessions:          make(map[int]*remoteSubscriberSession),
+		msgChan:           make(chan []byte, options.queueSize),
+		listenerErrorChan: make(chan error, 10),
+		sessionChan:       make(chan *remoteSubscriberSession, 10),
+		sessionErrorChan:  make(chan error, 10),
+		callbacks:         make(map[int]subscriberCallbacks),
+		headers:           NewHeaderFiller(opts...)}
 
 	if listener, err := net.Listen("tcp", fmt.Sprintf("%s:0", node.listenIP)); err != nil {
 		panic(err)
@@ -62,12 +76,13 @@ func newDefaultPublisher(node *defaultNode, topic string, msgType MessageType,
 	return pub
 }
 
+// start runs the publisher goroutine. The caller must have added it to wg.
 func (pub *defaultPublisher) start(wg *sync.WaitGroup) {
 	logger := pub.node.logger
 	logger.Debugf("Publisher goroutine for %s started.", pub.topic)
-	wg.Add(1)
 	defer func() {
 		logger.Debug("defaultPublisher.start exit")
+		close(pub.doneChan)
 		wg.Done()
 	}()
 
@@ -77,19 +92,26 @@ func (pub *defaultPublisher) start(wg *sync.WaitGroup) {
 		logger.Debug("defaultPublisher.start loop")
 		select {
 		case msg := <-pub.msgChan:
+			// Fan out without blocking: every session owns a bounded queue drained
+			// by its own writer, so a stalled subscriber only loses its own messages.
 			logger.Debug("Receive msgChan")
-			for _, s := range pub.sessions {
-				session := s
-				session.msgChan <- msg
+			pub.sessionsMutex.RLock()
+			for _, session := range pub.sessions {
+				if !session.queue.push(msg) {
+					logger.Debugf("Dropped message on topic %s for slow subscriber %s", pub.topic, session.callerID)
+				}
 			}
+			pub.sessionsMutex.RUnlock()
 
 		case err := <-pub.listenerErrorChan:
-			logger.Debug("Listener closed unexpectedly: %s", err)
+			logger.Debugf("Listener closed unexpectedly: %s", err)
 			pub.listener.Close()
 			return
 
 		case s := <-pub.sessionChan:
+			pub.sessionsMutex.Lock()
 			pub.sessions[s.id] = s
+			pub.sessionsMutex.Unlock()
 			go s.start()
 
 		case err := <-pub.sessionErrorChan:
@@ -98,7 +120,9 @@ func (pub *defaultPublisher) start(wg *sync.WaitGroup) {
 					logger.Error(err)
 				}
 				id := sessionError.session.id
+				pub.sessionsMutex.Lock()
 				delete(pub.sessions, id)
+				pub.sessionsMutex.Unlock()
 			}
 
 		case <-pub.shutdownChan:
@@ -110,10 +134,12 @@ func (pub *defaultPublisher) start(wg *sync.WaitGroup) {
 				logger.Warn(err)
 			}
 
+			pub.sessionsMutex.Lock()
 			for id, s := range pub.sessions {
-				s.quitChan <- struct{}{}
+				s.stop()
 				delete(pub.sessions, id)
 			}
+			pub.sessionsMutex.Unlock()
 			return
 		}
 	}
@@ -138,20 +164,150 @@ func (pub *defaultPublisher) listenRemoteSubscriber() {
 		}
 
 		logger.Debugf("Connected %s", conn.RemoteAddr().String())
-		id := pub.sessionIDCount
-		pub.sessionIDCount++
-		session := newRemoteSubscriberSession(pub, id, conn)
+		session := newRemoteSubscriberSession(pub, pub.nextSessionID(), conn)
 		pub.sessionChan <- session
 	}
 }
 
+// addCallbacks registers the callbacks of a new publisher handle and returns its id.
+// Every handle is registered, so the number of callbacks is the handle reference count.
+func (pub *defaultPublisher) addCallbacks(connectCallback, disconnectCallback func(SingleSubscriberPublisher)) int {
+	pub.callbacksMutex.Lock()
+	defer pub.callbacksMutex.Unlock()
+	id := pub.callbackIDCount
+	pub.callbackIDCount++
+	pub.callbacks[id] = subscriberCallbacks{connectCallback, disconnectCallback}
+	return id
+}
+
+// removeCallbacks unregisters the callbacks of a publisher handle and returns the number
+// of handles left.
+func (pub *defaultPublisher) removeCallbacks(id int) int {
+	pub.callbacksMutex.Lock()
+	defer pub.callbacksMutex.Unlock()
+	delete(pub.callbacks, id)
+	return len(pub.callbacks)
+}
+
+func (pub *defaultPublisher) connectionCallbacks() []subscriberCallbacks {
+	pub.callbacksMutex.Lock()
+	defer pub.callbacksMutex.Unlock()
+	callbacks := make([]subscriberCallbacks, 0, len(pub.callbacks))
+	for _, c := range pub.callbacks {
+		callbacks = append(callbacks, c)
+	}
+	return callbacks
+}
+
+func (pub *defaultPublisher) nextSessionID() int {
+	pub.sessionIDMutex.Lock()
+	defer pub.sessionIDMutex.Unlock()
+	id := pub.sessionIDCount
+	pub.sessionIDCount++
+	return id
+}
+
+// acceptUDPSubscriber handles the UDPROS part of a requestTopic call. The parameters are
+// [UDPROS, header, host, port, max_datagram_size] and the returned protocol parameters are
+// [UDPROS, host, port, connection_id, max_datagram_size, header].
+func (pub *defaultPublisher) acceptUDPSubscriber(params []interface{}) ([]interface{}, error) {
+	if len(params) != 5 {
+		return nil, fmt.Errorf("UDPROS expects 5 parameters but got %d", len(params))
+	}
+	headerBytes, ok := params[1].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("UDPROS connection header is not binary")
+	}
+	host, ok := params[2].(string)
+	if !ok {
+		return nil, fmt.Errorf("UDPROS host is not a string")
+	}
+	port, ok := params[3].(int32)
+	if !ok {
+		return nil, fmt.Errorf("UDPROS port is not an integer")
+	}
+	maxDatagramSize, ok := params[4].(int32)
+	if !ok {
+		return nil, fmt.Errorf("UDPROS max datagram size is not an integer")
+	}
+	if maxDatagramSize <= 0 {
+		maxDatagramSize = defaultMaxDatagramSize
+	}
+	if maxDatagramSize <= udpROSHeaderSize {
+		return nil, fmt.Errorf("UDPROS max datagram size %d is too small", maxDatagramSize)
+	}
+
+	headers, err := decodeHeaderFields(headerBytes)
+	if err != nil {
+		return nil, err
+	}
+	headerMap := headersToMap(headers)
+	if err := checkSubscriberHeader(pub.topic, pub.msgType.Name(), pub.msgType.MD5Sum(), headerMap); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("udp", net.JoinHostPort(host, fmt.Sprint(port)))
+	if err != nil {
+		return nil, err
+	}
+	_, localPort, err := net.SplitHostPort(conn.LocalAddr().String())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	udpPort, err := strconv.Atoi(localPort)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	session := newRemoteSubscriberSession(pub, pub.nextSessionID(), conn)
+	session.udp = &udpROSSender{
+		connectionID:    uint32(session.id),
+		maxDatagramSize: int(maxDatagramSize),
+	}
+	session.subscriberHeader = headerMap
+	select {
+	case pub.sessionChan <- session:
+	case <-pub.doneChan:
+		conn.Close()
+		return nil, fmt.Errorf("publisher for %s is shut down", pub.topic)
+	}
+
+	resHeaders := encodeHeaderFields(session.responseHeaders())
+	return []interface{}{udpROSProtocol, pub.node.hostname, udpPort, int(session.id), int(maxDatagramSize), resHeaders}, nil
+}
+
+// Publish hands msg over to the publisher goroutine. It blocks for at most the
+// configured publish timeout and drops the message if the publisher is backed up.
 func (pub *defaultPublisher) Publish(msg Message) {
+	if pub.headers != nil {
+		pub.headers.Fill(msg, pub.node.executor.Clock().Now())
+	}
 	var buf bytes.Buffer
 	_ = msg.Serialize(&buf)
-	pub.msgChan <- buf.Bytes()
+	select {
+	case pub.msgChan <- buf.Bytes():
+		return
+	default:
+	}
+	if pub.options.publishTimeout == 0 {
+		pub.node.logger.Debugf("Publish to %s dropped a message: publisher queue is full", pub.topic)
+		return
+	}
+	timer := time.NewTimer(pub.options.publishTimeout)
+	defer timer.Stop()
+	select {
+	case pub.msgChan <- buf.Bytes():
+	case <-pub.doneChan:
+	case <-timer.C:
+		pub.node.logger.Debugf("Publish to %s dropped a message: timed out after %v", pub.topic, pub.options.publishTimeout)
+	}
 }
 
 func (pub *defaultPublisher) GetNumSubscribers() int {
+	pub.sessionsMutex.RLock()
+	defer pub.sessionsMutex.RUnlock()
 	return len(pub.sessions)
 }
 
@@ -159,6 +315,29 @@ func (pub *defaultPublisher) Shutdown() {
 	pub.shutdownChan <- struct{}{}
 }
 
+// publisherHandle is the Publisher returned by each NewPublisher call. Shutting a handle
+// down only removes its own callbacks; the publisher is shut down with its last handle.
+type publisherHandle struct {
+	node         *defaultNode
+	pub          *defaultPublisher
+	id           int
+	shutdownOnce sync.Once
+}
+
+func (h *publisherHandle) Publish(msg Message) {
+	h.pub.Publish(msg)
+}
+
+func (h *publisherHandle) GetNumSubscribers() int {
+	return h.pub.GetNumSubscribers()
+}
+
+func (h *publisherHandle) Shutdown() {
+	h.shutdownOnce.Do(func() {
+		h.node.unadvertise(h.pub, h.id)
+	})
+}
+
 func (pub *defaultPublisher) hostAndPort() (string, string) {
 	_, port, err := net.SplitHostPort(pub.listener.Addr().String())
 	if err != nil {
@@ -169,23 +348,27 @@ func (pub *defaultPublisher) hostAndPort() (string, string) {
 }
 
 type remoteSubscriberSession struct {
-	id                 int
-	conn               net.Conn
-	nodeID             string
-	callerID           string
-	topic              string
-	typeText           string
-	md5sum             string
-	typeName           string
-	sizeBytesSent      uint32
-	msgBytesSent       uint32
-	numSent            int64
-	quitChan           chan struct{}
-	msgChan            chan []byte
-	errorChan          chan error
-	logger             Logger
-	connectCallback    func(SingleSubscriberPublisher)
-	disconnectCallback func(SingleSubscriberPublisher)
+	id               int
+	conn             net.Conn
+	nodeID           string
+	callerID         string
+	topic            string
+	typeText         string
+	md5sum           string
+	typeName         string
+	sizeBytesSent    uint32
+	msgBytesSent     uint32
+	numSent          int64
+	quitChan         chan struct{}
+	quitOnce         sync.Once
+	queue            *messageQueue
+	udp              *udpROSSender
+	subscriberHeader map[string]string
+	maxHeaderSize    int
+	errorChan        chan error
+	pubDoneChan      chan struct{}
+	logger           Logger
+	callbacks        func() []subscriberCallbacks
 }
 
 func newRemoteSubscriberSession(pub *defaultPublisher, id int, conn net.Conn) *remoteSubscriberSession {
@@ -201,24 +384,41 @@ func newRemoteSubscriberSession(pub *defaultPublisher, id int, conn net.Conn) *r
 	session.msgBytesSent = 0
 	session.numSent = 0
 	session.quitChan = make(chan struct{})
-	session.msgChan = make(chan []byte, 10)
+	session.queue = newMessageQueue(pub.options.queueSize, pub.options.dropPolicy)
+	session.maxHeaderSize = pub.node.options.maxHeaderSize
 	session.errorChan = pub.sessionErrorChan
+	session.pubDoneChan = pub.doneChan
 	session.logger = pub.node.logger
-	session.connectCallback = pub.connectCallback
-	session.disconnectCallback = pub.disconnectCallback
+	session.callbacks = pub.connectionCallbacks
 	return session
 }
 
+// udpROSSender fragments messages for a UDPROS subscriber.
+type udpROSSender struct {
+	connectionID    uint32
+	maxDatagramSize int
+	messageID       uint8
+}
+
+// stop asks the session to exit. Closing the connection also unblocks a
+// writer that is stuck on a subscriber which stopped reading.
+func (session *remoteSubscriberSession) stop() {
+	session.quitOnce.Do(func() {
+		close(session.quitChan)
+		session.conn.Close()
+	})
+}
+
 type singleSubPub struct {
 	subName string
 	topic   string
-	msgChan chan []byte
+	queue   *messageQueue
 }
 
 func (ssp *singleSubPub) Publish(msg Message) {
 	var buf bytes.Buffer
 	_ = msg.Serialize(&buf)
-	ssp.msgChan <- buf.Bytes()
+	ssp.queue.push(buf.Bytes())
 }
 
 func (ssp *singleSubPub) GetSubscriberName() string {
@@ -234,130 +434,181 @@ func (session *remoteSubscriberSession) start() {
 	logger.Debug("remoteSubscriberSession.start enter")
 
 	ssp := &singleSubPub{
-		topic:   session.topic,
-		msgChan: session.msgChan,
+		topic: session.topic,
+		queue: session.queue,
 		// callerID is filled in after header gets read later in this function.
 	}
 
 	defer func() {
 		logger.Debug("remoteSubscriberSession.start exit")
 
-		if session.disconnectCallback != nil {
-			session.disconnectCallback(ssp)
+		for _, c := range session.callbacks() {
+			if c.disconnect != nil {
+				c.disconnect(ssp)
+			}
 		}
 	}()
 	defer func() {
+		var sessionErr *remoteSubscriberSessionError
 		if err := recover(); err != nil {
 			if e, ok := err.(error); ok {
-				session.errorChan <- &remoteSubscriberSessionError{session, e}
+				sessionErr = &remoteSubscriberSessionError{session, e}
 			} else {
 				e = fmt.Errorf("Unkonwn error value")
-				session.errorChan <- &remoteSubscriberSessionError{session, e}
+				sessionErr = &remoteSubscriberSessionError{session, e}
 			}
 		} else {
-			session.errorChan <- &remoteSubscriberSessionError{session, nil}
+			sessionErr = &remoteSubscriberSessionError{session, nil}
+		}
+		session.stop()
+		select {
+		case session.errorChan <- sessionErr:
+		case <-session.pubDoneChan:
 		}
 	}()
-	// 1. Read connection header
-	headers, err := readConnectionHeader(session.conn)
-	if err != nil {
-		panic(errors.New("failed to read connection header"))
-	}
-	logger.Debug("TCPROS Connection Header:")
-	headerMap := make(map[string]string)
-	for _, h := range headers {
-		headerMap[h.key] = h.value
-		logger.Debugf("  `%s` = `%s`", h.key, h.value)
-	}
-
-	if headerMap["type"] != session.typeName && headerMap["type"] != "*" {
-		panic(fmt.Errorf("incompatible message type: does not match for topic %s: %s vs %s",
-			session.topic, session.typeName, headerMap["type"]))
+	if session.udp == nil {
+		// 1. Read connection header
+		headers, err := readConnectionHeader(session.conn, session.maxHeaderSize)
+		if err != nil {
+			panic(fmt.Errorf("failed to read connection header: %v", err))
+		}
+		logger.Debug("TCPROS Connection Header:")
+		for _, h := range headers {
+			logger.Debugf("  `%s` = `%s`", h.key, h.value)
+		}
+		session.subscriberHeader = headersToMap(headers)
 	}
 
-	if headerMap["md5sum"] != session.md5sum && headerMap["md5sum"] != "*" {
-		panic(fmt.Errorf("incompatible message md5: does not match for topic %s: %s vs %s",
-			session.topic, session.md5sum, headerMap["md5sum"]))
+	if err := checkSubscriberHeader(session.topic, session.typeName, session.md5sum, session.subscriberHeader); err != nil {
+		if session.udp == nil {
+			// Like roscpp, tell the subscriber why it was rejected.
+			writeConnectionHeader([]header{{"error", err.Error()}}, session.conn)
+		}
+		panic(err)
 	}
-	session.callerID = headerMap["callerid"]
-	ssp.subName = headerMap["callerid"]
-	if session.connectCallback != nil {
-		go session.connectCallback(ssp)
+	session.callerID = session.subscriberHeader["callerid"]
+	ssp.subName = session.subscriberHeader["callerid"]
+	for _, c := range session.callbacks() {
+		if c.connect != nil {
+			go c.connect(ssp)
+		}
 	}
 
-	// 2. Return response header
-	var resHeaders []header
-	resHeaders = append(resHeaders, header{"message_definition", session.typeText})
-	resHeaders = append(resHeaders, header{"callerid", session.nodeID})
-	resHeaders = append(resHeaders, header{"latching", "0"})
-	resHeaders = append(resHeaders, header{"md5sum", session.md5sum})
-	resHeaders = append(resHeaders, header{"topic", session.topic})
-	resHeaders = append(resHeaders, header{"type", session.typeName})
-	logger.Debug("TCPROS Response Header")
-	for _, h := range resHeaders {
-		logger.Debugf("  `%s` = `%s`", h.key, h.value)
+	if tcpConn, ok := session.conn.(*net.TCPConn); ok {
+		// Like roscpp, only disable Nagle's algorithm when the subscriber asks for it.
+		tcpConn.SetNoDelay(session.subscriberHeader["tcp_nodelay"] == "1")
 	}
-	err = writeConnectionHeader(resHeaders, session.conn)
-	if err != nil {
-		panic(errors.New("failed to write response header"))
+
+	if session.udp == nil {
+		// 2. Return response header
+		resHeaders := session.responseHeaders()
+		logger.Debug("TCPROS Response Header")
+		for _, h := range resHeaders {
+			logger.Debugf("  `%s` = `%s`", h.key, h.value)
+		}
+		if err := writeConnectionHeader(resHeaders, session.conn); err != nil {
+			panic(errors.New("failed to write response header"))
+		}
 	}
 
-	// Ticker to periodically check if the
-	// subscriber is still connected
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-	one := make([]byte, 1)
+	// Subscribers never send anything after the connection header, so a read
+	// only returns once the subscriber has gone away. For UDPROS the read fails
+	// once the subscriber's port becomes unreachable.
+	disconnectedChan := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, session.conn)
+		close(disconnectedChan)
+	}()
 
 	// 3. Start sending message
 	logger.Debug("Start sending messages...")
-	queueMaxSize := 100
-	queue := make(chan []byte, queueMaxSize)
 	for {
-		//logger.Debug("session.remoteSubscriberSession")
 		select {
-		case msg := <-session.msgChan:
-			logger.Debug("Receive msgChan")
-			if len(queue) == queueMaxSize {
-				<-queue
+		case <-session.queue.notify:
+			for {
+				msg, ok := session.queue.pop()
+				if !ok {
+					break
+				}
+				if err := session.write(msg); err != nil {
+					select {
+					case <-session.quitChan:
+						return
+					default:
+					}
+					panic(err)
+				}
 			}
-			queue <- msg
 
 		case <-session.quitChan:
 			logger.Debug("Receive quitChan")
 			return
 
-		case <-ticker.C:
-			session.conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
-			if _, err := session.conn.Read(one); err == io.EOF {
-				logger.Debug("Subscriber disconnected")
-				return
-			}
+		case <-disconnectedChan:
+			logger.Debug("Subscriber disconnected")
+			return
+		}
+	}
+}
 
-		case msg := <-queue:
-			logger.Debug("writing")
-			logger.Debug(hex.EncodeToString(msg))
-			session.conn.SetDeadline(time.Now().Add(10 * time.Millisecond))
-			size := uint32(len(msg))
-			if err := binary.Write(session.conn, binary.LittleEndian, size); err != nil {
-				if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
-					logger.Debug("timeout")
-					continue
-				} else {
-					logger.Error(err)
-					panic(err)
-				}
-			}
-			session.conn.SetDeadline(time.Now().Add(10 * time.Millisecond))
-			if _, err := session.conn.Write(msg); err != nil {
-				if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
-					logger.Debug("timeout")
-					continue
-				} else {
-					logger.Error(err)
-					panic(err)
-				}
-			}
-			logger.Debug(hex.EncodeToString(msg))
+// checkSubscriberHeader verifies that a subscriber's connection header matches the published topic.
+func checkSubscriberHeader(topic, typeName, md5sum string, headerMap map[string]string) error {
+	if headerMap["type"] != typeName && headerMap["type"] != "*" {
+		return fmt.Errorf("incompatible message type: does not match for topic %s: %s vs %s",
+			topic, typeName, headerMap["type"])
+	}
+	if headerMap["md5sum"] != md5sum && headerMap["md5sum"] != "*" {
+		return fmt.Errorf("incompatible message md5: does not match for topic %s: %s vs %s",
+			topic, md5sum, headerMap["md5sum"])
+	}
+	return nil
+}
+
+func (session *remoteSubscriberSession) responseHeaders() []header {
+	var resHeaders []header
+	resHeaders = append(resHeaders, header{"message_definition", session.typeText})
+	resHeaders = append(resHeaders, header{"callerid", session.nodeID})
+	resHeaders = append(resHeaders, header{"latching", "0"})
+	resHeaders = append(resHeaders, header{"md5sum", session.md5sum})
+	resHeaders = append(resHeaders, header{"topic", session.topic})
+	resHeaders = append(resHeaders, header{"type", session.typeName})
+	return resHeaders
+}
+
+// write sends one length-prefixed message frame. The frame is written in a single
+// call so that a failed write never leaves a partial frame on a live connection.
+func (session *remoteSubscriberSession) write(msg []byte) error {
+	if session.udp != nil {
+		return session.writeDatagrams(msg)
+	}
+	frame := make([]byte, 4+len(msg))
+	binary.LittleEndian.PutUint32(frame, uint32(len(msg)))
+	copy(frame[4:], msg)
+	if _, err := session.conn.Write(frame); err != nil {
+		return err
+	}
+	session.numSent++
+	session.msgBytesSent += uint32(len(msg))
+	session.sizeBytesSent += uint32(len(frame))
+	return nil
+}
+
+// writeDatagrams sends one message as a sequence of UDPROS datagrams.
+func (session *remoteSubscriberSession) writeDatagrams(msg []byte) error {
+	sender := session.udp
+	datagrams, err := fragmentUDPROSMessage(sender.connectionID, sender.messageID, msg, sender.maxDatagramSize)
+	if err != nil {
+		session.logger.Warnf("Dropped message on topic %s for %s: %v", session.topic, session.callerID, err)
+		return nil
+	}
+	sender.messageID++
+	for _, datagram := range datagrams {
+		if _, err := session.conn.Write(datagram); err != nil {
+			return err
 		}
+		session.sizeBytesSent += uint32(len(datagram))
 	}
+	session.numSent++
+	session.msgBytesSent += uint32(len(msg))
+	return nil
 }
@@ -0,0 +1,125 @@
+package ros
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type rawMessage struct {
+	data []byte
+}
+
+func (m *rawMessage) GetType() MessageType {
+	return &dummyMessage{}
+}
+
+func (m *rawMessage) Serialize(buf *bytes.Buffer) error {
+	_, err := buf.Write(m.data)
+	return err
+}
+
+func (m *rawMessage) Deserialize(buf *bytes.Reader) error {
+	m.data = make([]byte, buf.Len())
+	_, err := buf.Read(m.data)
+	return err
+}
+
+func connectTestSubscriber(t *testing.T, pub *defaultPublisher, callerID string) net.Conn {
+	conn, err := net.Dial("tcp", pub.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect to publisher: %v", err)
+	}
+	msgType := &dummyMessage{}
+	headers := []header{
+		{"topic", pub.topic},
+		{"md5sum", msgType.MD5Sum()},
+		{"type", msgType.Name()},
+		{"callerid", callerID},
+	}
+	if err := writeConnectionHeader(headers, conn); err != nil {
+		t.Fatalf("Failed to write connection header: %v", err)
+	}
+	if _, err := readConnectionHeader(conn, defaultMaxHeaderSize); err != nil {
+		t.Fatalf("Failed to read response header: %v", err)
+	}
+	return conn
+}
+
+func TestPublisherSlowSubscriber(t *testing.T) {
+	node, err := newDefaultNode("/test_publisher_node", []string{})
+	if err != nil {
+		t.Fatalf("Error starting new test node: %v", err)
+	}
+	defer node.Shutdown()
+
+	pub := newDefaultPublisher(node, "/test_slow", &dummyMessage{},
+		WithQueueSize(4), WithPublishTimeout(10*time.Millisecond))
+	node.publishers["/test_slow"] = pub
+	node.waitGroup.Add(1)
+	go pub.start(&node.waitGroup)
+
+	// The stalled subscriber never reads so its socket buffers fill up.
+	stalled := connectTestSubscriber(t, pub, "/stalled")
+	defer stalled.Close()
+	active := connectTestSubscriber(t, pub, "/active")
+	defer active.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for pub.GetNumSubscribers() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Subscribers did not connect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	received := make(chan []byte, 1000)
+	go func() {
+		for {
+			var size uint32
+			if err := binary.Read(active, binary.LittleEndian, &size); err != nil {
+				close(received)
+				return
+			}
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(active, buf); err != nil {
+				close(received)
+				return
+			}
+			received <- buf
+		}
+	}()
+
+	payload := make([]byte, 64*1024)
+	const numMessages = 200
+	for i := 0; i < numMessages; i++ {
+		payload[0] = byte(i)
+		msg := &rawMessage{data: append([]byte(nil), payload...)}
+		start := time.Now()
+		pub.Publish(msg)
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Fatalf("Publish blocked for %v", elapsed)
+		}
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case buf, ok := <-received:
+			if !ok {
+				t.Fatal("Active subscriber connection closed")
+			}
+			if len(buf) != len(payload) {
+				t.Fatalf("Expected message of %d bytes but got %d", len(payload), len(buf))
+			}
+			if buf[0] == byte(numMessages-1) {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Active subscriber did not receive the last message")
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ros
+
+import (
+	"sync"
+)
+
+// messageQueue is a bounded FIFO of serialized messages shared by one producer
+// and one consumer goroutine. Pushing never blocks; when the queue is full a
+// message is discarded according to the drop policy.
+type messageQueue struct {
+	mutex  sync.Mutex
+	items  [][]byte
+	size   int
+	policy DropPolicy
+	notify chan struct{}
+}
+
+func newMessageQueue(size int, policy DropPolicy) *messageQueue {
+	return &messageQueue{
+		items:  make([][]byte, 0, size),
+		size:   size,
+		policy: policy,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// push appends msg to the queue. It returns false if a message had to be dropped.
+func (q *messageQueue) push(msg []byte) bool {
+	q.mutex.Lock()
+	accepted := true
+	if len(q.items) >= q.size {
+		accepted = false
+		if q.policy == DropOldest {
+			q.items[0] = nil
+			q.items = append(q.items[1:], msg)
+		}
+	} else {
+		q.items = append(q.items, msg)
+	}
+	q.mutex.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return accepted
+}
+
+// pop removes and returns the oldest message in the queue.
+func (q *messageQueue) pop() ([]byte, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	msg := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	return msg, true
+}
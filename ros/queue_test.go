@@ -0,0 +1,55 @@
+package ros
+
+import (
+	"testing"
+)
+
+func TestMessageQueue(t *testing.T) {
+	t.Run("DropOldest", func(t *testing.T) {
+		q := newMessageQueue(2, DropOldest)
+		if !q.push([]byte("a")) || !q.push([]byte("b")) {
+			t.Fatal("Expected push to succeed while queue has room")
+		}
+		if q.push([]byte("c")) {
+			t.Error("Expected push to report a dropped message on a full queue")
+		}
+		if len(q.items) != 2 {
+			t.Errorf("Expected queue length 2 but got %d", len(q.items))
+		}
+		for _, want := range []string{"b", "c"} {
+			got, ok := q.pop()
+			if !ok || string(got) != want {
+				t.Errorf("Expected %s but got %s", want, got)
+			}
+		}
+		if _, ok := q.pop(); ok {
+			t.Error("Expected empty queue")
+		}
+	})
+
+	t.Run("DropNewest", func(t *testing.T) {
+		q := newMessageQueue(2, DropNewest)
+		q.push([]byte("a"))
+		q.push([]byte("b"))
+		if q.push([]byte("c")) {
+			t.Error("Expected push to report a dropped message on a full queue")
+		}
+		for _, want := range []string{"a", "b"} {
+			got, ok := q.pop()
+			if !ok || string(got) != want {
+				t.Errorf("Expected %s but got %s", want, got)
+			}
+		}
+	})
+
+	t.Run("Notify", func(t *testing.T) {
+		q := newMessageQueue(1, DropOldest)
+		q.push([]byte("a"))
+		q.push([]byte("b"))
+		select {
+		case <-q.notify:
+		default:
+			t.Error("Expected notification after push")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package ros
 
 import (
+	"context"
 	"time"
 )
 
@@ -8,12 +9,17 @@ import (
 type Node interface {
 	// NewPublisher creates a publisher which can used to publish ros messages of type MessageType
 	// to the specified topic.
-	NewPublisher(topic string, msgType MessageType) Publisher
+	// Each remote subscriber gets its own bounded queue and writer, so a slow subscriber
+	// loses messages according to the drop policy instead of stalling the topic.
+	// Options can be used to change the queue size, drop policy and publish timeout.
+	// Every call returns its own handle; publishers created for the same topic share one
+	// connection, which is shut down together with its last handle.
+	NewPublisher(topic string, msgType MessageType, opts ...PublisherOption) Publisher
 
 	// NewPublisherWithCallbacks creates a publisher which gives you callbacks when subscribers
 	// connect and disconnect.  The callbacks are called in their own goroutines, so they don't
 	// need to return immediately to let the connection proceed.
-	NewPublisherWithCallbacks(topic string, msgType MessageType, connectCallback, disconnectCallback func(SingleSubscriberPublisher)) Publisher
+	NewPublisherWithCallbacks(topic string, msgType MessageType, connectCallback, disconnectCallback func(SingleSubscriberPublisher), opts ...PublisherOption) Publisher
 
 	// NewSubscriber creates a subscriber to a topic and calls callback on receiving a message.
 	// Callback should be a function which takes 0, 1, or 2 arguments.
@@ -22,7 +28,23 @@ type Node interface {
 	// 1-arguments - Callback argument should be of the generated message type.
 	// 2-arguments - Callback first argument should be of the generated message type and
 	//               the second argument should be of type MessageEvent.
-	NewSubscriber(topic string, msgType MessageType, callback interface{}) Subscriber
+	//
+	// A callback with any other signature is rejected with an error log. Use Subscribe to have
+	// the callback signature checked at compile time.
+	//
+	// Options such as WithTransportHints can be used to request UDPROS with a TCPROS fallback,
+	// WithPublisherCallbacks to be told when publishers connect, disconnect or are rejected.
+	//
+	// Every call returns its own handle; subscribers to the same topic share one subscription,
+	// which is shut down together with its last handle.
+	NewSubscriber(topic string, msgType MessageType, callback interface{}, opts ...SubscriberOption) Subscriber
+
+	// NewSubscriberChan creates a subscriber to a topic that delivers each message with its
+	// MessageEvent into the returned channel. Messages are delivered by the subscriber itself,
+	// so the channel is fed without Spin or SpinOnce.
+	// The channel buffers up to bufferSize messages; when the reader falls behind, messages are
+	// dropped according to policy. The channel is closed when the subscriber is shut down.
+	NewSubscriberChan(topic string, msgType MessageType, bufferSize int, policy DropPolicy, opts ...SubscriberOption) (<-chan ReceivedMessage, Subscriber)
 
 	// NewServiceClient creates a service client which can be used to connect to a service server
 	// send service requests.
@@ -32,13 +54,28 @@ type Node interface {
 	// service requests from service clients.
 	NewServiceServer(service string, srvType ServiceType, callback interface{}) ServiceServer
 
+	// WaitForService blocks until service is registered with the master and its server
+	// accepts connections, or until ctx is done; use context.WithTimeout to bound the wait.
+	WaitForService(ctx context.Context, service string) error
+
+	// NewTimer creates a timer that calls callback every period. The callback runs on the
+	// node's executor like subscriber callbacks, and the timer follows the executor's clock.
+	// A period that is not positive is reported to the logger and the timer never fires.
+	// While a callback waits for the executor, the periods that fall due are skipped.
+	NewTimer(period time.Duration, callback func(TimerEvent)) Timer
+
+	// Clock returns the clock of the node's executor, which timers follow and which stamps
+	// the ReceiptTime of received messages. Tests can use it to follow simulated time.
+	Clock() Clock
+
 	// OK represents the status of ros node.
 	OK() bool
 
 	// SpinOnce executes the job at the top of the Job queue.
-	// Job queue consists of callback functions for subscribers and service server.
+	// Job queue consists of callback functions for subscribers, service servers and timers.
 	// Blocking callbacks or callbacks that take too long to execute will result in new messages
 	// being dropped due to job queue being full.
+	// The node's executor decides what exactly runs, see WithExecutor.
 	SpinOnce()
 
 	// Spin is job executor that continuosly starts executing callback jobs in the job queue.
@@ -80,26 +117,49 @@ type Node interface {
 	// NonRosArgs returns an array of all the non ros arguments of the ros node.
 	NonRosArgs() []string
 	Name() string
+
+	// Namespace returns a view of the node whose relative names resolve in the namespace ns,
+	// like a roscpp NodeHandle. ns is resolved against the namespace of this node, so views
+	// nest. remappings, which may be nil, are added to the remappings of this node with
+	// their names resolved in ns; private names still resolve against the node name.
+	// The view shares the connections and the executor of the node. Its Shutdown only
+	// releases the publishers, subscribers, services and timers created through it.
+	// Invalid names and remappings are reported to the logger.
+	Namespace(ns string, remappings NameMap) Node
+
+	// ResolveName returns the global name that name stands for in the namespace of the node,
+	// after applying the remappings if remap is set. All methods of the node resolve names
+	// this way. An *InvalidNameError is returned if name is not a valid ROS name.
+	ResolveName(name string, remap bool) (string, error)
+
+	// AddRemapping remaps the name from to the name to, both resolved in the namespace of the
+	// node, like a from:=to command line argument. It applies to names resolved afterwards,
+	// including those of namespace views. An *InvalidNameError is returned if either name
+	// is not a valid ROS name.
+	AddRemapping(from string, to string) error
 }
 
 // NewNode creates and returns a new instance of ros node
 // Returns a non nil error when unable connect to ros master
 // or create a new node.
-func NewNode(name string, args []string) (Node, error) {
-	return newDefaultNode(name, args)
+// Options such as WithMaxMessageSize bound what remote peers may make the node allocate.
+func NewNode(name string, args []string, opts ...NodeOption) (Node, error) {
+	return newDefaultNode(name, args, opts...)
 }
 
 // Publisher can be used to publish a ros messages to a specific topic.
 // Shutdown can be used to shutdown this particular publisher.
 // All running subscribers, publishers and services are shutdown on node exit.
 type Publisher interface {
-	// Publish publishes ros message
+	// Publish publishes ros message.
+	// Publish never blocks longer than the publish timeout of the publisher.
 	Publish(msg Message)
 
 	// GetNumSubscribers gets the number of subscribers to the publishing topic
 	GetNumSubscribers() int
 
-	// Shutdown stops the publisher
+	// Shutdown releases this publisher handle. The topic stops being advertised
+	// once every handle for it is shut down.
 	Shutdown()
 }
 
@@ -123,7 +183,8 @@ type Subscriber interface {
 	// GetNumPublishers gets the numbers of publishers to the topic we are subscribed to has.
 	GetNumPublishers() int
 
-	// Shutdown stop subscriber.
+	// Shutdown removes the callback or channel of this subscriber handle only.
+	// The subscription ends once every handle for the topic is shut down.
 	Shutdown()
 }
 
@@ -141,6 +202,89 @@ type MessageEvent struct {
 	ConnectionHeader map[string]string
 }
 
+// PublisherEvent describes a change of the connection between a subscriber and one of the
+// publishers of its topic. It is passed to the callbacks set with WithPublisherCallbacks.
+type PublisherEvent struct {
+	// Topic is the name of the subscribed topic.
+	Topic string
+
+	// CallerID is the name of the publisher node. It is empty if the publisher
+	// was rejected before it sent a connection header.
+	CallerID string
+
+	// URI is the XML-RPC URI of the publisher node.
+	URI string
+
+	// ConnectionHeader is the connection header the publisher answered with, if any.
+	ConnectionHeader map[string]string
+
+	// Err is the reason a publisher was rejected or disconnected. It is nil when
+	// the publisher unregistered from the master or the subscriber was shut down.
+	Err error
+}
+
+// DeadlineEvent tells that messages of a topic stopped or resumed arriving in time. It is
+// passed to the callbacks set with WithDeadline.
+type DeadlineEvent struct {
+	// Topic is the name of the subscribed topic.
+	Topic string
+
+	// Period is the longest time expected between two messages.
+	Period time.Duration
+
+	// LastReceipt is when the last message was received on the executor's clock. It is
+	// zero if no message has been received.
+	LastReceipt time.Time
+
+	// Missed is the number of deadlines missed in a row, up to the latest one the missed
+	// callback is called for. The recovered callback is called with the number missed
+	// before the message that arrived.
+	Missed int
+}
+
+// LivelinessEvent tells that the number of publishers of a topic fell below or reached the
+// minimum again. It is passed to the callbacks set with WithLiveliness.
+type LivelinessEvent struct {
+	// Topic is the name of the subscribed topic.
+	Topic string
+
+	// Publishers is the number of publishers connected.
+	Publishers int
+
+	// MinPublishers is the number of publishers expected.
+	MinPublishers int
+}
+
+// TimerEvent is passed to timer callbacks.
+type TimerEvent struct {
+	// LastExpected is when the previous callback should have run.
+	LastExpected time.Time
+
+	// LastReal is when the previous callback actually ran.
+	LastReal time.Time
+
+	// CurrentExpected is when this callback should have run.
+	CurrentExpected time.Time
+
+	// CurrentReal is when this callback actually runs.
+	CurrentReal time.Time
+}
+
+// Timer calls a callback periodically. Timers are stopped on node shutdown.
+type Timer interface {
+	// Stop stops the timer. A callback already queued does not run.
+	Stop()
+}
+
+// ReceivedMessage is a message delivered by a subscriber created with Node.NewSubscriberChan.
+type ReceivedMessage struct {
+	// Message is the deserialized message.
+	Message Message
+
+	// Event holds the meta information of the message.
+	Event MessageEvent
+}
+
 // ServiceServer can be used to shutdown a running service server
 type ServiceServer interface {
 	// Shutdown stops the service server.
@@ -0,0 +1,368 @@
+package ros
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scalars of the form deg(...) and rad(...) are read as angles, like rosparam does.
+var (
+	implicitDegrees = regexp.MustCompile(`^deg\(([^)]*)\)$`)
+	implicitRadians = regexp.MustCompile(`^rad\(([^)]*)\)$`)
+)
+
+// yaml11Bools holds the plain scalars that YAML 1.1, which rosparam reads, resolves to
+// booleans but YAML 1.2 leaves as strings.
+var yaml11Bools = map[string]bool{
+	"yes": true, "Yes": true, "YES": true, "on": true, "On": true, "ON": true,
+	"no": false, "No": false, "NO": false, "off": false, "Off": false, "OFF": false,
+}
+
+// ParseParamYAML parses a YAML document into a parameter value the way rosparam does.
+// Mappings become map[string]interface{}, sequences []interface{}, integers int32
+// (or float64 if they do not fit), and binary data []byte. As in YAML 1.1, yes, no, on
+// and off are booleans unless quoted. Values tagged !degrees are
+// converted to radians, and values tagged !radians are evaluated; both accept arithmetic
+// expressions using pi, e.g. "!radians pi/2".
+func ParseParamYAML(data []byte) (interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	return decodeYAMLParam(doc.Content[0])
+}
+
+func decodeYAMLParam(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return decodeYAMLParam(node.Alias)
+	case yaml.MappingNode:
+		values := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Tag == "!!merge" {
+				merged, err := decodeYAMLParam(value)
+				if err != nil {
+					return nil, err
+				}
+				m, ok := merged.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("line %d: only mappings can be merged", key.Line)
+				}
+				for k, v := range m {
+					if _, ok := values[k]; !ok {
+						values[k] = v
+					}
+				}
+				continue
+			}
+			v, err := decodeYAMLParam(value)
+			if err != nil {
+				return nil, err
+			}
+			values[key.Value] = v
+		}
+		return values, nil
+	case yaml.SequenceNode:
+		items := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			v, err := decodeYAMLParam(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = v
+		}
+		return items, nil
+	case yaml.ScalarNode:
+		return decodeYAMLScalar(node)
+	}
+	return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+}
+
+func decodeYAMLScalar(node *yaml.Node) (interface{}, error) {
+	tag := node.ShortTag()
+	if node.Style == 0 && tag == "!!str" {
+		if m := implicitDegrees.FindStringSubmatch(node.Value); m != nil {
+			return evalAngle(m[1], math.Pi/180, node.Line)
+		}
+		if m := implicitRadians.FindStringSubmatch(node.Value); m != nil {
+			return evalAngle(m[1], 1, node.Line)
+		}
+		if b, ok := yaml11Bools[node.Value]; ok {
+			return b, nil
+		}
+	}
+	switch tag {
+	case "!degrees":
+		return evalAngle(node.Value, math.Pi/180, node.Line)
+	case "!radians":
+		return evalAngle(node.Value, 1, node.Line)
+	case "!!null":
+		return nil, fmt.Errorf("line %d: the parameter server cannot store null values", node.Line)
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case "!!int":
+		var i int64
+		if err := node.Decode(&i); err != nil {
+			return nil, err
+		}
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return float64(i), nil
+		}
+		return int32(i), nil
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return nil, err
+		}
+		return f, nil
+	case "!!binary":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(node.Value), ""))
+	}
+	// Strings, timestamps and unknown tags keep their text.
+	return node.Value, nil
+}
+
+// evalAngle evaluates an arithmetic expression and multiplies it by scale.
+func evalAngle(expr string, scale float64, line int) (float64, error) {
+	value, err := evalExpression(expr)
+	if err != nil {
+		return 0, fmt.Errorf("line %d: invalid angle %q: %v", line, expr, err)
+	}
+	return value * scale, nil
+}
+
+// evalExpression evaluates numbers and pi combined with + - * / and parentheses.
+func evalExpression(expr string) (float64, error) {
+	p := &exprParser{input: strings.ReplaceAll(expr, " ", "")}
+	value, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos != len(p.input) {
+		return 0, fmt.Errorf("unexpected %q", p.input[p.pos:])
+	}
+	return value, nil
+}
+
+type exprParser struct {
+	input string
+	pos   int
+}
+
+func (p *exprParser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *exprParser) sum() (float64, error) {
+	value, err := p.product()
+	for err == nil && (p.peek() == '+' || p.peek() == '-') {
+		op := p.peek()
+		p.pos++
+		var rhs float64
+		if rhs, err = p.product(); op == '+' {
+			value += rhs
+		} else {
+			value -= rhs
+		}
+	}
+	return value, err
+}
+
+func (p *exprParser) product() (float64, error) {
+	value, err := p.factor()
+	for err == nil && (p.peek() == '*' || p.peek() == '/') {
+		op := p.peek()
+		p.pos++
+		var rhs float64
+		if rhs, err = p.factor(); op == '*' {
+			value *= rhs
+		} else {
+			value /= rhs
+		}
+	}
+	return value, err
+}
+
+func (p *exprParser) factor() (float64, error) {
+	switch c := p.peek(); {
+	case c == '-':
+		p.pos++
+		value, err := p.factor()
+		return -value, err
+	case c == '+':
+		p.pos++
+		return p.factor()
+	case c == '(':
+		p.pos++
+		value, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return value, nil
+	case strings.HasPrefix(p.input[p.pos:], "pi"):
+		p.pos += 2
+		return math.Pi, nil
+	}
+	start := p.pos
+	for p.pos < len(p.input) && strings.IndexByte("0123456789.eE", p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, fmt.Errorf("expected a number at %q", p.input[start:])
+	}
+	return strconv.ParseFloat(p.input[start:p.pos], 64)
+}
+
+// parseParamArgument parses the value of a _name:=value command line argument as YAML,
+// like roscpp and rospy do. Values that are not valid YAML stay strings.
+func parseParamArgument(value string) interface{} {
+	if strings.TrimSpace(value) == "" {
+		return value
+	}
+	parsed, err := ParseParamYAML([]byte(value))
+	if err != nil {
+		return value
+	}
+	return parsed
+}
+
+// LoadParamYAML sets the parameters of the YAML document data in namespace. Mappings are
+// merged into existing namespaces instead of replacing them, as with rosparam load, so
+// an empty document or mapping loads nothing.
+func LoadParamYAML(node Node, namespace string, data []byte) error {
+	value, err := ParseParamYAML(data)
+	if err != nil {
+		return err
+	}
+	return setParamTree(node, namespace, value)
+}
+
+// LoadParamFile sets the parameters of the YAML file at path in namespace, like
+// rosparam load.
+func LoadParamFile(node Node, namespace string, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := LoadParamYAML(node, namespace, data); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// setParamTree sets the leaves of value one by one so that existing parameters next to
+// them are kept. An empty mapping has no leaves and sets nothing.
+func setParamTree(node Node, name string, value interface{}) error {
+	if m, ok := value.(map[string]interface{}); ok {
+		for key, child := range m {
+			if err := setParamTree(node, joinParamName(name, key), child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return node.SetParam(name, value)
+}
+
+// DumpParamYAML returns the parameter or namespace name as a YAML document, like
+// rosparam dump.
+func DumpParamYAML(node Node, name string) ([]byte, error) {
+	value, err := node.GetParam(name)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := encodeYAMLParam(value)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// encodeYAMLParam converts a parameter value to a YAML node. Floats always keep a
+// fraction or exponent so that they are read back as floats.
+func encodeYAMLParam(value interface{}) (*yaml.Node, error) {
+	scalar := func(tag string, text string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: text}
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range keys {
+			child, err := encodeYAMLParam(v[key])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, scalar("!!str", key), child)
+		}
+		return node, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			child, err := encodeYAMLParam(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	case []byte:
+		return scalar("!!binary", base64.StdEncoding.EncodeToString(v)), nil
+	case float64:
+		text := strconv.FormatFloat(v, 'g', -1, 64)
+		switch {
+		case math.IsInf(v, 1):
+			text = ".inf"
+		case math.IsInf(v, -1):
+			text = "-.inf"
+		case math.IsNaN(v):
+			text = ".nan"
+		case !strings.ContainsAny(text, ".e"):
+			text += ".0"
+		}
+		return scalar("!!float", text), nil
+	case nil:
+		return nil, fmt.Errorf("the parameter server cannot store null values")
+	}
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// DumpParamFile writes the parameter or namespace name to the YAML file at path.
+func DumpParamFile(node Node, name string, path string) error {
+	data, err := DumpParamYAML(node, name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,100 @@
+package ros
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseParamYAML(t *testing.T) {
+	value, err := ParseParamYAML([]byte(`
+rate: 10
+big: 10000000000
+scale: 0.5
+name: base_link
+quoted: "10"
+enabled: yes
+lights: Off
+answer: "yes"
+frames: [map, odom]
+defaults: &defaults
+  gain: 1
+tuned:
+  <<: *defaults
+  offset: 2
+data: !!binary aGVsbG8=
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"rate":     int32(10),
+		"big":      float64(10000000000),
+		"scale":    0.5,
+		"name":     "base_link",
+		"quoted":   "10",
+		"enabled":  true,
+		"lights":   false,
+		"answer":   "yes",
+		"frames":   []interface{}{"map", "odom"},
+		"defaults": map[string]interface{}{"gain": int32(1)},
+		"tuned":    map[string]interface{}{"gain": int32(1), "offset": int32(2)},
+		"data":     []byte("hello"),
+	}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("Unexpected value %#v", value)
+	}
+
+	// Strings that YAML 1.1 reads as booleans are quoted when dumped.
+	node, err := encodeYAMLParam(map[string]interface{}{"answer": "yes", "enabled": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed, err := ParseParamYAML(data); err != nil || !reflect.DeepEqual(parsed, map[string]interface{}{"answer": "yes", "enabled": true}) {
+		t.Errorf("Unexpected round trip %#v of %s: %v", parsed, data, err)
+	}
+
+	if _, err := ParseParamYAML([]byte("value: null")); err == nil {
+		t.Error("Expected null values to be rejected")
+	}
+}
+
+func TestParseParamYAMLAngles(t *testing.T) {
+	value, err := ParseParamYAML([]byte(`
+a: !degrees 180
+b: !radians pi/2
+c: deg(-90)
+d: rad(2*pi - 1)
+e: !degrees 1e2
+not_angle: "deg(90)"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := value.(map[string]interface{})
+	angles := map[string]float64{
+		"a": math.Pi,
+		"b": math.Pi / 2,
+		"c": -math.Pi / 2,
+		"d": 2*math.Pi - 1,
+		"e": 100 * math.Pi / 180,
+	}
+	for key, expected := range angles {
+		if actual, ok := m[key].(float64); !ok || math.Abs(actual-expected) > 1e-12 {
+			t.Errorf("Expected %s to be %v but got %v", key, expected, m[key])
+		}
+	}
+	if m["not_angle"] != "deg(90)" {
+		t.Errorf("Expected a quoted string to stay a string but got %v", m["not_angle"])
+	}
+
+	if _, err := ParseParamYAML([]byte("a: !degrees ninety")); err == nil {
+		t.Error("Expected an invalid angle to be rejected")
+	}
+}
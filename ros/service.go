@@ -16,3 +16,11 @@ type Service interface {
 	ReqMessage() Message
 	ResMessage() Message
 }
+
+// TypedService is a Service that knows its ServiceType, as the services generated by
+// gengo do. GetServiceType must not dereference its receiver, so that the type can be
+// taken from a nil service.
+type TypedService interface {
+	Service
+	GetServiceType() ServiceType
+}
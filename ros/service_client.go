@@ -5,18 +5,19 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
 	"net"
 	"net/url"
 	"time"
 )
 
 type defaultServiceClient struct {
-	logger    Logger
-	service   string
-	srvType   ServiceType
-	masterURI string
-	nodeID    string
+	logger         Logger
+	service        string
+	srvType        ServiceType
+	masterURI      string
+	nodeID         string
+	maxHeaderSize  int
+	maxMessageSize int
 }
 
 func newDefaultServiceClient(logger Logger, nodeID string, masterURI string, service string, srvType ServiceType) *defaultServiceClient {
@@ -26,6 +27,8 @@ func newDefaultServiceClient(logger Logger, nodeID string, masterURI string, ser
 	client.srvType = srvType
 	client.masterURI = masterURI
 	client.nodeID = nodeID
+	client.maxHeaderSize = defaultMaxHeaderSize
+	client.maxMessageSize = defaultMaxMessageSize
 	return client
 }
 
@@ -52,6 +55,7 @@ func (c *defaultServiceClient) Call(srv Service) error {
 	if err != nil {
 		return err
 	}
+	defer conn.Close()
 
 	// 1. Write connection header
 	var headers []header
@@ -72,7 +76,7 @@ func (c *defaultServiceClient) Call(srv Service) error {
 
 	// 2. Read reponse header
 	conn.SetDeadline(time.Now().Add(10 * time.Millisecond))
-	resHeaders, err := readConnectionHeader(conn)
+	resHeaders, err := readConnectionHeader(conn, c.maxHeaderSize)
 	if err != nil {
 		return err
 	}
@@ -84,7 +88,8 @@ func (c *defaultServiceClient) Call(srv Service) error {
 		logger.Debugf("  `%s` = `%s`", h.key, h.value)
 	}
 	if resHeaderMap["type"] != msgType || resHeaderMap["md5sum"] != md5sum {
-		logger.Fatalf("Incompatible message type!")
+		return fmt.Errorf("incompatible service type: %s (%s) vs %s (%s)",
+			msgType, md5sum, resHeaderMap["type"], resHeaderMap["md5sum"])
 	}
 	logger.Debug("Start receiving messages...")
 
@@ -111,15 +116,9 @@ func (c *defaultServiceClient) Call(srv Service) error {
 	}
 
 	if ok == 0 {
-		var size uint32
 		conn.SetDeadline(time.Now().Add(10 * time.Millisecond))
-		if err := binary.Read(conn, binary.LittleEndian, &size); err != nil {
-			return err
-		}
-
-		errMsg := make([]byte, int(size))
-		conn.SetDeadline(time.Now().Add(10 * time.Millisecond))
-		if _, err := io.ReadFull(conn, errMsg); err != nil {
+		errMsg, err := readMessageFrame(conn, c.maxMessageSize)
+		if err != nil {
 			return err
 		}
 
@@ -128,17 +127,12 @@ func (c *defaultServiceClient) Call(srv Service) error {
 
 	// 5. Receive response
 	conn.SetDeadline(time.Now().Add(10 * time.Millisecond))
-	logger.Debug("Reading message size...")
-	var msgSize uint32
-	if err := binary.Read(conn, binary.LittleEndian, &msgSize); err != nil {
-		return err
-	}
-	logger.Debugf("  %d", msgSize)
-	resBuffer := make([]byte, int(msgSize))
-	logger.Debug("Reading message body...")
-	if _, err = io.ReadFull(conn, resBuffer); err != nil {
+	logger.Debug("Reading message...")
+	resBuffer, err := readMessageFrame(conn, c.maxMessageSize)
+	if err != nil {
 		return err
 	}
+	logger.Debugf("  %d", len(resBuffer))
 	resReader := bytes.NewReader(resBuffer)
 	if err := srv.ResMessage().Deserialize(resReader); err != nil {
 		return err
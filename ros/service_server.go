@@ -5,10 +5,10 @@ import (
 	"container/list"
 	"encoding/binary"
 	"fmt"
-	"io"
 	"net"
-	"reflect"
 	"time"
+
+	"github.com/fetchrobotics/rosgo/internal/nodeimpl"
 )
 
 type serviceResult struct {
@@ -108,7 +108,7 @@ func (s *defaultServiceServer) start() {
 		select {
 		case ev := <-s.sessionCloseChan:
 			if ev.err != nil {
-				logger.Error("session error: %v", ev.err)
+				logger.Errorf("session error: %v", ev.err)
 			}
 			for e := s.sessions.Front(); e != nil; e = e.Next() {
 				if e.Value == ev.session {
@@ -124,7 +124,7 @@ func (s *defaultServiceServer) start() {
 			_, err := callRosAPI(s.node.masterURI, "unregisterService",
 				s.node.qualifiedName, s.service, s.rosrpcAddr)
 			if err != nil {
-				logger.Warn("Failed unregisterService(%s): %v", s.service, err)
+				logger.Warnf("Failed unregisterService(%s): %v", s.service, err)
 			}
 			logger.Debugf("Called unregisterService(%s)", s.service)
 			for e := s.sessions.Front(); e != nil; e = e.Next() {
@@ -167,6 +167,7 @@ func (s *remoteClientSession) start() {
 	var err error
 	logger.Debugf("remoteClientSession.start '%s'", s.server.service)
 	defer func() {
+		conn.Close()
 		logger.Debug("remoteClientSession.start exit")
 	}()
 	defer func() {
@@ -185,7 +186,7 @@ func (s *remoteClientSession) start() {
 
 	// 1. Read request header
 	conn.SetDeadline(time.Now().Add(10 * time.Millisecond))
-	reqHeader, err := readConnectionHeader(conn)
+	reqHeader, err := readConnectionHeader(conn, s.server.node.options.maxHeaderSize)
 	if err != nil {
 		panic(err)
 	}
@@ -216,55 +217,36 @@ func (s *remoteClientSession) start() {
 	}
 	if reqHeaderMap["service"] != service ||
 		reqHeaderMap["md5sum"] != md5sum {
-		logger.Fatalf("Incompatible message type!")
+		panic(fmt.Errorf("incompatible service request from %s: %s (%s)",
+			reqHeaderMap["callerid"], reqHeaderMap["service"], reqHeaderMap["md5sum"]))
 	}
 
 	// 3. Read request
-	logger.Debug("Reading message size...")
-	var msgSize uint32
-	conn.SetDeadline(time.Now().Add(10 * time.Millisecond))
-	if err := binary.Read(conn, binary.LittleEndian, &msgSize); err != nil {
-		panic(err)
-	}
-	logger.Debugf("  %d", msgSize)
-	resBuffer := make([]byte, int(msgSize))
-	logger.Debug("Reading message body...")
+	logger.Debug("Reading message...")
 	conn.SetDeadline(time.Now().Add(10 * time.Millisecond))
-	if _, err = io.ReadFull(conn, resBuffer); err != nil {
+	resBuffer, err := readMessageFrame(conn, s.server.node.options.maxMessageSize)
+	if err != nil {
 		panic(err)
 	}
+	logger.Debugf("  %d", len(resBuffer))
 
-	s.server.node.jobChan <- func() {
+	s.server.node.executor.Post(Job{Name: "service " + s.server.service, Run: func() {
 		srv := s.server.srvType.NewService()
 		reader := bytes.NewReader(resBuffer)
-		err := srv.ReqMessage().Deserialize(reader)
-		if err != nil {
-			s.errorChan <- err
-		}
-		args := []reflect.Value{reflect.ValueOf(srv)}
-		fun := reflect.ValueOf(s.server.handler)
-		results := fun.Call(args)
-
-		if len(results) != 1 {
-			logger.Debug("Service callback return type must be 'error'")
+		if err := srv.ReqMessage().Deserialize(reader); err != nil {
 			s.errorChan <- err
 			return
 		}
-		result := results[0]
-		if result.IsNil() {
-			logger.Debug("Service callback success")
-			var buf bytes.Buffer
-			_ = srv.ResMessage().Serialize(&buf)
-			s.responseChan <- buf.Bytes()
-		} else {
+		if err := nodeimpl.CallServiceHandler(s.server.handler, srv); err != nil {
 			logger.Debug("Service callback failure")
-			if err, ok := result.Interface().(error); ok {
-				s.errorChan <- err
-			} else {
-				s.errorChan <- fmt.Errorf("Service handler has invalid signature")
-			}
+			s.errorChan <- err
+			return
 		}
-	}
+		logger.Debug("Service callback success")
+		var buf bytes.Buffer
+		_ = srv.ResMessage().Serialize(&buf)
+		s.responseChan <- buf.Bytes()
+	}})
 
 	timeoutChan := time.After(1000 * time.Millisecond)
 	select {
@@ -0,0 +1,342 @@
+package ros
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Parameters that enable and tune the statistics of subscriptions, as read by roscpp.
+const (
+	statisticsTopic              = "/statistics"
+	enableStatisticsParam        = "/enable_statistics"
+	statisticsWindowMinParam     = "/statistics_window_min"
+	statisticsWindowMaxParam     = "/statistics_window_max"
+	statisticsMinElementsParam   = "/statistics_window_min_elements"
+	statisticsMaxElementsParam   = "/statistics_window_max_elements"
+	defaultStatisticsWindowMin   = 4
+	defaultStatisticsWindowMax   = 64
+	defaultStatisticsMinElements = 10
+	defaultStatisticsMaxElements = 100
+)
+
+const topicStatisticsText = `string topic
+string node_pub
+string node_sub
+time window_start
+time window_stop
+int32 delivered_msgs
+int32 dropped_msgs
+int32 traffic
+duration period_mean
+duration period_stddev
+duration period_max
+duration stamp_age_mean
+duration stamp_age_stddev
+duration stamp_age_max`
+
+type topicStatisticsType struct{}
+
+func (t *topicStatisticsType) Text() string        { return topicStatisticsText }
+func (t *topicStatisticsType) MD5Sum() string      { return "10152ed868c5097a5e2e4a89d7daa710" }
+func (t *topicStatisticsType) Name() string        { return "rosgraph_msgs/TopicStatistics" }
+func (t *topicStatisticsType) NewMessage() Message { return new(topicStatistics) }
+
+var msgTopicStatistics = &topicStatisticsType{}
+
+// topicStatistics is a rosgraph_msgs/TopicStatistics message.
+type topicStatistics struct {
+	Topic          string
+	NodePub        string
+	NodeSub        string
+	WindowStart    Time
+	WindowStop     Time
+	DeliveredMsgs  int32
+	DroppedMsgs    int32
+	Traffic        int32
+	PeriodMean     Duration
+	PeriodStddev   Duration
+	PeriodMax      Duration
+	StampAgeMean   Duration
+	StampAgeStddev Duration
+	StampAgeMax    Duration
+}
+
+func (m *topicStatistics) GetType() MessageType {
+	return msgTopicStatistics
+}
+
+func (m *topicStatistics) Serialize(buf *bytes.Buffer) error {
+	for _, s := range []string{m.Topic, m.NodePub, m.NodeSub} {
+		binary.Write(buf, binary.LittleEndian, uint32(len(s)))
+		buf.WriteString(s)
+	}
+	for _, t := range []*temporal{&m.WindowStart.temporal, &m.WindowStop.temporal} {
+		binary.Write(buf, binary.LittleEndian, t)
+	}
+	binary.Write(buf, binary.LittleEndian, []int32{m.DeliveredMsgs, m.DroppedMsgs, m.Traffic})
+	for _, d := range m.durations() {
+		binary.Write(buf, binary.LittleEndian, d)
+	}
+	return nil
+}
+
+func (m *topicStatistics) Deserialize(buf *bytes.Reader) error {
+	for _, s := range []*string{&m.Topic, &m.NodePub, &m.NodeSub} {
+		var size uint32
+		if err := binary.Read(buf, binary.LittleEndian, &size); err != nil {
+			return err
+		}
+		if int64(size) > int64(buf.Len()) {
+			return io.ErrUnexpectedEOF
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(buf, data); err != nil {
+			return err
+		}
+		*s = string(data)
+	}
+	for _, t := range []*temporal{&m.WindowStart.temporal, &m.WindowStop.temporal} {
+		if err := binary.Read(buf, binary.LittleEndian, t); err != nil {
+			return err
+		}
+	}
+	for _, i := range []*int32{&m.DeliveredMsgs, &m.DroppedMsgs, &m.Traffic} {
+		if err := binary.Read(buf, binary.LittleEndian, i); err != nil {
+			return err
+		}
+	}
+	for _, d := range m.durations() {
+		if err := binary.Read(buf, binary.LittleEndian, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *topicStatistics) durations() []*temporal {
+	return []*temporal{
+		&m.PeriodMean.temporal, &m.PeriodStddev.temporal, &m.PeriodMax.temporal,
+		&m.StampAgeMean.temporal, &m.StampAgeStddev.temporal, &m.StampAgeMax.temporal,
+	}
+}
+
+// statisticsConfig holds the statistics parameters of the node.
+type statisticsConfig struct {
+	minWindow   time.Duration
+	maxWindow   time.Duration
+	minElements int
+	maxElements int
+}
+
+// loadStatisticsConfig reads the statistics parameters. It returns nil if statistics
+// are disabled.
+func loadStatisticsConfig(node Node) (*statisticsConfig, error) {
+	enabled, err := GetParamBool(node, enableStatisticsParam, false)
+	if err != nil || !enabled {
+		return nil, err
+	}
+	minWindow, err := GetParamFloat(node, statisticsWindowMinParam, defaultStatisticsWindowMin)
+	if err != nil {
+		return nil, err
+	}
+	maxWindow, err := GetParamFloat(node, statisticsWindowMaxParam, defaultStatisticsWindowMax)
+	if err != nil {
+		return nil, err
+	}
+	config := &statisticsConfig{
+		minWindow: time.Duration(minWindow * float64(time.Second)),
+		maxWindow: time.Duration(maxWindow * float64(time.Second)),
+	}
+	if config.minWindow <= 0 || config.maxWindow < config.minWindow {
+		return nil, fmt.Errorf("invalid statistics window [%v, %v]", config.minWindow, config.maxWindow)
+	}
+	if config.minElements, err = GetParamInt(node, statisticsMinElementsParam, defaultStatisticsMinElements); err != nil {
+		return nil, err
+	}
+	if config.maxElements, err = GetParamInt(node, statisticsMaxElementsParam, defaultStatisticsMaxElements); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// connectionStatistics holds what was received from one publisher in the current window.
+type connectionStatistics struct {
+	windowStart time.Time
+	arrivals    []time.Time
+	ages        []time.Duration
+	dropped     int
+	traffic     int
+}
+
+// statisticsLogger computes the statistics of each connection of a subscription, like
+// the StatisticsLogger of roscpp. It is owned by the subscriber goroutine.
+//
+// The window is shared by the connections. It starts at the minimum and is doubled
+// when a window holds more than the maximum number of messages, or halved when it
+// holds less than the minimum, within the configured range.
+type statisticsLogger struct {
+	topic       string
+	nodeName    string
+	config      statisticsConfig
+	window      time.Duration
+	hasHeader   bool
+	connections map[string]*connectionStatistics
+	publish     func(Message)
+}
+
+func newStatisticsLogger(topic string, nodeName string, msgType MessageType, config statisticsConfig, publish func(Message)) *statisticsLogger {
+	return &statisticsLogger{
+		topic:       topic,
+		nodeName:    nodeName,
+		config:      config,
+		window:      config.minWindow,
+		hasHeader:   hasHeader(msgType),
+		connections: make(map[string]*connectionStatistics),
+		publish:     publish,
+	}
+}
+
+// record adds a message of the publisher of event. dropped tells whether the message
+// was dropped by a full subscriber channel. The statistics of the connection are
+// published when its window is over.
+func (l *statisticsLogger) record(msg []byte, event MessageEvent, dropped bool) {
+	received := event.ReceiptTime
+	stats, ok := l.connections[event.PublisherName]
+	if !ok {
+		stats = &connectionStatistics{windowStart: received}
+		l.connections[event.PublisherName] = stats
+	}
+	stats.arrivals = append(stats.arrivals, received)
+	stats.traffic += len(msg)
+	if dropped {
+		stats.dropped++
+	}
+	if stamp, ok := l.stamp(msg); ok {
+		stats.ages = append(stats.ages, received.Sub(stamp))
+	}
+	if received.Sub(stats.windowStart) <= l.window {
+		return
+	}
+
+	result := &topicStatistics{
+		Topic:         l.topic,
+		NodePub:       event.PublisherName,
+		NodeSub:       l.nodeName,
+		WindowStart:   timeFromGo(stats.windowStart),
+		WindowStop:    timeFromGo(received),
+		DeliveredMsgs: int32(len(stats.arrivals)),
+		DroppedMsgs:   int32(stats.dropped),
+		Traffic:       int32(stats.traffic),
+	}
+	periods := make([]time.Duration, 0, len(stats.arrivals))
+	for i := 1; i < len(stats.arrivals); i++ {
+		periods = append(periods, stats.arrivals[i].Sub(stats.arrivals[i-1]))
+	}
+	result.PeriodMean, result.PeriodStddev, result.PeriodMax = summarize(periods)
+	result.StampAgeMean, result.StampAgeStddev, result.StampAgeMax = summarize(stats.ages)
+	l.publish(result)
+
+	switch n := len(stats.arrivals); {
+	case n > l.config.maxElements && l.window*2 <= l.config.maxWindow:
+		l.window *= 2
+	case n < l.config.minElements && l.window/2 >= l.config.minWindow:
+		l.window /= 2
+	}
+	*stats = connectionStatistics{windowStart: received}
+}
+
+// forget drops the statistics of a publisher that disconnected.
+func (l *statisticsLogger) forget(publisherName string) {
+	delete(l.connections, publisherName)
+}
+
+// stamp returns the header stamp of a serialized message whose type starts with a
+// header. Unset stamps are ignored.
+func (l *statisticsLogger) stamp(msg []byte) (time.Time, bool) {
+	if !l.hasHeader || len(msg) < 12 {
+		return time.Time{}, false
+	}
+	sec := binary.LittleEndian.Uint32(msg[4:8])
+	nsec := binary.LittleEndian.Uint32(msg[8:12])
+	if sec == 0 && nsec == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(sec), int64(nsec)), true
+}
+
+// hasHeader reports whether messages of msgType start with a header, so that their
+// serialized stamp follows the sequence number.
+func hasHeader(msgType MessageType) bool {
+	t := reflect.TypeOf(msgType.NewMessage())
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	index, ok := headerIndex(t.Elem())
+	return ok && index.header == 0
+}
+
+// summarize returns the mean, the standard deviation and the maximum of durations.
+// Negative durations, such as the age of a stamp from an unsynchronized clock, count
+// as zero.
+func summarize(durations []time.Duration) (Duration, Duration, Duration) {
+	if len(durations) == 0 {
+		return Duration{}, Duration{}, Duration{}
+	}
+	var sum, max float64
+	for _, d := range durations {
+		x := math.Max(0, float64(d))
+		sum += x
+		max = math.Max(max, x)
+	}
+	mean := sum / float64(len(durations))
+	var variance float64
+	for _, d := range durations {
+		x := math.Max(0, float64(d))
+		variance += (x - mean) * (x - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(durations)))
+	return durationFromNSec(mean), durationFromNSec(stddev), durationFromNSec(max)
+}
+
+func durationFromNSec(nsec float64) Duration {
+	var d Duration
+	d.FromNSec(uint64(nsec))
+	return d
+}
+
+func timeFromGo(t time.Time) Time {
+	var result Time
+	result.FromNSec(uint64(t.UnixNano()))
+	return result
+}
+
+// statisticsConfig reads the statistics parameters once per node, when the first
+// subscription is created. It returns nil if statistics are disabled.
+func (node *defaultNode) statisticsConfig() *statisticsConfig {
+	node.statisticsOnce.Do(func() {
+		config, err := loadStatisticsConfig(node)
+		if err != nil {
+			node.logger.Errorf("Statistics are disabled: %v", err)
+		}
+		node.statistics = config
+	})
+	return node.statistics
+}
+
+// newStatisticsLogger returns the statistics logger of a new subscription to topic, or
+// nil if config is nil because statistics are disabled. The statistics of /statistics
+// and /clock are never published. The caller must hold subscribersMutex.
+func (node *defaultNode) newStatisticsLogger(topic string, msgType MessageType, config *statisticsConfig) *statisticsLogger {
+	if topic == statisticsTopic || topic == "/clock" || config == nil {
+		return nil
+	}
+	if node.statisticsPub == nil {
+		node.statisticsPub = node.advertise(statisticsTopic, msgTopicStatistics, nil, nil, []PublisherOption{WithQueueSize(10)})
+	}
+	return newStatisticsLogger(topic, node.qualifiedName, msgType, *config, node.statisticsPub.Publish)
+}
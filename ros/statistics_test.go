@@ -0,0 +1,158 @@
+package ros
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type stampedMessageType struct {
+	dummyMessage
+}
+
+func (m *stampedMessageType) Text() string {
+	return "# A stamped value\n\nHeader header  # the stamp\nfloat64 value\n"
+}
+
+func (m *stampedMessageType) NewMessage() Message {
+	return &stampedValue{}
+}
+
+// stampedValue is a message of stampedMessageType, laid out like a generated message.
+type stampedValue struct {
+	Header struct {
+		Seq     uint32
+		Stamp   Time
+		FrameId string
+	}
+	Value float64
+}
+
+func (m *stampedValue) GetType() MessageType {
+	return &stampedMessageType{}
+}
+
+func (m *stampedValue) Serialize(buf *bytes.Buffer) error {
+	_, err := buf.Write(stampedMessage(time.Unix(int64(m.Header.Stamp.Sec), int64(m.Header.Stamp.NSec))))
+	return err
+}
+
+func (m *stampedValue) Deserialize(buf *bytes.Reader) error {
+	return fmt.Errorf("stampedValue cannot be deserialized")
+}
+
+// stampedMessage serializes a message of stampedMessageType.
+func stampedMessage(stamp time.Time) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint32{7, uint32(stamp.Unix()), uint32(stamp.Nanosecond()), 0})
+	binary.Write(&buf, binary.LittleEndian, 1.5)
+	return buf.Bytes()
+}
+
+func TestHasHeader(t *testing.T) {
+	if !hasHeader(&stampedMessageType{}) {
+		t.Error("expected a header")
+	}
+	if hasHeader(&dummyMessage{}) {
+		t.Error("expected no header in an empty message")
+	}
+	if hasHeader(&rawMessageType{}) {
+		t.Error("expected no header in a raw message")
+	}
+}
+
+func TestStatisticsLogger(t *testing.T) {
+	var published []*topicStatistics
+	config := statisticsConfig{minWindow: time.Second, maxWindow: 4 * time.Second, minElements: 2, maxElements: 5}
+	logger := newStatisticsLogger("/data", "/listener", &stampedMessageType{}, config, func(msg Message) {
+		published = append(published, msg.(*topicStatistics))
+	})
+
+	start := time.Unix(1000, 0)
+	receive := func(publisher string, offset time.Duration, age time.Duration, dropped bool) {
+		received := start.Add(offset)
+		logger.record(stampedMessage(received.Add(-age)), MessageEvent{PublisherName: publisher, ReceiptTime: received}, dropped)
+	}
+	receive("/talker", 0, 10*time.Millisecond, false)
+	receive("/talker", 200*time.Millisecond, 30*time.Millisecond, true)
+	receive("/other", 300*time.Millisecond, 0, false)
+	receive("/talker", 600*time.Millisecond, 20*time.Millisecond, false)
+	if len(published) != 0 {
+		t.Fatalf("expected no statistics before the end of the window but got %d", len(published))
+	}
+	receive("/talker", 1200*time.Millisecond, 20*time.Millisecond, false)
+	if len(published) != 1 {
+		t.Fatalf("expected statistics at the end of the window but got %d", len(published))
+	}
+
+	stats := published[0]
+	if stats.Topic != "/data" || stats.NodePub != "/talker" || stats.NodeSub != "/listener" {
+		t.Errorf("unexpected connection %s from %s to %s", stats.Topic, stats.NodePub, stats.NodeSub)
+	}
+	if stats.WindowStart != NewTime(1000, 0) || stats.WindowStop != NewTime(1001, 200000000) {
+		t.Errorf("unexpected window [%v, %v]", stats.WindowStart, stats.WindowStop)
+	}
+	if stats.DeliveredMsgs != 4 || stats.DroppedMsgs != 1 || stats.Traffic != 4*24 {
+		t.Errorf("unexpected counts %d delivered, %d dropped, %d bytes", stats.DeliveredMsgs, stats.DroppedMsgs, stats.Traffic)
+	}
+	if stats.PeriodMean != NewDuration(0, 400000000) || stats.PeriodMax != NewDuration(0, 600000000) {
+		t.Errorf("unexpected periods with mean %v and max %v", stats.PeriodMean, stats.PeriodMax)
+	}
+	if stddev := stats.PeriodStddev.ToSec(); stddev < 0.163 || stddev > 0.164 {
+		t.Errorf("expected a period stddev of 0.163s but got %v", stddev)
+	}
+	if stats.StampAgeMean != NewDuration(0, 20000000) || stats.StampAgeMax != NewDuration(0, 30000000) {
+		t.Errorf("unexpected stamp ages with mean %v and max %v", stats.StampAgeMean, stats.StampAgeMax)
+	}
+
+	var buf bytes.Buffer
+	if err := stats.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded topicStatistics
+	if err := decoded.Deserialize(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, stats) {
+		t.Errorf("expected %+v after a round trip but got %+v", stats, decoded)
+	}
+
+	// The window held less than minElements messages of /other, so it does not grow.
+	logger.forget("/other")
+	receive("/other", 1300*time.Millisecond, 0, false)
+	receive("/other", 2400*time.Millisecond, 0, false)
+	if len(published) != 2 || published[1].DeliveredMsgs != 2 {
+		t.Fatalf("expected the forgotten connection to start a new window but got %+v", published[1:])
+	}
+
+	for i := 0; i < 6; i++ {
+		receive("/talker", 1300*time.Millisecond+time.Duration(i)*200*time.Millisecond, 0, false)
+	}
+	if logger.window != 2*time.Second {
+		t.Errorf("expected the window to grow to 2s but got %v", logger.window)
+	}
+}
+
+func TestStatisticsConfigIsReadOnce(t *testing.T) {
+	master, server := newStandInMaster(t)
+	node, err := newDefaultNode("/listener", []string{"__master:=" + server.URL}, WithExecutor(NewManualExecutor(time.Unix(0, 0))))
+	if err != nil {
+		t.Fatalf("Error starting new test node: %v", err)
+	}
+	defer node.Shutdown()
+	calls := func() int {
+		master.mutex.Lock()
+		defer master.mutex.Unlock()
+		return len(master.callers)
+	}
+
+	node.NewSubscriber("/first", &rawMessageType{}, func(*rawMessage) {})
+	before := calls()
+	node.NewSubscriber("/second", &rawMessageType{}, func(*rawMessage) {})
+	if n := calls() - before; n != 1 {
+		t.Errorf("Expected only registerSubscriber to be called for the second subscription but got %d calls", n)
+	}
+}
@@ -6,9 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"reflect"
 	"sync"
 	"time"
+
+	"github.com/fetchrobotics/rosgo/internal/nodeimpl"
 )
 
 type messageEvent struct {
@@ -16,38 +17,150 @@ type messageEvent struct {
 	event MessageEvent
 }
 
+// publisherConnectionState is the kind of a publisherConnectionEvent.
+type publisherConnectionState int
+
+const (
+	publisherConnected publisherConnectionState = iota
+	publisherDisconnected
+	publisherRejected
+)
+
+// publisherConnectionEvent is reported by connection goroutines to the subscriber goroutine.
+type publisherConnectionEvent struct {
+	state publisherConnectionState
+	event PublisherEvent
+}
+
+func newPublisherConnectionEvent(state publisherConnectionState, topic string, pubURI string, headerMap map[string]string, err error) publisherConnectionEvent {
+	return publisherConnectionEvent{state, PublisherEvent{
+		Topic:            topic,
+		CallerID:         headerMap["callerid"],
+		URI:              pubURI,
+		ConnectionHeader: headerMap,
+		Err:              err,
+	}}
+}
+
+// publisherCallbacks are the connection callbacks registered with WithPublisherCallbacks.
+type publisherCallbacks struct {
+	handleID     int
+	connected    func(PublisherEvent)
+	disconnected func(PublisherEvent)
+	rejected     func(PublisherEvent)
+}
+
+// handleMonitor is the deadline and liveliness monitor of one subscriber handle.
+type handleMonitor struct {
+	handleID int
+	monitor  *SubscriptionMonitor
+}
+
+// messageChannel delivers received messages straight from the subscriber goroutine
+// into a bounded channel, bypassing the job queue. Only the subscriber goroutine
+// sends on and closes the channel.
+type messageChannel struct {
+	handleID int
+	ch       chan ReceivedMessage
+	policy   DropPolicy
+	dropped  uint64
+}
+
+func newMessageChannel(size int, policy DropPolicy) *messageChannel {
+	if size < 1 {
+		size = 1
+	}
+	return &messageChannel{ch: make(chan ReceivedMessage, size), policy: policy}
+}
+
+// push delivers msg without blocking. It returns false if a message had to be dropped.
+func (c *messageChannel) push(msg ReceivedMessage) bool {
+	if nodeimpl.PushMessage(c.ch, c.policy == DropOldest, msg) {
+		return true
+	}
+	c.dropped++
+	return false
+}
+
+// messageCallback is a callback registered by one subscriber handle.
+type messageCallback struct {
+	handleID int
+	callback interface{}
+}
+
 // The subscription object runs in own goroutine (startSubscription).
 // Do not access any properties from other goroutine.
 type defaultSubscriber struct {
-	topic            string
-	msgType          MessageType
-	pubList          []string
-	pubListChan      chan []string
-	msgChan          chan messageEvent
-	callbacks        []interface{}
-	addCallbackChan  chan interface{}
-	shutdownChan     chan struct{}
-	connections      map[string]chan struct{}
-	disconnectedChan chan string
-}
-
-func newDefaultSubscriber(topic string, msgType MessageType, callback interface{}) *defaultSubscriber {
-	return &defaultSubscriber{
-		topic:            topic,
-		msgType:          msgType,
-		msgChan:          make(chan messageEvent, 10),
-		pubListChan:      make(chan []string, 10),
-		addCallbackChan:  make(chan interface{}, 10),
-		shutdownChan:     make(chan struct{}, 10),
-		disconnectedChan: make(chan string, 10),
-		connections:      make(map[string]chan struct{}),
-		callbacks:        []interface{}{callback}}
-}
-
-func (sub *defaultSubscriber) start(wg *sync.WaitGroup, nodeID string, nodeURI string, masterURI string, jobChan chan func(), logger Logger) {
+	topic          string
+	msgType        MessageType
+	options        subscriberOptions
+	hostname       string
+	listenIP       string
+	maxHeaderSize  int
+	maxMessageSize int
+	pubList        []string
+	pubListChan    chan []string
+	msgChan        chan messageEvent
+	callbacks      []messageCallback
+	channels       []*messageChannel
+	shutdownChan   chan struct{}
+	connections    map[string]chan struct{}
+	publishers     map[string]PublisherEvent
+
+	// done is closed when the subscriber goroutine exits, so that the goroutines of
+	// publisher connections stop sending to it.
+	done chan struct{}
+
+	// Handles are counted under the node's subscribersMutex.
+	handleIDCount int
+	numHandles    int
+
+	// Handle updates are queued under handleMutex and handleChan tells the subscriber
+	// goroutine to take them, so that adding or removing a handle never waits for it.
+	handleMutex   sync.Mutex
+	handleUpdates []subscriberHandleUpdate
+	handleChan    chan struct{}
+
+	publisherCallbacks  []publisherCallbacks
+	connectionEventChan chan publisherConnectionEvent
+	monitors            []handleMonitor
+
+	// statistics is nil unless the node publishes topic statistics.
+	statistics *statisticsLogger
+}
+
+func newDefaultSubscriber(topic string, msgType MessageType, callback interface{}, opts ...SubscriberOption) *defaultSubscriber {
+	sub := &defaultSubscriber{
+		topic:         topic,
+		msgType:       msgType,
+		options:       newSubscriberOptions(opts),
+		msgChan:       make(chan messageEvent, 10),
+		pubListChan:   make(chan []string, 10),
+		shutdownChan:  make(chan struct{}, 10),
+		done:          make(chan struct{}),
+		connections:   make(map[string]chan struct{}),
+		publishers:    make(map[string]PublisherEvent),
+		handleIDCount: 1,
+		numHandles:    1,
+		handleChan:    make(chan struct{}, 1),
+
+		connectionEventChan: make(chan publisherConnectionEvent, 10),
+	}
+	// The callback and options passed here belong to the first handle.
+	if callback != nil {
+		sub.callbacks = append(sub.callbacks, messageCallback{0, callback})
+	}
+	if sub.options.publisherCallbacks != nil {
+		sub.publisherCallbacks = append(sub.publisherCallbacks, *sub.options.publisherCallbacks)
+	}
+	return sub
+}
+
+// start runs the subscriber goroutine. The caller must have added it to wg.
+func (sub *defaultSubscriber) start(wg *sync.WaitGroup, nodeID string, nodeURI string, masterURI string, executor Executor, logger Logger) {
 	logger.Debugf("Subscriber goroutine for %s started.", sub.topic)
-	wg.Add(1)
 	defer wg.Done()
+	defer close(sub.done)
 	defer func() {
 		logger.Debug("defaultSubscriber.start exit")
 	}()
@@ -61,79 +174,103 @@ func (sub *defaultSubscriber) start(wg *sync.WaitGroup, nodeID string, nodeURI s
 			sub.pubList = list
 
 			for _, pub := range deadPubs {
-				quitChan := sub.connections[pub]
-				quitChan <- struct{}{}
-				delete(sub.connections, pub)
+				if quitChan, ok := sub.connections[pub]; ok {
+					quitChan <- struct{}{}
+					delete(sub.connections, pub)
+				}
+				if event, ok := sub.publishers[pub]; ok {
+					delete(sub.publishers, pub)
+					sub.notifyPublisherCallbacks(publisherDisconnected, event)
+				}
 			}
+			sub.notifyMonitors()
 
 			for _, pub := range newPubs {
-				protocols := []interface{}{[]interface{}{"TCPROS"}}
-				result, err := callRosAPI(pub, "requestTopic", nodeID, sub.topic, protocols)
-				if err != nil {
-					logger.Fatalf("[DefaultSubscriber] %v", err)
-					continue
+				if err := sub.connectPublisher(pub, nodeID, executor.Clock(), logger); err != nil {
+					logger.Errorf("[DefaultSubscriber] %v", err)
+					sub.notifyPublisherCallbacks(publisherRejected, PublisherEvent{Topic: sub.topic, URI: pub, Err: err})
 				}
+			}
 
-				protocolParams := result.([]interface{})
-				for _, x := range protocolParams {
-					logger.Debug(x)
-				}
+		case <-sub.handleChan:
+			logger.Debug("Receive handleChan")
+			sub.takeHandleUpdates()
 
-				name := protocolParams[0].(string)
-				if name == "TCPROS" {
-					addr := protocolParams[1].(string)
-					port := protocolParams[2].(int32)
-					uri := fmt.Sprintf("%s:%d", addr, port)
-					quitChan := make(chan struct{}, 10)
-					sub.connections[pub] = quitChan
-					go startRemotePublisherConn(logger,
-						uri, sub.topic,
-						sub.msgType.MD5Sum(),
-						sub.msgType.Name(), nodeID,
-						sub.msgChan,
-						quitChan,
-						sub.disconnectedChan)
-				} else {
-					logger.Warnf("rosgo Not support protocol '%s'", name)
+		case ev := <-sub.connectionEventChan:
+			logger.Debugf("Connection to %s changed state: %d", ev.event.URI, ev.state)
+			if _, ok := sub.connections[ev.event.URI]; !ok {
+				// Stale event of a connection that was already closed.
+				break
+			}
+			switch ev.state {
+			case publisherConnected:
+				sub.publishers[ev.event.URI] = ev.event
+			case publisherDisconnected, publisherRejected:
+				delete(sub.connections, ev.event.URI)
+				delete(sub.publishers, ev.event.URI)
+				if sub.statistics != nil {
+					sub.statistics.forget(ev.event.CallerID)
 				}
 			}
-
-		case callback := <-sub.addCallbackChan:
-			logger.Debug("Receive addCallbackChan")
-			sub.callbacks = append(sub.callbacks, callback)
+			sub.notifyPublisherCallbacks(ev.state, ev.event)
+			sub.notifyMonitors()
 
 		case msgEvent := <-sub.msgChan:
-			// Pop received message then bind callbacks and enqueue to the job channel.
+			// Pop received message then deliver it to the channels directly,
+			// bind callbacks and enqueue to the job channel.
 			logger.Debug("Receive msgChan")
-			callbacks := make([]interface{}, len(sub.callbacks))
+			dropped := false
+			for _, channel := range sub.channels {
+				m := sub.msgType.NewMessage()
+				if err := m.Deserialize(bytes.NewReader(msgEvent.bytes)); err != nil {
+					logger.Error(err)
+					continue
+				}
+				if !channel.push(ReceivedMessage{m, msgEvent.event}) {
+					logger.Debugf("Channel of %s is full, dropped %d messages", sub.topic, channel.dropped)
+					dropped = true
+				}
+			}
+			if sub.statistics != nil {
+				sub.statistics.record(msgEvent.bytes, msgEvent.event, dropped)
+			}
+			for _, m := range sub.monitors {
+				m.monitor.MessageReceived()
+			}
+			if len(sub.callbacks) == 0 {
+				break
+			}
+			callbacks := make([]messageCallback, len(sub.callbacks))
 			copy(callbacks, sub.callbacks)
-			jobChan <- func() {
+			executor.Post(Job{Name: "subscriber " + sub.topic, Run: func() {
 				m := sub.msgType.NewMessage()
 				reader := bytes.NewReader(msgEvent.bytes)
 				if err := m.Deserialize(reader); err != nil {
 					logger.Error(err)
 				}
-				args := []reflect.Value{reflect.ValueOf(m), reflect.ValueOf(msgEvent.event)}
-				for _, callback := range callbacks {
-					fun := reflect.ValueOf(callback)
-					numArgsNeeded := fun.Type().NumIn()
-					if numArgsNeeded <= 2 {
-						fun.Call(args[0:numArgsNeeded])
-					}
+				for _, c := range callbacks {
+					nodeimpl.CallMessageCallback(c.callback, m, msgEvent.event)
 				}
-			}
+			}})
 			logger.Debug("Callback job enqueued.")
 
-		case pubURI := <-sub.disconnectedChan:
-			logger.Debugf("Connection to %s was disconnected.", pubURI)
-			delete(sub.connections, pubURI)
-
 		case <-sub.shutdownChan:
 			// Shutdown subscription goroutine
 			logger.Debug("Receive shutdownChan")
+			// Take the handles added meanwhile to close their channels as well.
+			sub.takeHandleUpdates()
+			for _, m := range sub.monitors {
+				m.monitor.Stop()
+			}
 			for _, closeChan := range sub.connections {
 				close(closeChan)
 			}
+			for _, channel := range sub.channels {
+				close(channel.ch)
+			}
+			for _, event := range sub.publishers {
+				sub.notifyPublisherCallbacks(publisherDisconnected, event)
+			}
 			_, err := callRosAPI(masterURI, "unregisterSubscriber", nodeID, sub.topic, nodeURI)
 			if err != nil {
 				logger.Warn(err)
@@ -143,17 +280,224 @@ func (sub *defaultSubscriber) start(wg *sync.WaitGroup, nodeID string, nodeURI s
 	}
 }
 
-func startRemotePublisherConn(logger Logger,
-	pubURI string, topic string, md5sum string,
+// subscriberHandleUpdate adds or removes what one subscriber handle registered.
+// Updates go through a single queue so that a removal never overtakes an addition.
+type subscriberHandleUpdate struct {
+	handleID           int
+	remove             bool
+	callback           interface{}
+	channel            *messageChannel
+	publisherCallbacks *publisherCallbacks
+	monitor            *SubscriptionMonitor
+}
+
+// queueHandleUpdate queues update for the subscriber goroutine without blocking.
+func (sub *defaultSubscriber) queueHandleUpdate(update subscriberHandleUpdate) {
+	sub.handleMutex.Lock()
+	sub.handleUpdates = append(sub.handleUpdates, update)
+	sub.handleMutex.Unlock()
+	select {
+	case sub.handleChan <- struct{}{}:
+	default:
+		// The subscriber goroutine has yet to take the updates queued before.
+	}
+}
+
+// takeHandleUpdates applies the queued handle updates in order.
+func (sub *defaultSubscriber) takeHandleUpdates() {
+	sub.handleMutex.Lock()
+	updates := sub.handleUpdates
+	sub.handleUpdates = nil
+	sub.handleMutex.Unlock()
+	for _, update := range updates {
+		sub.updateHandle(update)
+	}
+}
+
+func (sub *defaultSubscriber) updateHandle(update subscriberHandleUpdate) {
+	if update.remove {
+		sub.removeHandle(update.handleID)
+		return
+	}
+	if update.callback != nil {
+		sub.callbacks = append(sub.callbacks, messageCallback{update.handleID, update.callback})
+	}
+	if update.channel != nil {
+		sub.channels = append(sub.channels, update.channel)
+	}
+	if update.monitor != nil {
+		sub.monitors = append(sub.monitors, handleMonitor{update.handleID, update.monitor})
+		update.monitor.PublishersChanged(len(sub.publishers))
+	}
+	if callbacks := update.publisherCallbacks; callbacks != nil {
+		sub.publisherCallbacks = append(sub.publisherCallbacks, *callbacks)
+		// Tell the new callbacks about the publishers that are already connected.
+		if callbacks.connected != nil {
+			for _, event := range sub.publishers {
+				go callbacks.connected(event)
+			}
+		}
+	}
+}
+
+// removeHandle drops the callbacks and the channel of a shut down subscriber handle.
+// The channel of the handle is closed.
+func (sub *defaultSubscriber) removeHandle(id int) {
+	callbacks := sub.callbacks[:0]
+	for _, c := range sub.callbacks {
+		if c.handleID != id {
+			callbacks = append(callbacks, c)
+		}
+	}
+	sub.callbacks = callbacks
+
+	channels := sub.channels[:0]
+	for _, channel := range sub.channels {
+		if channel.handleID == id {
+			close(channel.ch)
+		} else {
+			channels = append(channels, channel)
+		}
+	}
+	sub.channels = channels
+
+	publisherCallbacks := sub.publisherCallbacks[:0]
+	for _, c := range sub.publisherCallbacks {
+		if c.handleID != id {
+			publisherCallbacks = append(publisherCallbacks, c)
+		}
+	}
+	sub.publisherCallbacks = publisherCallbacks
+
+	monitors := sub.monitors[:0]
+	for _, m := range sub.monitors {
+		if m.handleID == id {
+			m.monitor.Stop()
+		} else {
+			monitors = append(monitors, m)
+		}
+	}
+	sub.monitors = monitors
+}
+
+// notifyMonitors tells the monitors of the handles the number of connected publishers.
+func (sub *defaultSubscriber) notifyMonitors() {
+	for _, m := range sub.monitors {
+		m.monitor.PublishersChanged(len(sub.publishers))
+	}
+}
+
+// connectPublisher negotiates a connection with the publisher node at pubURI and
+// starts the goroutine receiving its messages.
+func (sub *defaultSubscriber) connectPublisher(pub string, nodeID string, clock Clock, logger Logger) error {
+	protocols, udpConn, err := sub.requestedProtocols(nodeID)
+	if err != nil {
+		return err
+	}
+	result, err := callRosAPI(pub, "requestTopic", nodeID, sub.topic, protocols)
+	if err != nil {
+		if udpConn != nil {
+			udpConn.Close()
+		}
+		return fmt.Errorf("requestTopic(%s) to %s failed: %v", sub.topic, pub, err)
+	}
+
+	protocolParams, ok := result.([]interface{})
+	if !ok || len(protocolParams) == 0 {
+		if udpConn != nil {
+			udpConn.Close()
+		}
+		return fmt.Errorf("publisher %s selected no protocol", pub)
+	}
+	for _, x := range protocolParams {
+		logger.Debug(x)
+	}
+
+	name, _ := protocolParams[0].(string)
+	if name != udpROSProtocol && udpConn != nil {
+		udpConn.Close()
+	}
+	switch {
+	case name == tcpROSProtocol && len(protocolParams) == 3:
+		addr, _ := protocolParams[1].(string)
+		port, _ := protocolParams[2].(int32)
+		quitChan := make(chan struct{}, 10)
+		sub.connections[pub] = quitChan
+		go startRemotePublisherConn(logger, clock,
+			pub, fmt.Sprintf("%s:%d", addr, port), sub.topic,
+			sub.msgType.MD5Sum(),
+			sub.msgType.Name(), nodeID,
+			sub.options.transportHints,
+			sub.maxHeaderSize, sub.messageSizeLimit(),
+			sub.msgChan,
+			quitChan,
+			sub.connectionEventChan,
+			sub.done)
+	case name == udpROSProtocol && udpConn != nil:
+		connectionID, maxDatagramSize, resHeaderMap, err := parseUDPROSProtocolParams(protocolParams)
+		if err != nil {
+			udpConn.Close()
+			return err
+		}
+		quitChan := make(chan struct{}, 10)
+		sub.connections[pub] = quitChan
+		go startRemotePublisherUDPConn(logger, clock,
+			udpConn, pub, sub.topic,
+			sub.msgType.MD5Sum(),
+			connectionID, maxDatagramSize,
+			sub.messageSizeLimit(),
+			resHeaderMap,
+			sub.msgChan,
+			quitChan,
+			sub.connectionEventChan,
+			sub.done)
+	default:
+		return fmt.Errorf("rosgo Not support protocol '%v'", protocolParams[0])
+	}
+	return nil
+}
+
+// notifyPublisherCallbacks calls the connection callbacks of state in their own goroutines.
+func (sub *defaultSubscriber) notifyPublisherCallbacks(state publisherConnectionState, event PublisherEvent) {
+	for _, callbacks := range sub.publisherCallbacks {
+		var callback func(PublisherEvent)
+		switch state {
+		case publisherConnected:
+			callback = callbacks.connected
+		case publisherDisconnected:
+			callback = callbacks.disconnected
+		case publisherRejected:
+			callback = callbacks.rejected
+		}
+		if callback != nil {
+			go callback(event)
+		}
+	}
+}
+
+func startRemotePublisherConn(logger Logger, clock Clock,
+	pubURI string, addr string, topic string, md5sum string,
 	msgType string, nodeID string,
+	hints *TransportHints,
+	maxHeaderSize int, maxMessageSize int,
 	msgChan chan messageEvent,
 	quitChan chan struct{},
-	disconnectedChan chan string) {
+	connectionEventChan chan publisherConnectionEvent,
+	done <-chan struct{}) {
 	logger.Debug("startRemotePublisherConn()")
 
-	conn, err := net.Dial("tcp", pubURI)
+	conn, err := net.Dial("tcp", addr)
 	if err != nil {
-		logger.Fatalf("Failed to connect %s!", pubURI)
+		logger.Errorf("Failed to connect %s: %v", addr, err)
+		sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherRejected, topic, pubURI, nil, err))
+		return
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetNoDelay(hints.GetTCPNoDelay())
+		if period := hints.GetKeepAlive(); period > 0 {
+			tcpConn.SetKeepAlive(true)
+			tcpConn.SetKeepAlivePeriod(period)
+		}
 	}
 	defer func() {
 		if err := conn.Close(); err != nil {
@@ -168,20 +512,25 @@ func startRemotePublisherConn(logger Logger,
 	headers = append(headers, header{"md5sum", md5sum})
 	headers = append(headers, header{"type", msgType})
 	headers = append(headers, header{"callerid", nodeID})
+	headers = append(headers, hints.connectionHeaders()...)
 	logger.Debug("TCPROS Connection Header")
 	for _, h := range headers {
 		logger.Debugf("  `%s` = `%s`", h.key, h.value)
 	}
 	err = writeConnectionHeader(headers, conn)
 	if err != nil {
-		logger.Fatal("Failed to write connection header.")
+		logger.Errorf("Failed to write connection header to %s: %v", addr, err)
+		sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherRejected, topic, pubURI, nil, err))
+		return
 	}
 
 	// 2. Read response header
 	var resHeaders []header
-	resHeaders, err = readConnectionHeader(conn)
+	resHeaders, err = readConnectionHeader(conn, maxHeaderSize)
 	if err != nil {
-		logger.Fatal("Failed to read reasponse header.")
+		logger.Errorf("Failed to read response header from %s: %v", addr, err)
+		sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherRejected, topic, pubURI, nil, err))
+		return
 	}
 	logger.Debug("TCPROS Response Header:")
 	resHeaderMap := make(map[string]string)
@@ -190,9 +539,12 @@ func startRemotePublisherConn(logger Logger,
 		logger.Debugf("  `%s` = `%s`", h.key, h.value)
 	}
 
-	if md5sum != resHeaderMap["md5sum"] && md5sum != "*" {
-		panic("incompatible message type: md5sum mismatch")
+	if err := checkPublisherHeader(md5sum, resHeaderMap); err != nil {
+		logger.Errorf("Publisher %s on topic %s: %v", pubURI, topic, err)
+		sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherRejected, topic, pubURI, resHeaderMap, err))
+		return
 	}
+	sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherConnected, topic, pubURI, resHeaderMap, nil))
 
 	logger.Debug("Start receiving messages...")
 	event := MessageEvent{ // Event struct to be sent with each message.
@@ -201,9 +553,12 @@ func startRemotePublisherConn(logger Logger,
 	}
 
 	// 3. Start reading messages
+	// Reads resume where a timed out read stopped, so a slow sender never
+	// desynchronizes the framing.
 	readingSize := true
-	var msgSize uint32
+	sizeBuffer := make([]byte, 4)
 	var buffer []byte
+	var offset int
 	for {
 		select {
 		case <-quitChan:
@@ -212,11 +567,12 @@ func startRemotePublisherConn(logger Logger,
 			conn.SetDeadline(time.Now().Add(1000 * time.Millisecond))
 			if readingSize {
 				//logger.Debug("Reading message size...")
-				err := binary.Read(conn, binary.LittleEndian, &msgSize)
+				n, err := io.ReadFull(conn, sizeBuffer[offset:])
+				offset += n
 				if err != nil {
 					if err == io.EOF {
 						logger.Infof("Publisher %s on topic %s disconnected", pubURI, topic)
-						disconnectedChan <- pubURI
+						sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherDisconnected, topic, pubURI, resHeaderMap, err))
 						return
 					}
 					if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
@@ -225,19 +581,27 @@ func startRemotePublisherConn(logger Logger,
 						continue
 					} else {
 						logger.Error("Failed to read a message size", err)
-						disconnectedChan <- pubURI
+						sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherDisconnected, topic, pubURI, resHeaderMap, err))
 						return
 					}
 				}
+				msgSize := binary.LittleEndian.Uint32(sizeBuffer)
 				logger.Debugf("  %d", msgSize)
+				if err := checkSizeLimit("message", msgSize, maxMessageSize); err != nil {
+					logger.Errorf("Publisher %s on topic %s: %v", pubURI, topic, err)
+					sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherDisconnected, topic, pubURI, resHeaderMap, err))
+					return
+				}
 				buffer = make([]byte, int(msgSize))
+				offset = 0
 				readingSize = false
 			} else {
-				_, err = io.ReadFull(conn, buffer)
+				n, err := io.ReadFull(conn, buffer[offset:])
+				offset += n
 				if err != nil {
 					if err == io.EOF {
-						logger.Info("Publisher disconnected")
-						disconnectedChan <- pubURI
+						logger.Infof("Publisher %s on topic %s disconnected", pubURI, topic)
+						sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherDisconnected, topic, pubURI, resHeaderMap, err))
 						return
 					}
 					if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
@@ -246,18 +610,177 @@ func startRemotePublisherConn(logger Logger,
 						continue
 					} else {
 						logger.Error("Failed to read a message body", err)
-						disconnectedChan <- pubURI
+						sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherDisconnected, topic, pubURI, resHeaderMap, err))
 						return
 					}
 				}
-				event.ReceiptTime = time.Now()
-				msgChan <- messageEvent{bytes: buffer, event: event}
+				event.ReceiptTime = clock.Now()
+				select {
+				case msgChan <- messageEvent{bytes: buffer, event: event}:
+				case <-done:
+					return
+				}
+				offset = 0
 				readingSize = true
 			}
 		}
 	}
 }
 
+// checkPublisherHeader verifies the connection header a publisher answered with.
+func checkPublisherHeader(md5sum string, headerMap map[string]string) error {
+	if reason, ok := headerMap["error"]; ok {
+		return fmt.Errorf("publisher refused the connection: %s", reason)
+	}
+	if md5sum != headerMap["md5sum"] && md5sum != "*" {
+		return fmt.Errorf("incompatible message type: md5sum mismatch")
+	}
+	return nil
+}
+
+// messageSizeLimit returns the stricter of the node's message size limit and the
+// limit requested in the transport hints.
+func (sub *defaultSubscriber) messageSizeLimit() int {
+	limit := sub.maxMessageSize
+	if hinted := sub.options.transportHints.GetMaxMessageSize(); hinted > 0 && (limit <= 0 || hinted < limit) {
+		limit = hinted
+	}
+	return limit
+}
+
+// requestedProtocols builds the protocol list of a requestTopic call from the transport hints.
+// When UDPROS is requested, the returned socket receives the publisher's datagrams.
+func (sub *defaultSubscriber) requestedProtocols(nodeID string) ([]interface{}, *net.UDPConn, error) {
+	hints := sub.options.transportHints
+	var protocols []interface{}
+	var udpConn *net.UDPConn
+	for _, protocol := range hints.Protocols() {
+		switch protocol {
+		case tcpROSProtocol:
+			protocols = append(protocols, []interface{}{tcpROSProtocol})
+		case udpROSProtocol:
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(sub.listenIP)})
+			if err != nil {
+				return nil, nil, err
+			}
+			udpConn = conn
+			headers := []header{
+				{"topic", sub.topic},
+				{"md5sum", sub.msgType.MD5Sum()},
+				{"callerid", nodeID},
+				{"type", sub.msgType.Name()},
+			}
+			port := conn.LocalAddr().(*net.UDPAddr).Port
+			protocols = append(protocols, []interface{}{
+				udpROSProtocol, encodeHeaderFields(headers), sub.hostname, port, hints.GetMaxDatagramSize()})
+		}
+	}
+	return protocols, udpConn, nil
+}
+
+// parseUDPROSProtocolParams parses the [UDPROS, host, port, connection_id, max_datagram_size, header]
+// reply of a publisher's requestTopic.
+func parseUDPROSProtocolParams(params []interface{}) (uint32, int, map[string]string, error) {
+	if len(params) != 6 {
+		return 0, 0, nil, fmt.Errorf("UDPROS reply expects 6 parameters but got %d", len(params))
+	}
+	connectionID, ok := params[3].(int32)
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("UDPROS connection id is not an integer")
+	}
+	maxDatagramSize, ok := params[4].(int32)
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("UDPROS max datagram size is not an integer")
+	}
+	headerBytes, ok := params[5].([]byte)
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("UDPROS connection header is not binary")
+	}
+	headers, err := decodeHeaderFields(headerBytes)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return uint32(connectionID), int(maxDatagramSize), headersToMap(headers), nil
+}
+
+func startRemotePublisherUDPConn(logger Logger, clock Clock,
+	conn *net.UDPConn, pubURI string, topic string, md5sum string,
+	connectionID uint32, maxDatagramSize int, maxMessageSize int,
+	resHeaderMap map[string]string,
+	msgChan chan messageEvent,
+	quitChan chan struct{},
+	connectionEventChan chan publisherConnectionEvent,
+	done <-chan struct{}) {
+	logger.Debug("startRemotePublisherUDPConn()")
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Errorf("Error closing connection: %v", err)
+		}
+		logger.Debug("startRemotePublisherUDPConn() exit")
+	}()
+
+	logger.Debug("UDPROS Response Header:")
+	for k, v := range resHeaderMap {
+		logger.Debugf("  `%s` = `%s`", k, v)
+	}
+	if err := checkPublisherHeader(md5sum, resHeaderMap); err != nil {
+		logger.Errorf("Publisher %s on topic %s: %v", pubURI, topic, err)
+		sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherRejected, topic, pubURI, resHeaderMap, err))
+		return
+	}
+	sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherConnected, topic, pubURI, resHeaderMap, nil))
+
+	event := MessageEvent{ // Event struct to be sent with each message.
+		PublisherName:    resHeaderMap["callerid"],
+		ConnectionHeader: resHeaderMap,
+	}
+
+	// Datagrams may be larger than requested if the publisher ignored our limit,
+	// so read into a buffer that fits any UDP payload.
+	buffer := make([]byte, 65536)
+	reassembler := newUDPROSReassembler(connectionID)
+	reassembler.maxMessageSize = maxMessageSize
+	for {
+		select {
+		case <-quitChan:
+			return
+		default:
+			conn.SetReadDeadline(time.Now().Add(1000 * time.Millisecond))
+			n, err := conn.Read(buffer)
+			if err != nil {
+				if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
+					continue
+				}
+				logger.Error("Failed to read a datagram", err)
+				sendConnectionEvent(connectionEventChan, done, newPublisherConnectionEvent(publisherDisconnected, topic, pubURI, resHeaderMap, err))
+				return
+			}
+			msg, complete, err := reassembler.feed(buffer[:n])
+			if err != nil {
+				logger.Debugf("Dropped UDPROS datagram on topic %s: %v", topic, err)
+				continue
+			}
+			if complete {
+				event.ReceiptTime = clock.Now()
+				select {
+				case msgChan <- messageEvent{bytes: msg, event: event}:
+				case <-done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// sendConnectionEvent hands ev to the subscriber goroutine unless done is closed because
+// the subscriber goroutine exited.
+func sendConnectionEvent(connectionEventChan chan publisherConnectionEvent, done <-chan struct{}, ev publisherConnectionEvent) {
+	select {
+	case connectionEventChan <- ev:
+	case <-done:
+	}
+}
+
 func (sub *defaultSubscriber) Shutdown() {
 	sub.shutdownChan <- struct{}{}
 }
@@ -265,3 +788,22 @@ func (sub *defaultSubscriber) Shutdown() {
 func (sub *defaultSubscriber) GetNumPublishers() int {
 	return len(sub.pubList)
 }
+
+// subscriberHandle is the Subscriber returned by each NewSubscriber call. Shutting a handle
+// down only removes its own callback or channel; the subscription ends with its last handle.
+type subscriberHandle struct {
+	node         *defaultNode
+	sub          *defaultSubscriber
+	id           int
+	shutdownOnce sync.Once
+}
+
+func (h *subscriberHandle) GetNumPublishers() int {
+	return h.sub.GetNumPublishers()
+}
+
+func (h *subscriberHandle) Shutdown() {
+	h.shutdownOnce.Do(func() {
+		h.node.unsubscribe(h.sub, h.id)
+	})
+}
@@ -0,0 +1,264 @@
+package ros
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMessageChannelDropPolicy(t *testing.T) {
+	oldest := newMessageChannel(2, DropOldest)
+	newest := newMessageChannel(2, DropNewest)
+	for i := 0; i < 3; i++ {
+		msg := ReceivedMessage{Event: MessageEvent{PublisherName: string(rune('a' + i))}}
+		accepted := i < 2
+		if oldest.push(msg) != accepted || newest.push(msg) != accepted {
+			t.Errorf("Unexpected push result for message %d", i)
+		}
+	}
+	if oldest.dropped != 1 || newest.dropped != 1 {
+		t.Errorf("Expected one dropped message but got %d and %d", oldest.dropped, newest.dropped)
+	}
+	for _, name := range []string{"b", "c"} {
+		if got := (<-oldest.ch).Event.PublisherName; got != name {
+			t.Errorf("DropOldest: expected %s but got %s", name, got)
+		}
+	}
+	for _, name := range []string{"a", "b"} {
+		if got := (<-newest.ch).Event.PublisherName; got != name {
+			t.Errorf("DropNewest: expected %s but got %s", name, got)
+		}
+	}
+}
+
+func TestConnectionAfterSubscriberExit(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	// Nothing receives the events, as after the subscriber goroutine exited.
+	done := make(chan struct{})
+	close(done)
+	exited := make(chan struct{})
+	go func() {
+		startRemotePublisherConn(NewDefaultLogger(), systemClock{}, "http://publisher:11311", addr, "/test_exit", "*",
+			"empty_msg", "/test_subscriber", NewTransportHints(), defaultMaxHeaderSize, 0,
+			make(chan messageEvent), make(chan struct{}), make(chan publisherConnectionEvent), done)
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("Connection goroutine blocked on sending its rejection")
+	}
+}
+
+func TestSubscriberChannelWithoutSpin(t *testing.T) {
+	sub := newDefaultSubscriber("/test_chan", &rawMessageType{}, nil)
+	channel := newMessageChannel(4, DropOldest)
+	sub.channels = append(sub.channels, channel)
+
+	var wg sync.WaitGroup
+	// No job channel: channel subscribers must not depend on Spin.
+	wg.Add(1)
+	go sub.start(&wg, "/test_node", "", "", nil, NewDefaultLogger())
+
+	sub.msgChan <- messageEvent{[]byte("hello"), MessageEvent{PublisherName: "/talker"}}
+	select {
+	case received := <-channel.ch:
+		msg, ok := received.Message.(*rawMessage)
+		if !ok || string(msg.data) != "hello" {
+			t.Errorf("Unexpected message %v", received.Message)
+		}
+		if received.Event.PublisherName != "/talker" {
+			t.Errorf("Unexpected publisher %s", received.Event.PublisherName)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Message was not delivered")
+	}
+
+	sub.Shutdown()
+	select {
+	case _, ok := <-channel.ch:
+		if ok {
+			t.Error("Expected the channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Channel was not closed on shutdown")
+	}
+	wg.Wait()
+}
+
+type otherMessageType struct {
+	rawMessageType
+}
+
+func (t *otherMessageType) MD5Sum() string {
+	return "0123456789abcdef0123456789abcdef"
+}
+
+func TestSubscriberPublisherCallbacks(t *testing.T) {
+	node, err := newDefaultNode("/test_pubcb_node", []string{})
+	if err != nil {
+		t.Fatalf("Error starting new test node: %v", err)
+	}
+	defer node.Shutdown()
+
+	topic := "/test_pubcb"
+	pub := newDefaultPublisher(node, topic, &rawMessageType{})
+	node.publishers[topic] = pub
+	node.waitGroup.Add(1)
+	go pub.start(&node.waitGroup)
+
+	events := make(chan string, 10)
+	record := func(kind string) func(PublisherEvent) {
+		return func(ev PublisherEvent) {
+			if ev.URI != node.xmlrpcURI || ev.Topic != topic {
+				t.Errorf("Unexpected %s event %+v", kind, ev)
+			}
+			if kind != "rejected" && ev.CallerID != node.qualifiedName {
+				t.Errorf("Expected caller id %s but got %s", node.qualifiedName, ev.CallerID)
+			}
+			if kind == "rejected" && ev.Err == nil {
+				t.Error("Expected a reason for the rejection")
+			}
+			events <- kind
+		}
+	}
+	expect := func(kind string) {
+		select {
+		case got := <-events:
+			if got != kind {
+				t.Errorf("Expected %s event but got %s", kind, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("No %s event", kind)
+		}
+	}
+	callbacks := WithPublisherCallbacks(record("connected"), record("disconnected"), record("rejected"))
+
+	var wg sync.WaitGroup
+	sub := newDefaultSubscriber(topic, &rawMessageType{}, nil, callbacks)
+	wg.Add(1)
+	go sub.start(&wg, "/test_pubcb_sub", "", "", node.executor, node.logger)
+	sub.pubListChan <- []string{node.xmlrpcURI}
+	expect("connected")
+	sub.Shutdown()
+	expect("disconnected")
+
+	mismatched := newDefaultSubscriber(topic, &otherMessageType{}, nil, callbacks)
+	wg.Add(1)
+	go mismatched.start(&wg, "/test_pubcb_mismatched", "", "", node.executor, node.logger)
+	mismatched.pubListChan <- []string{node.xmlrpcURI}
+	expect("rejected")
+	mismatched.Shutdown()
+	wg.Wait()
+}
+
+func TestSubscriberHandles(t *testing.T) {
+	node, err := newDefaultNode("/test_handles_node", []string{})
+	if err != nil {
+		t.Fatalf("Error starting new test node: %v", err)
+	}
+	defer node.Shutdown()
+
+	topic := "/test_handles"
+	msgType := &rawMessageType{}
+	sub := newDefaultSubscriber(topic, msgType, nil)
+	node.subscribers[topic] = sub
+	first := &subscriberHandle{node: node, sub: sub, id: 0}
+	node.waitGroup.Add(1)
+	go sub.start(&node.waitGroup, node.qualifiedName, node.xmlrpcURI, "", node.executor, node.logger)
+
+	ch, second := node.NewSubscriberChan(topic, msgType, 1, DropOldest)
+	third := node.NewSubscriber(topic, msgType, func(*rawMessage) {})
+	if second == Subscriber(first) || second == third {
+		t.Fatal("Expected a distinct handle per call")
+	}
+
+	second.Shutdown()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("Expected the channel of the shut down handle to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Channel was not closed")
+	}
+	// Shutting down twice must not release another handle.
+	second.Shutdown()
+	first.Shutdown()
+	if node.subscribers[topic] != sub {
+		t.Fatal("Subscription must survive while a handle is left")
+	}
+
+	third.Shutdown()
+	if _, ok := node.subscribers[topic]; ok {
+		t.Error("Subscription must end with its last handle")
+	}
+}
+
+func TestSubscriberHandleUpdatesDoNotBlock(t *testing.T) {
+	sub := newDefaultSubscriber("/test_handle_updates", &rawMessageType{}, nil)
+	// The subscriber goroutine is not running, as if it were busy.
+	var channels []*messageChannel
+	for id := 1; id <= 20; id++ {
+		channel := newMessageChannel(1, DropOldest)
+		channel.handleID = id
+		channels = append(channels, channel)
+		sub.queueHandleUpdate(subscriberHandleUpdate{handleID: id, channel: channel})
+	}
+	sub.queueHandleUpdate(subscriberHandleUpdate{handleID: 1, remove: true})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go sub.start(&wg, "/test_node", "", "", nil, NewDefaultLogger())
+	if _, ok := <-channels[0].ch; ok {
+		t.Error("Expected the removal to be applied after the addition")
+	}
+	sub.Shutdown()
+	wg.Wait()
+	for _, channel := range channels[1:] {
+		if _, ok := <-channel.ch; ok {
+			t.Fatal("Expected the channels of the added handles to be closed on shutdown")
+		}
+	}
+}
+
+func TestPublisherHandles(t *testing.T) {
+	node, err := newDefaultNode("/test_pub_handles_node", []string{})
+	if err != nil {
+		t.Fatalf("Error starting new test node: %v", err)
+	}
+	defer node.Shutdown()
+
+	topic := "/test_pub_handles"
+	pub := newDefaultPublisher(node, topic, &rawMessageType{})
+	node.publishers[topic] = pub
+	node.waitGroup.Add(1)
+	go pub.start(&node.waitGroup)
+	first := &publisherHandle{node: node, pub: pub, id: pub.addCallbacks(nil, nil)}
+
+	second := node.NewPublisher(topic, &rawMessageType{})
+	first.Shutdown()
+	first.Shutdown()
+	select {
+	case <-pub.doneChan:
+		t.Fatal("Publisher must survive while a handle is left")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	second.Shutdown()
+	select {
+	case <-pub.doneChan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publisher must be shut down with its last handle")
+	}
+	if _, ok := node.publishers[topic]; ok {
+		t.Error("Publisher must be removed from the node")
+	}
+}
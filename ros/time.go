@@ -44,6 +44,12 @@ func (t *Time) Sub(d Duration) Time {
 	return Time{temporal{sec, nsec}}
 }
 
+// UnixNano returns t as a signed number of nanoseconds since the Unix epoch, for
+// arithmetic on times such as their differences.
+func (t *Time) UnixNano() int64 {
+	return int64(t.ToNSec())
+}
+
 // Cmp compares time t and time other.
 // Return integer representing the comparison.
 //  1 : t > other
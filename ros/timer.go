@@ -0,0 +1,19 @@
+package ros
+
+import (
+	"time"
+
+	"github.com/fetchrobotics/rosgo/internal/nodeimpl"
+)
+
+// defaultTimer fires on the executor's clock and runs its callback as a job of the executor.
+type defaultTimer = nodeimpl.Timer[ClockTimer]
+
+func newDefaultTimer(executor Executor, period time.Duration, callback func(TimerEvent)) *defaultTimer {
+	post := func(name string, run func()) {
+		executor.Post(Job{Name: name, Run: run})
+	}
+	return nodeimpl.NewTimer[ClockTimer](executor.Clock(), post, period, func(event nodeimpl.TimerEvent) {
+		callback(TimerEvent(event))
+	})
+}
@@ -0,0 +1,126 @@
+package ros
+
+import (
+	"time"
+)
+
+const (
+	tcpROSProtocol = "TCPROS"
+	udpROSProtocol = "UDPROS"
+)
+
+// TransportHints describes how a subscriber would like to be connected to its publishers.
+// Protocols are requested in the order they were added, and the publisher picks the first
+// one it supports. Hints are built in the same way as roscpp's ros::TransportHints:
+//
+//	hints := ros.NewTransportHints().Unreliable().Reliable().TCPNoDelay(true)
+type TransportHints struct {
+	protocols       []string
+	maxDatagramSize int
+	tcpNoDelay      bool
+	maxMessageSize  int
+	keepAlive       time.Duration
+}
+
+// NewTransportHints creates empty transport hints. Subscribers with no protocol in their
+// hints use TCPROS.
+func NewTransportHints() *TransportHints {
+	return &TransportHints{}
+}
+
+// Reliable adds TCPROS to the ordered list of requested protocols.
+func (h *TransportHints) Reliable() *TransportHints {
+	h.addProtocol(tcpROSProtocol)
+	return h
+}
+
+// Unreliable adds UDPROS to the ordered list of requested protocols.
+func (h *TransportHints) Unreliable() *TransportHints {
+	h.addProtocol(udpROSProtocol)
+	return h
+}
+
+// MaxDatagramSize sets the largest UDPROS datagram, including its 8 byte header,
+// the publisher may send. Zero selects the default size.
+func (h *TransportHints) MaxDatagramSize(size int) *TransportHints {
+	h.maxDatagramSize = size
+	return h
+}
+
+// TCPNoDelay asks both ends of a TCPROS connection to disable Nagle's algorithm,
+// so that small messages are sent immediately instead of being coalesced.
+// The request is sent to the publisher as the `tcp_nodelay` connection header.
+func (h *TransportHints) TCPNoDelay(nodelay bool) *TransportHints {
+	h.tcpNoDelay = nodelay
+	return h
+}
+
+// MaxMessageSize sets the largest message, in bytes, the subscriber accepts from a publisher.
+// A connection that delivers a larger message is closed. Zero sets no limit of its own;
+// the limit of the node set with WithMaxMessageSize applies in any case.
+func (h *TransportHints) MaxMessageSize(size int) *TransportHints {
+	h.maxMessageSize = size
+	return h
+}
+
+// KeepAlive enables TCP keep-alive probes with the given period on TCPROS connections.
+// Zero keeps the operating system default.
+func (h *TransportHints) KeepAlive(period time.Duration) *TransportHints {
+	h.keepAlive = period
+	return h
+}
+
+// Protocols returns the requested protocols in order of preference.
+func (h *TransportHints) Protocols() []string {
+	if h == nil || len(h.protocols) == 0 {
+		return []string{tcpROSProtocol}
+	}
+	protocols := make([]string, len(h.protocols))
+	copy(protocols, h.protocols)
+	return protocols
+}
+
+// GetMaxDatagramSize returns the requested UDPROS datagram size.
+func (h *TransportHints) GetMaxDatagramSize() int {
+	if h == nil || h.maxDatagramSize <= 0 {
+		return defaultMaxDatagramSize
+	}
+	return h.maxDatagramSize
+}
+
+// GetTCPNoDelay reports whether Nagle's algorithm should be disabled.
+func (h *TransportHints) GetTCPNoDelay() bool {
+	return h != nil && h.tcpNoDelay
+}
+
+// GetMaxMessageSize returns the requested message size limit, or zero if none was set.
+func (h *TransportHints) GetMaxMessageSize() int {
+	if h == nil || h.maxMessageSize < 0 {
+		return 0
+	}
+	return h.maxMessageSize
+}
+
+// GetKeepAlive returns the requested keep-alive period, or zero for the system default.
+func (h *TransportHints) GetKeepAlive() time.Duration {
+	if h == nil {
+		return 0
+	}
+	return h.keepAlive
+}
+
+// connectionHeaders returns the hints that are forwarded to the publisher in the
+// TCPROS connection header.
+func (h *TransportHints) connectionHeaders() []header {
+	var headers []header
+	if h.GetTCPNoDelay() {
+		headers = append(headers, header{"tcp_nodelay", "1"})
+	}
+	return headers
+}
+
+func (h *TransportHints) addProtocol(protocol string) {
+	if !contains(h.protocols, protocol) {
+		h.protocols = append(h.protocols, protocol)
+	}
+}
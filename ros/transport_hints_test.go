@@ -0,0 +1,106 @@
+package ros
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTransportHints(t *testing.T) {
+	var hints *TransportHints
+	if protocols := hints.Protocols(); len(protocols) != 1 || protocols[0] != tcpROSProtocol {
+		t.Errorf("Expected TCPROS by default but got %v", protocols)
+	}
+
+	hints = NewTransportHints().Unreliable().Reliable().Unreliable().TCPNoDelay(true).MaxMessageSize(1024)
+	protocols := hints.Protocols()
+	if len(protocols) != 2 || protocols[0] != udpROSProtocol || protocols[1] != tcpROSProtocol {
+		t.Errorf("Expected [UDPROS TCPROS] but got %v", protocols)
+	}
+	if hints.GetMaxDatagramSize() != defaultMaxDatagramSize {
+		t.Errorf("Expected default datagram size but got %d", hints.GetMaxDatagramSize())
+	}
+	if hints.GetMaxMessageSize() != 1024 {
+		t.Errorf("Expected max message size 1024 but got %d", hints.GetMaxMessageSize())
+	}
+	headers := hints.connectionHeaders()
+	if len(headers) != 1 || headers[0] != (header{"tcp_nodelay", "1"}) {
+		t.Errorf("Expected tcp_nodelay header but got %v", headers)
+	}
+	if len(NewTransportHints().connectionHeaders()) != 0 {
+		t.Error("Expected no connection headers without hints")
+	}
+}
+
+func TestRemotePublisherConnHints(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	md5sum := (&dummyMessage{}).MD5Sum()
+	headerChan := make(chan map[string]string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		headers, err := readConnectionHeader(conn, defaultMaxHeaderSize)
+		if err != nil {
+			return
+		}
+		headerChan <- headersToMap(headers)
+		writeConnectionHeader([]header{{"md5sum", md5sum}, {"callerid", "/fake_publisher"}}, conn)
+		// Announce a message larger than the subscriber accepts.
+		binary.Write(conn, binary.LittleEndian, uint32(4096))
+		conn.Write(make([]byte, 4096))
+		time.Sleep(time.Second)
+	}()
+
+	hints := NewTransportHints().TCPNoDelay(true).MaxMessageSize(1024).KeepAlive(5 * time.Second)
+	msgChan := make(chan messageEvent, 1)
+	quitChan := make(chan struct{})
+	defer close(quitChan)
+	connectionEventChan := make(chan publisherConnectionEvent, 2)
+	go startRemotePublisherConn(NewDefaultLogger(), systemClock{}, "http://publisher:11311", listener.Addr().String(), "/test_hints", md5sum,
+		"empty_msg", "/test_subscriber", hints, defaultMaxHeaderSize, hints.GetMaxMessageSize(),
+		msgChan, quitChan, connectionEventChan, nil)
+
+	select {
+	case headers := <-headerChan:
+		if headers["tcp_nodelay"] != "1" {
+			t.Errorf("Expected tcp_nodelay header but got %v", headers)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publisher did not receive a connection header")
+	}
+
+	select {
+	case ev := <-connectionEventChan:
+		if ev.state != publisherConnected {
+			t.Fatalf("Expected a connected event first but got %d", ev.state)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscriber did not report the connection")
+	}
+
+	select {
+	case ev := <-connectionEventChan:
+		if ev.state != publisherDisconnected {
+			t.Errorf("Expected a disconnected event but got %d", ev.state)
+		}
+		if _, ok := ev.event.Err.(*SizeLimitError); !ok {
+			t.Errorf("Expected a SizeLimitError but got %v", ev.event.Err)
+		}
+		if ev.event.CallerID != "/fake_publisher" || ev.event.URI != "http://publisher:11311" {
+			t.Errorf("Unexpected event %+v", ev.event)
+		}
+	case <-msgChan:
+		t.Fatal("Oversized message must not be delivered")
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscriber did not drop the connection")
+	}
+}
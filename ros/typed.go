@@ -0,0 +1,106 @@
+package ros
+
+import (
+	"fmt"
+)
+
+// TypedPublisher publishes messages of a single generated message type.
+// Publishing a message of another type is a compile time error.
+type TypedPublisher[T Message] interface {
+	// Publish publishes ros message.
+	// Publish never blocks longer than the publish timeout of the publisher.
+	Publish(msg T)
+
+	// GetNumSubscribers gets the number of subscribers to the publishing topic
+	GetNumSubscribers() int
+
+	// Shutdown stops the publisher
+	Shutdown()
+}
+
+// TypedServiceClient calls a service server with services of a single generated service type.
+type TypedServiceClient[T TypedService] interface {
+	// Call calls a service server with a service request.
+	Call(srv T) error
+
+	// Shutdown stops the service client.
+	Shutdown()
+}
+
+// Advertise creates a publisher for messages of type T. The message type is taken from T,
+// so T must be a generated message type whose GetType does not dereference its receiver.
+func Advertise[T Message](node Node, topic string, opts ...PublisherOption) TypedPublisher[T] {
+	return &typedPublisher[T]{node.NewPublisher(topic, messageTypeOf[T](), opts...)}
+}
+
+// Subscribe creates a subscriber to a topic and calls callback with every message of type T
+// received on it. Unlike Node.NewSubscriber the callback signature is checked at compile time
+// and the callback is called without reflection.
+func Subscribe[T Message](node Node, topic string, callback func(T, MessageEvent), opts ...SubscriberOption) Subscriber {
+	return node.NewSubscriber(topic, messageTypeOf[T](), typedMessageCallback(node.Logger(), topic, callback), opts...)
+}
+
+// NewTypedServiceClient creates a service client for services of type T. The service
+// type is taken from T, so T must be a generated service type.
+func NewTypedServiceClient[T TypedService](node Node, service string) TypedServiceClient[T] {
+	return &typedServiceClient[T]{node.NewServiceClient(service, serviceTypeOf[T]())}
+}
+
+// NewTypedServiceServer creates a service server that calls handler for each request.
+// The service type is taken from T, so T must be a generated service type. Unlike
+// Node.NewServiceServer the handler signature is checked at compile time and the handler
+// is called without reflection.
+func NewTypedServiceServer[T TypedService](node Node, service string, handler func(T) error) ServiceServer {
+	return node.NewServiceServer(service, serviceTypeOf[T](), typedServiceHandler(handler))
+}
+
+func messageTypeOf[T Message]() MessageType {
+	var msg T
+	return msg.GetType()
+}
+
+func serviceTypeOf[T TypedService]() ServiceType {
+	var srv T
+	return srv.GetServiceType()
+}
+
+// typedMessageCallback adapts callback to the signature dispatched without reflection.
+// Messages of another type than T are reported to logger and dropped.
+func typedMessageCallback[T Message](logger Logger, topic string, callback func(T, MessageEvent)) func(Message, MessageEvent) {
+	return func(msg Message, event MessageEvent) {
+		typed, ok := msg.(T)
+		if !ok {
+			var expected T
+			logger.Errorf("Subscribe(%s): callback expects %T but received %T", topic, expected, msg)
+			return
+		}
+		callback(typed, event)
+	}
+}
+
+// typedServiceHandler adapts handler to the signature dispatched without reflection.
+func typedServiceHandler[T Service](handler func(T) error) func(Service) error {
+	return func(srv Service) error {
+		typed, ok := srv.(T)
+		if !ok {
+			return fmt.Errorf("unexpected service type %T", srv)
+		}
+		return handler(typed)
+	}
+}
+
+type typedPublisher[T Message] struct {
+	Publisher
+}
+
+func (p *typedPublisher[T]) Publish(msg T) {
+	p.Publisher.Publish(msg)
+}
+
+type typedServiceClient[T TypedService] struct {
+	ServiceClient
+}
+
+func (c *typedServiceClient[T]) Call(srv T) error {
+	return c.ServiceClient.Call(srv)
+}
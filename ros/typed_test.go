@@ -0,0 +1,150 @@
+package ros
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fetchrobotics/rosgo/internal/nodeimpl"
+)
+
+type rawMessageType struct {
+	dummyMessage
+}
+
+func (t *rawMessageType) NewMessage() Message {
+	return &rawMessage{}
+}
+
+type rawService struct {
+	request  rawMessage
+	response rawMessage
+}
+
+func (s *rawService) ReqMessage() Message         { return &s.request }
+func (s *rawService) ResMessage() Message         { return &s.response }
+func (s *rawService) GetServiceType() ServiceType { return &rawServiceType{} }
+
+// errorLogger records the errors logged.
+type errorLogger struct {
+	DefaultLogger
+	errors []string
+}
+
+func (l *errorLogger) Errorf(format string, v ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprintf(format, v...))
+}
+
+type rawServiceType struct{}
+
+func (t *rawServiceType) MD5Sum() string            { return "d41d8cd98f00b204e9800998ecf8427e" }
+func (t *rawServiceType) Name() string              { return "raw_srv" }
+func (t *rawServiceType) RequestType() MessageType  { return &rawMessageType{} }
+func (t *rawServiceType) ResponseType() MessageType { return &rawMessageType{} }
+func (t *rawServiceType) NewService() Service       { return &rawService{} }
+
+// otherMessage is a message of a type other than rawMessage.
+type otherMessage struct {
+	rawMessage
+}
+
+func TestMessageTypeOf(t *testing.T) {
+	if name := messageTypeOf[*rawMessage]().Name(); name != "empty_msg" {
+		t.Errorf("Expected empty_msg but got %s", name)
+	}
+}
+
+func TestTypedMessageCallback(t *testing.T) {
+	var received *rawMessage
+	var publisher string
+	logger := &errorLogger{}
+	callback := typedMessageCallback(logger, "/chatter", func(msg *rawMessage, event MessageEvent) {
+		received = msg
+		publisher = event.PublisherName
+	})
+
+	msg := &rawMessage{data: []byte("hello")}
+	nodeimpl.CallMessageCallback(callback, msg, MessageEvent{PublisherName: "/talker"})
+	if received != msg || publisher != "/talker" {
+		t.Errorf("Callback was not called with the message: %v %s", received, publisher)
+	}
+
+	received = nil
+	nodeimpl.CallMessageCallback(callback, &otherMessage{}, MessageEvent{})
+	if received != nil || len(logger.errors) != 1 {
+		t.Errorf("Expected a message of another type to be logged and dropped but got %v %v", received, logger.errors)
+	}
+}
+
+func TestCheckMessageCallback(t *testing.T) {
+	msgType := &rawMessageType{}
+	valid := []interface{}{
+		func() {},
+		func(*rawMessage) {},
+		func(Message) {},
+		func(*rawMessage, MessageEvent) {},
+		typedMessageCallback(NewDefaultLogger(), "/chatter", func(*rawMessage, MessageEvent) {}),
+	}
+	for _, callback := range valid {
+		if err := nodeimpl.CheckMessageCallback(callback, msgType.NewMessage(), MessageEvent{}); err != nil {
+			t.Errorf("Expected %T to be valid: %v", callback, err)
+		}
+	}
+
+	invalid := []interface{}{
+		nil,
+		"not a function",
+		func(*rawMessage, MessageEvent, int) {},
+		func(*rawService) {},
+		func(*rawMessage, string) {},
+		func(...Message) {},
+	}
+	for _, callback := range invalid {
+		if err := nodeimpl.CheckMessageCallback(callback, msgType.NewMessage(), MessageEvent{}); err == nil {
+			t.Errorf("Expected %T to be rejected", callback)
+		}
+	}
+}
+
+func TestCallMessageCallbackReflection(t *testing.T) {
+	var received *rawMessage
+	nodeimpl.CallMessageCallback(func(msg *rawMessage) { received = msg }, &rawMessage{}, MessageEvent{})
+	if received == nil {
+		t.Error("Reflective callback was not called")
+	}
+}
+
+func TestTypedServiceHandler(t *testing.T) {
+	handlerErr := errors.New("failed")
+	handler := typedServiceHandler(func(srv *rawService) error {
+		srv.response.data = srv.request.data
+		if string(srv.request.data) == "fail" {
+			return handlerErr
+		}
+		return nil
+	})
+
+	srv := &rawService{request: rawMessage{data: []byte("ping")}}
+	if err := nodeimpl.CallServiceHandler(handler, srv); err != nil {
+		t.Fatal(err)
+	}
+	if string(srv.response.data) != "ping" {
+		t.Errorf("Expected response ping but got %q", srv.response.data)
+	}
+
+	srv.request.data = []byte("fail")
+	if err := nodeimpl.CallServiceHandler(handler, srv); err != handlerErr {
+		t.Errorf("Expected handler error but got %v", err)
+	}
+
+	reflective := func(srv *rawService) error { return handlerErr }
+	if err := nodeimpl.CallServiceHandler(reflective, srv); err != handlerErr {
+		t.Errorf("Expected handler error from reflective handler but got %v", err)
+	}
+}
+
+func TestServiceTypeOf(t *testing.T) {
+	if name := serviceTypeOf[*rawService]().Name(); name != "raw_srv" {
+		t.Errorf("Expected raw_srv but got %s", name)
+	}
+}
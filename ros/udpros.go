@@ -0,0 +1,164 @@
+package ros
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// UDPROS datagram op codes.
+const (
+	udpROSData0 uint8 = 0
+	udpROSDataN uint8 = 1
+	udpROSPing  uint8 = 2
+	udpROSErr   uint8 = 3
+)
+
+const (
+	udpROSHeaderSize       = 8
+	defaultMaxDatagramSize = 1500
+	maxUDPROSBlocks        = int(^uint16(0))
+)
+
+// udpROSDatagramHeader prefixes every UDPROS datagram.
+// For DATA0 datagrams block holds the total number of blocks of the message,
+// for DATAN datagrams it holds the index of the block.
+type udpROSDatagramHeader struct {
+	connectionID uint32
+	opCode       uint8
+	messageID    uint8
+	block        uint16
+}
+
+func (h *udpROSDatagramHeader) encode(buf []byte) {
+	binary.LittleEndian.PutUint32(buf[0:4], h.connectionID)
+	buf[4] = h.opCode
+	buf[5] = h.messageID
+	binary.LittleEndian.PutUint16(buf[6:8], h.block)
+}
+
+func decodeUDPROSDatagramHeader(datagram []byte) (udpROSDatagramHeader, error) {
+	var h udpROSDatagramHeader
+	if len(datagram) < udpROSHeaderSize {
+		return h, fmt.Errorf("UDPROS datagram too short: %d bytes", len(datagram))
+	}
+	h.connectionID = binary.LittleEndian.Uint32(datagram[0:4])
+	h.opCode = datagram[4]
+	h.messageID = datagram[5]
+	h.block = binary.LittleEndian.Uint16(datagram[6:8])
+	return h, nil
+}
+
+// fragmentUDPROSMessage splits a serialized message into datagrams of at most
+// maxDatagramSize bytes. Like roscpp, the fragmented payload carries the same
+// 4 byte length prefix as a TCPROS frame.
+func fragmentUDPROSMessage(connectionID uint32, messageID uint8, msg []byte, maxDatagramSize int) ([][]byte, error) {
+	blockSize := maxDatagramSize - udpROSHeaderSize
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("max datagram size %d is too small", maxDatagramSize)
+	}
+	payload := make([]byte, 4+len(msg))
+	binary.LittleEndian.PutUint32(payload, uint32(len(msg)))
+	copy(payload[4:], msg)
+
+	numBlocks := (len(payload) + blockSize - 1) / blockSize
+	if numBlocks > maxUDPROSBlocks {
+		return nil, fmt.Errorf("message of %d bytes needs too many UDPROS blocks", len(msg))
+	}
+
+	datagrams := make([][]byte, 0, numBlocks)
+	for i := 0; i < numBlocks; i++ {
+		h := udpROSDatagramHeader{connectionID: connectionID, messageID: messageID}
+		if i == 0 {
+			h.opCode = udpROSData0
+			h.block = uint16(numBlocks)
+		} else {
+			h.opCode = udpROSDataN
+			h.block = uint16(i)
+		}
+		end := (i + 1) * blockSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		block := payload[i*blockSize : end]
+		datagram := make([]byte, udpROSHeaderSize+len(block))
+		h.encode(datagram)
+		copy(datagram[udpROSHeaderSize:], block)
+		datagrams = append(datagrams, datagram)
+	}
+	return datagrams, nil
+}
+
+// udpROSReassembler rebuilds messages from the datagrams of one UDPROS connection.
+// A message with a lost or reordered block is dropped as a whole, as is a message
+// larger than maxMessageSize when a limit is set.
+type udpROSReassembler struct {
+	connectionID   uint32
+	maxMessageSize int
+	active         bool
+	messageID      uint8
+	numBlocks      uint16
+	lastBlock      uint16
+	payload        []byte
+}
+
+func newUDPROSReassembler(connectionID uint32) *udpROSReassembler {
+	return &udpROSReassembler{connectionID: connectionID}
+}
+
+// feed consumes one datagram and returns the message once all of its blocks arrived.
+func (r *udpROSReassembler) feed(datagram []byte) ([]byte, bool, error) {
+	h, err := decodeUDPROSDatagramHeader(datagram)
+	if err != nil {
+		return nil, false, err
+	}
+	if h.connectionID != r.connectionID {
+		return nil, false, fmt.Errorf("unexpected UDPROS connection id %d", h.connectionID)
+	}
+	block := datagram[udpROSHeaderSize:]
+
+	switch h.opCode {
+	case udpROSData0:
+		if h.block == 0 {
+			r.active = false
+			return nil, false, fmt.Errorf("UDPROS message without blocks")
+		}
+		r.active = true
+		r.messageID = h.messageID
+		r.numBlocks = h.block
+		r.lastBlock = 0
+		r.payload = append(r.payload[:0], block...)
+	case udpROSDataN:
+		if !r.active || h.messageID != r.messageID || h.block != r.lastBlock+1 {
+			r.active = false
+			return nil, false, nil
+		}
+		r.lastBlock = h.block
+		r.payload = append(r.payload, block...)
+	case udpROSPing:
+		return nil, false, nil
+	case udpROSErr:
+		r.active = false
+		return nil, false, fmt.Errorf("UDPROS error datagram received")
+	default:
+		return nil, false, fmt.Errorf("unknown UDPROS op code %d", h.opCode)
+	}
+
+	if r.maxMessageSize > 0 && len(r.payload) > r.maxMessageSize+4 {
+		r.active = false
+		return nil, false, &SizeLimitError{Kind: "message", Size: uint32(len(r.payload) - 4), Limit: r.maxMessageSize}
+	}
+	if r.lastBlock+1 != r.numBlocks {
+		return nil, false, nil
+	}
+	r.active = false
+	if len(r.payload) < 4 {
+		return nil, false, fmt.Errorf("UDPROS message too short")
+	}
+	size := binary.LittleEndian.Uint32(r.payload[0:4])
+	if uint64(size) != uint64(len(r.payload)-4) {
+		return nil, false, fmt.Errorf("UDPROS message size mismatch: %d vs %d", size, len(r.payload)-4)
+	}
+	msg := make([]byte, size)
+	copy(msg, r.payload[4:])
+	return msg, true, nil
+}
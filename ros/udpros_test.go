@@ -0,0 +1,217 @@
+package ros
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFragmentUDPROSMessage(t *testing.T) {
+	msg := make([]byte, 2500)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+
+	datagrams, err := fragmentUDPROSMessage(7, 3, msg, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 4 byte length prefix + 2500 bytes in blocks of 992 bytes.
+	if len(datagrams) != 3 {
+		t.Fatalf("Expected 3 datagrams but got %d", len(datagrams))
+	}
+	for i, datagram := range datagrams {
+		if len(datagram) > 1000 {
+			t.Errorf("Datagram %d is %d bytes", i, len(datagram))
+		}
+		h, err := decodeUDPROSDatagramHeader(datagram)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.connectionID != 7 || h.messageID != 3 {
+			t.Errorf("Unexpected datagram header %+v", h)
+		}
+		if i == 0 && (h.opCode != udpROSData0 || h.block != 3) {
+			t.Errorf("Unexpected first datagram header %+v", h)
+		}
+		if i > 0 && (h.opCode != udpROSDataN || h.block != uint16(i)) {
+			t.Errorf("Unexpected datagram header %+v", h)
+		}
+	}
+
+	r := newUDPROSReassembler(7)
+	for i, datagram := range datagrams {
+		got, complete, err := r.feed(datagram)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if complete != (i == len(datagrams)-1) {
+			t.Fatalf("Unexpected completion at datagram %d", i)
+		}
+		if complete && !bytes.Equal(got, msg) {
+			t.Error("Reassembled message does not match")
+		}
+	}
+
+	if _, err := fragmentUDPROSMessage(7, 0, msg, udpROSHeaderSize); err == nil {
+		t.Error("Expected error for a datagram size without room for data")
+	}
+}
+
+func TestUDPROSReassemblerLostBlock(t *testing.T) {
+	first, _ := fragmentUDPROSMessage(1, 0, make([]byte, 100), 50)
+	second, _ := fragmentUDPROSMessage(1, 1, []byte("hello"), 50)
+
+	r := newUDPROSReassembler(1)
+	// Drop the second block of the first message.
+	for i, datagram := range first {
+		if i == 1 {
+			continue
+		}
+		if _, complete, _ := r.feed(datagram); complete {
+			t.Fatal("Message with a lost block must not complete")
+		}
+	}
+	got, complete, err := r.feed(second[0])
+	if err != nil || !complete || string(got) != "hello" {
+		t.Errorf("Expected the next message to be delivered: %q %v %v", got, complete, err)
+	}
+
+	if _, _, err := r.feed(second[0][:4]); err == nil {
+		t.Error("Expected error for a truncated datagram")
+	}
+	other, _ := fragmentUDPROSMessage(2, 0, []byte("x"), 50)
+	if _, _, err := r.feed(other[0]); err == nil {
+		t.Error("Expected error for a foreign connection id")
+	}
+}
+
+func FuzzUDPROSReassembler(f *testing.F) {
+	datagrams, _ := fragmentUDPROSMessage(1, 0, make([]byte, 100), 50)
+	f.Add(datagrams[0], datagrams[1])
+	f.Add([]byte{0x01, 0, 0, 0, udpROSData0, 0, 0xff, 0xff}, []byte{})
+	f.Fuzz(func(t *testing.T, first []byte, second []byte) {
+		r := newUDPROSReassembler(1)
+		r.maxMessageSize = 1024
+		for _, datagram := range [][]byte{first, second} {
+			msg, complete, err := r.feed(datagram)
+			if err == nil && complete && len(msg) > r.maxMessageSize {
+				t.Fatalf("Message of %d bytes exceeds the limit", len(msg))
+			}
+		}
+	})
+}
+
+func TestUDPROSPublisher(t *testing.T) {
+	node, err := newDefaultNode("/test_udpros_node", []string{})
+	if err != nil {
+		t.Fatalf("Error starting new test node: %v", err)
+	}
+	defer node.Shutdown()
+
+	topic := "/test_udpros"
+	msgType := &dummyMessage{}
+	pub := newDefaultPublisher(node, topic, msgType)
+	node.publishers[topic] = pub
+	node.waitGroup.Add(1)
+	go pub.start(&node.waitGroup)
+
+	sub := newDefaultSubscriber(topic, msgType, nil,
+		WithTransportHints(NewTransportHints().Unreliable().Reliable().MaxDatagramSize(512)))
+	sub.hostname = "127.0.0.1"
+	sub.listenIP = "127.0.0.1"
+	protocols, udpConn, err := sub.requestedProtocols("/udpros_subscriber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(protocols) != 2 || udpConn == nil {
+		t.Fatalf("Expected UDPROS and TCPROS to be requested but got %v", protocols)
+	}
+
+	// Negotiate through the node's XML-RPC server as a remote subscriber would.
+	nodeURI := "http://" + node.xmlrpcListener.Addr().String()
+	result, err := callRosAPI(nodeURI, "requestTopic", "/udpros_subscriber", topic, protocols)
+	if err != nil {
+		t.Fatalf("requestTopic failed: %v", err)
+	}
+	params := result.([]interface{})
+	if params[0] != udpROSProtocol {
+		t.Fatalf("Expected UDPROS to be selected but got %v", params[0])
+	}
+	connectionID, maxDatagramSize, resHeaderMap, err := parseUDPROSProtocolParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxDatagramSize != 512 {
+		t.Errorf("Expected max datagram size 512 but got %d", maxDatagramSize)
+	}
+	if resHeaderMap["topic"] != topic || resHeaderMap["md5sum"] != msgType.MD5Sum() {
+		t.Errorf("Unexpected response header %v", resHeaderMap)
+	}
+
+	msgChan := make(chan messageEvent, 10)
+	quitChan := make(chan struct{})
+	defer close(quitChan)
+	go startRemotePublisherUDPConn(node.logger, NewManualClock(time.Unix(100, 0)), udpConn, "pub", topic, msgType.MD5Sum(),
+		connectionID, maxDatagramSize, 0, resHeaderMap, msgChan, quitChan, make(chan publisherConnectionEvent, 2), nil)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for pub.GetNumSubscribers() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("UDPROS subscriber was not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	payload := make([]byte, 3000)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+	pub.Publish(&rawMessage{data: payload})
+
+	select {
+	case ev := <-msgChan:
+		if !bytes.Equal(ev.bytes, payload) {
+			t.Error("Received message does not match the published one")
+		}
+		if ev.event.ConnectionHeader["callerid"] != node.qualifiedName {
+			t.Errorf("Unexpected publisher name %s", ev.event.ConnectionHeader["callerid"])
+		}
+		if !ev.event.ReceiptTime.Equal(time.Unix(100, 0)) {
+			t.Errorf("Expected the receipt time from the clock but got %v", ev.event.ReceiptTime)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("UDPROS message not received")
+	}
+}
+
+func TestUDPROSRequestTopicRejectsMismatch(t *testing.T) {
+	node, err := newDefaultNode("/test_udpros_reject_node", []string{})
+	if err != nil {
+		t.Fatalf("Error starting new test node: %v", err)
+	}
+	defer node.Shutdown()
+
+	topic := "/test_udpros_reject"
+	pub := newDefaultPublisher(node, topic, &dummyMessage{})
+	node.publishers[topic] = pub
+	node.waitGroup.Add(1)
+	go pub.start(&node.waitGroup)
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	headers := encodeHeaderFields([]header{
+		{"topic", topic}, {"md5sum", "0123"}, {"callerid", "/other"}, {"type", "other/Type"},
+	})
+	protocols := []interface{}{
+		[]interface{}{udpROSProtocol, headers, "127.0.0.1", int32(conn.LocalAddr().(*net.UDPAddr).Port), int32(1500)},
+	}
+	nodeURI := "http://" + node.xmlrpcListener.Addr().String()
+	if _, err := callRosAPI(nodeURI, "requestTopic", "/other", topic, protocols); err == nil {
+		t.Error("Expected requestTopic to fail for a mismatched connection header")
+	}
+}
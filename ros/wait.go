@@ -0,0 +1,101 @@
+package ros
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+const waitForServicePollInterval = 100 * time.Millisecond
+
+// WaitForMessage subscribes to topic, waits for one message and unsubscribes again.
+// It returns an error if ctx is done before a message arrives; use context.WithTimeout
+// to bound the wait. The node does not need to spin.
+func WaitForMessage(ctx context.Context, node Node, topic string, msgType MessageType, opts ...SubscriberOption) (Message, MessageEvent, error) {
+	msgChan, sub := node.NewSubscriberChan(topic, msgType, 1, DropNewest, opts...)
+	defer sub.Shutdown()
+	select {
+	case received, ok := <-msgChan:
+		if !ok {
+			return nil, MessageEvent{}, fmt.Errorf("subscriber to %s was shut down", topic)
+		}
+		return received.Message, received.Event, nil
+	case <-ctx.Done():
+		return nil, MessageEvent{}, fmt.Errorf("waiting for a message on %s: %w", topic, ctx.Err())
+	}
+}
+
+// WaitForService blocks until service is registered with the master and its server accepts
+// connections, or until ctx is done.
+func (node *defaultNode) WaitForService(ctx context.Context, service string) error {
+	name, err := node.resolver.resolveName(service, true)
+	if err != nil {
+		return err
+	}
+	return waitForService(ctx, node.master, name, waitForServicePollInterval)
+}
+
+// waitForService polls the master for the server of service and probes every server it
+// is told about until one answers.
+func waitForService(ctx context.Context, master *MasterClient, service string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		uri, err := master.LookupService(ctx, service)
+		if err == nil {
+			err = probeService(ctx, uri, service, master.CallerID())
+		}
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for service %s: %w (last error: %v)", service, ctx.Err(), lastErr)
+		}
+	}
+}
+
+// probeService connects to the server at the ROSRPC URI serviceURI and exchanges connection
+// headers with the probe flag set, which makes the server close the connection without
+// waiting for a request.
+func probeService(ctx context.Context, serviceURI string, service string, callerID string) error {
+	serviceURL, err := url.Parse(serviceURI)
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", serviceURL.Host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	headers := []header{
+		{"service", service},
+		{"md5sum", "*"},
+		{"callerid", callerID},
+		{"probe", "1"},
+	}
+	if err := writeConnectionHeader(headers, conn); err != nil {
+		return err
+	}
+	resHeaders, err := readConnectionHeader(conn, defaultMaxHeaderSize)
+	if err != nil {
+		return err
+	}
+	if resHeaderMap := headersToMap(resHeaders); resHeaderMap["error"] != "" {
+		return fmt.Errorf("service %s rejected the probe: %s", service, resHeaderMap["error"])
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package ros
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// probeServer accepts connections like a service server and reports the headers it receives.
+func probeServer(t *testing.T) (string, <-chan map[string]string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan map[string]string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			headers, err := readConnectionHeader(conn, defaultMaxHeaderSize)
+			if err == nil {
+				received <- headersToMap(headers)
+				writeConnectionHeader([]header{{"service", "/add"}, {"callerid", "/server"}}, conn)
+			}
+			conn.Close()
+		}
+	}()
+	return "rosrpc://" + listener.Addr().String(), received
+}
+
+func TestWaitForService(t *testing.T) {
+	_, server := newStandInMaster(t)
+	client := NewMasterClient(server.URL, "/waiter")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := waitForService(ctx, client, "/add", 5*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to time out but got %v", err)
+	}
+
+	uri, received := probeServer(t)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		client.RegisterService(context.Background(), "/add", uri, "http://server:1")
+	}()
+	if err := waitForService(context.Background(), client, "/add", 5*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	headers := <-received
+	if headers["probe"] != "1" || headers["service"] != "/add" || headers["callerid"] != "/waiter" {
+		t.Errorf("Unexpected probe header %v", headers)
+	}
+}
+
+func TestWaitForServiceUnreachableServer(t *testing.T) {
+	_, server := newStandInMaster(t)
+	client := NewMasterClient(server.URL, "/waiter")
+	// Registered, but nothing listens on the port.
+	client.RegisterService(context.Background(), "/add", "rosrpc://127.0.0.1:1", "http://server:1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := waitForService(ctx, client, "/add", 5*time.Millisecond); err == nil {
+		t.Error("Expected an unreachable server not to count as available")
+	}
+}
@@ -0,0 +1,637 @@
+// Package rostest provides an in-memory implementation of ros.Node for unit tests.
+//
+// Topics, services and parameters live in the Node itself, so nothing talks to a master
+// or opens a socket. Messages published on a topic are delivered to the subscribers of
+// the same Node, and tests can inject messages, capture what was published and stub
+// service responses. Callbacks run on a ros.ManualExecutor, so tests decide when they run.
+package rostest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/internal/nodeimpl"
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// InjectedPublisherName is the publisher name of the MessageEvent of injected messages.
+const InjectedPublisherName = "/rostest"
+
+// Node is an in-memory ros.Node. Publisher, subscriber and transport options are accepted
+// but have no effect, except for the header options of publishers such as
+// ros.WithHeaderSequence, which fill in the headers of published messages, and the
+// deadline and liveliness options of subscribers, which follow the executor's clock and
+// count the publishers of the node.
+type Node struct {
+	qualifiedName string
+	namespace     string
+	parent        *Node
+	view          *view
+	*state
+
+	// remappings maps resolved names and searches maps the unresolved names of the
+	// remappings of this node or view, guarded by remapMutex of the state.
+	remappings ros.NameMap
+	searches   ros.NameMap
+}
+
+// state is shared by a node and its namespace views.
+type state struct {
+	executor   *ros.ManualExecutor
+	nonRosArgs []string
+
+	mutex      sync.Mutex
+	remapMutex sync.RWMutex
+	ok         bool
+	logger     ros.Logger
+	topics     map[string]*topic
+	services   map[string]*serviceServer
+	params     map[string]interface{}
+	timers     []ros.Timer
+}
+
+// view holds what a namespace view releases when it is shut down.
+type view struct {
+	mutex    sync.Mutex
+	shutdown bool
+	releases map[interface{}]func()
+}
+
+type topic struct {
+	publishers  map[*publisher]struct{}
+	subscribers map[*subscriber]struct{}
+	published   []ros.Message
+}
+
+// NewNode creates an in-memory node. Its executor's clock starts at the Unix epoch.
+func NewNode(name string, args ...string) *Node {
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return &Node{
+		qualifiedName: name,
+		namespace:     path.Dir(name),
+		remappings:    make(ros.NameMap),
+		searches:      make(ros.NameMap),
+		state: &state{
+			executor:   ros.NewManualExecutor(time.Unix(0, 0)),
+			nonRosArgs: args,
+			ok:         true,
+			logger:     ros.NewDefaultLogger(),
+			topics:     make(map[string]*topic),
+			services:   make(map[string]*serviceServer),
+			params:     make(map[string]interface{}),
+		},
+	}
+}
+
+// Namespace returns a view of the node whose relative names resolve in ns. The view
+// shares the topics, services, parameters and executor of the node, so messages
+// injected or published through either are seen by both. Shutting the view down
+// releases only what was created through it.
+func (n *Node) Namespace(ns string, remappings ros.NameMap) ros.Node {
+	if err := ros.ValidateName(ns); err != nil {
+		n.Logger().Errorf("Namespace %s: %v", ns, err)
+	}
+	child := &Node{
+		qualifiedName: n.qualifiedName,
+		namespace:     n.qualify(ns),
+		parent:        n,
+		view:          &view{releases: make(map[interface{}]func())},
+		state:         n.state,
+		remappings:    make(ros.NameMap),
+		searches:      make(ros.NameMap),
+	}
+	for from, to := range remappings {
+		if err := child.AddRemapping(from, to); err != nil {
+			n.Logger().Errorf("Namespace %s: remapping %s:=%s: %v", ns, from, to, err)
+		}
+	}
+	return child
+}
+
+// ResolveName returns the global name that name stands for in the namespace of the node.
+func (n *Node) ResolveName(name string, remap bool) (string, error) {
+	if err := ros.ValidateName(name); err != nil {
+		return "", err
+	}
+	if remap {
+		return n.resolve(name), nil
+	}
+	return n.qualify(name), nil
+}
+
+// AddRemapping remaps the name from to the name to for the node or view and the views
+// created from it.
+func (n *Node) AddRemapping(from string, to string) error {
+	if err := ros.ValidateName(from); err != nil {
+		return err
+	}
+	if err := ros.ValidateName(to); err != nil {
+		return err
+	}
+	n.remapMutex.Lock()
+	defer n.remapMutex.Unlock()
+	n.remappings[n.qualify(from)] = n.qualify(to)
+	n.searches[from] = to
+	return nil
+}
+
+// track remembers release to be called for handle when the view is shut down. The node
+// itself releases everything on shutdown and does not track.
+func (n *Node) track(handle interface{}, release func()) {
+	if n.view == nil {
+		return
+	}
+	n.view.mutex.Lock()
+	defer n.view.mutex.Unlock()
+	n.view.releases[handle] = release
+}
+
+// untrack forgets a handle that was shut down by itself.
+func (n *Node) untrack(handle interface{}) {
+	if n.view == nil {
+		return
+	}
+	n.view.mutex.Lock()
+	defer n.view.mutex.Unlock()
+	delete(n.view.releases, handle)
+}
+
+// Executor returns the executor running the node's callbacks. Use it to run pending
+// callbacks and to advance the clock of timers.
+func (n *Node) Executor() *ros.ManualExecutor {
+	return n.executor
+}
+
+// resolve turns a relative or private name into a global one and applies the remappings.
+func (n *Node) resolve(name string) string {
+	resolved := n.qualify(name)
+	n.remapMutex.RLock()
+	defer n.remapMutex.RUnlock()
+	for v := n; v != nil; v = v.parent {
+		if to, ok := v.remappings[resolved]; ok {
+			return to
+		}
+	}
+	return resolved
+}
+
+// searchKey returns the name that key is remapped to without resolving it, like the
+// master client of a node does before searching.
+func (n *Node) searchKey(key string) string {
+	n.remapMutex.RLock()
+	defer n.remapMutex.RUnlock()
+	for v := n; v != nil; v = v.parent {
+		if to, ok := v.searches[key]; ok {
+			return to
+		}
+	}
+	return key
+}
+
+// resolveParam resolves the name of a parameter, which must be a valid ROS name.
+func (n *Node) resolveParam(name string) (string, error) {
+	if err := ros.ValidateName(name); err != nil {
+		return "", err
+	}
+	return n.resolve(name), nil
+}
+
+// qualify turns a relative or private name into a global one.
+func (n *Node) qualify(name string) string {
+	switch {
+	case strings.HasPrefix(name, "/"):
+		return path.Clean(name)
+	case strings.HasPrefix(name, "~"):
+		return path.Join(n.qualifiedName, name[1:])
+	default:
+		return path.Join(n.namespace, name)
+	}
+}
+
+// topic returns the topic called name. The caller must hold the mutex.
+func (n *Node) topic(name string) *topic {
+	t, ok := n.topics[name]
+	if !ok {
+		t = &topic{
+			publishers:  make(map[*publisher]struct{}),
+			subscribers: make(map[*subscriber]struct{}),
+		}
+		n.topics[name] = t
+	}
+	return t
+}
+
+// Inject delivers msg to the subscribers of topic as if a remote node had published it.
+func (n *Node) Inject(topic string, msg ros.Message) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	event := ros.MessageEvent{
+		PublisherName:    InjectedPublisherName,
+		ReceiptTime:      n.executor.Clock().Now(),
+		ConnectionHeader: map[string]string{"callerid": InjectedPublisherName},
+	}
+	return n.deliver(n.resolve(topic), msg, event, nil)
+}
+
+// Published returns copies of the messages published on topic by the node's publishers,
+// in the order they were published.
+func (n *Node) Published(topic string) []ros.Message {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	t, ok := n.topics[n.resolve(topic)]
+	if !ok {
+		return nil
+	}
+	published := make([]ros.Message, len(t.published))
+	copy(published, t.published)
+	return published
+}
+
+// deliver hands a copy of msg to each subscriber of topic, or only to target if it is set.
+// The caller must hold the mutex.
+func (n *Node) deliver(name string, msg ros.Message, event ros.MessageEvent, target *subscriber) error {
+	t := n.topic(name)
+	for sub := range t.subscribers {
+		if target != nil && sub != target {
+			continue
+		}
+		if sub.msgType.MD5Sum() != msg.GetType().MD5Sum() && sub.msgType.MD5Sum() != "*" {
+			return fmt.Errorf("message type %s does not match subscriber type %s on %s",
+				msg.GetType().Name(), sub.msgType.Name(), name)
+		}
+		m, err := copyMessage(msg, sub.msgType)
+		if err != nil {
+			return err
+		}
+		sub.deliver(m, event)
+	}
+	return nil
+}
+
+// copyMessage round-trips msg through its serialization, as a remote subscriber would see it.
+func copyMessage(msg ros.Message, msgType ros.MessageType) (ros.Message, error) {
+	m := msgType.NewMessage()
+	if err := copyInto(m, msg); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// copyInto deserializes the serialization of src into dst.
+func copyInto(dst ros.Message, src ros.Message) error {
+	var buf bytes.Buffer
+	if err := src.Serialize(&buf); err != nil {
+		return err
+	}
+	return dst.Deserialize(bytes.NewReader(buf.Bytes()))
+}
+
+func (n *Node) NewPublisher(topic string, msgType ros.MessageType, opts ...ros.PublisherOption) ros.Publisher {
+	return n.NewPublisherWithCallbacks(topic, msgType, nil, nil, opts...)
+}
+
+func (n *Node) NewPublisherWithCallbacks(topic string, msgType ros.MessageType, connectCallback, disconnectCallback func(ros.SingleSubscriberPublisher), opts ...ros.PublisherOption) ros.Publisher {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	pub := &publisher{
+		node:               n,
+		topic:              n.resolve(topic),
+		msgType:            msgType,
+		connectCallback:    connectCallback,
+		disconnectCallback: disconnectCallback,
+		headers:            ros.NewHeaderFiller(opts...),
+	}
+	t := n.topic(pub.topic)
+	t.publishers[pub] = struct{}{}
+	for sub := range t.subscribers {
+		pub.notify(pub.connectCallback, sub)
+		sub.monitor.PublishersChanged(len(t.publishers))
+	}
+	n.track(pub, pub.Shutdown)
+	return pub
+}
+
+func (n *Node) NewSubscriber(topic string, msgType ros.MessageType, callback interface{}, opts ...ros.SubscriberOption) ros.Subscriber {
+	if err := nodeimpl.CheckMessageCallback(callback, msgType.NewMessage(), ros.MessageEvent{}); err != nil {
+		n.Logger().Errorf("NewSubscriber(%s): %v", topic, err)
+		callback = nil
+	}
+	return n.subscribe(topic, msgType, &subscriber{callback: callback}, opts)
+}
+
+func (n *Node) NewSubscriberChan(topic string, msgType ros.MessageType, bufferSize int, policy ros.DropPolicy, opts ...ros.SubscriberOption) (<-chan ros.ReceivedMessage, ros.Subscriber) {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	ch := make(chan ros.ReceivedMessage, bufferSize)
+	return ch, n.subscribe(topic, msgType, &subscriber{ch: ch, policy: policy}, opts)
+}
+
+func (n *Node) subscribe(topic string, msgType ros.MessageType, sub *subscriber, opts []ros.SubscriberOption) *subscriber {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	sub.node = n
+	sub.topic = n.resolve(topic)
+	sub.msgType = msgType
+	sub.monitor = ros.NewSubscriptionMonitor(sub.topic, n.executor, opts...)
+	t := n.topic(sub.topic)
+	t.subscribers[sub] = struct{}{}
+	for pub := range t.publishers {
+		pub.notify(pub.connectCallback, sub)
+	}
+	sub.monitor.PublishersChanged(len(t.publishers))
+	n.track(sub, sub.Shutdown)
+	return sub
+}
+
+func (n *Node) NewServiceClient(service string, srvType ros.ServiceType) ros.ServiceClient {
+	return &serviceClient{node: n, service: n.resolve(service), srvType: srvType}
+}
+
+func (n *Node) NewServiceServer(service string, srvType ros.ServiceType, callback interface{}) ros.ServiceServer {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	s := &serviceServer{node: n, name: n.resolve(service), srvType: srvType, handler: callback}
+	n.services[s.name] = s
+	n.track(s, s.Shutdown)
+	return s
+}
+
+// StubService answers calls to service with handler, which fills in the response of the
+// service it is passed. A stub replaces any server of the same name.
+func (n *Node) StubService(service string, handler func(ros.Service) error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	s := &serviceServer{node: n, name: n.resolve(service), handler: handler}
+	n.services[s.name] = s
+}
+
+// ServiceCalls returns the services that were called on service, with their responses
+// filled in, in the order they were called.
+func (n *Node) ServiceCalls(service string) []ros.Service {
+	n.mutex.Lock()
+	s, ok := n.services[n.resolve(service)]
+	n.mutex.Unlock()
+	if !ok {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	calls := make([]ros.Service, len(s.calls))
+	copy(calls, s.calls)
+	return calls
+}
+
+// WaitForService blocks until a server or stub for service exists or ctx is done.
+func (n *Node) WaitForService(ctx context.Context, service string) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		n.mutex.Lock()
+		_, ok := n.services[n.resolve(service)]
+		n.mutex.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for service %s: %w", service, ctx.Err())
+		}
+	}
+}
+
+func (n *Node) NewTimer(period time.Duration, callback func(ros.TimerEvent)) ros.Timer {
+	post := func(name string, run func()) {
+		n.executor.Post(ros.Job{Name: name, Run: run})
+	}
+	timer := nodeimpl.NewTimer[ros.ClockTimer](n.executor.Clock(), post, period, func(event nodeimpl.TimerEvent) {
+		callback(ros.TimerEvent(event))
+	})
+	if period <= 0 {
+		n.Logger().Errorf("NewTimer: the period must be positive but is %v", period)
+		return timer
+	}
+	timer.OnStop = func() { n.untrack(timer) }
+	timer.Start()
+	n.mutex.Lock()
+	n.timers = append(n.timers, timer)
+	n.mutex.Unlock()
+	n.track(timer, timer.Stop)
+	return timer
+}
+
+// Clock returns the clock of the node's executor.
+func (n *Node) Clock() ros.Clock {
+	return n.executor.Clock()
+}
+
+func (n *Node) OK() bool {
+	if n.view != nil {
+		n.view.mutex.Lock()
+		shutdown := n.view.shutdown
+		n.view.mutex.Unlock()
+		if shutdown {
+			return false
+		}
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.ok
+}
+
+// SpinOnce runs the callbacks that are pending on the node's executor.
+func (n *Node) SpinOnce() {
+	n.executor.SpinOnce()
+}
+
+// Spin runs callbacks as they are queued until the node is shut down.
+func (n *Node) Spin() {
+	n.executor.Spin(n.OK)
+}
+
+// Shutdown stops the timers and closes the channels of all subscribers. On a namespace
+// view it only releases what was created through the view.
+func (n *Node) Shutdown() {
+	if n.view != nil {
+		n.view.mutex.Lock()
+		n.view.shutdown = true
+		releases := n.view.releases
+		n.view.releases = make(map[interface{}]func())
+		n.view.mutex.Unlock()
+		for _, release := range releases {
+			release()
+		}
+		return
+	}
+	n.mutex.Lock()
+	n.ok = false
+	timers := n.timers
+	n.timers = nil
+	for _, t := range n.topics {
+		for sub := range t.subscribers {
+			sub.close()
+		}
+		t.subscribers = make(map[*subscriber]struct{})
+	}
+	n.mutex.Unlock()
+	for _, timer := range timers {
+		timer.Stop()
+	}
+}
+
+// GetParam returns the parameter name. A namespace is returned as a map of its
+// parameters, like the parameter server does, and a parameter that is not set is
+// reported with the *ros.APIError the master answers with.
+func (n *Node) GetParam(name string) (interface{}, error) {
+	key, err := n.resolveParam(name)
+	if err != nil {
+		return nil, err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	value, ok := n.getParam(key)
+	if !ok {
+		return nil, &ros.APIError{Method: "getParam", Code: -1, Message: "Parameter [" + key + "] is not set"}
+	}
+	return value, nil
+}
+
+// getParam returns the parameter or namespace key. The caller must hold the mutex.
+func (n *Node) getParam(key string) (interface{}, bool) {
+	if value, ok := n.params[key]; ok {
+		return value, true
+	}
+	prefix := strings.TrimSuffix(key, "/") + "/"
+	namespace := map[string]interface{}{}
+	found := false
+	for k, value := range n.params {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		found = true
+		parts := strings.Split(strings.TrimPrefix(k, prefix), "/")
+		m := namespace
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := m[part].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				m[part] = child
+			}
+			m = child
+		}
+		m[parts[len(parts)-1]] = value
+	}
+	return namespace, found
+}
+
+// SetParam sets the parameter name. A map value sets a namespace of parameters and
+// replaces what was in it.
+func (n *Node) SetParam(name string, value interface{}) error {
+	key, err := n.resolveParam(name)
+	if err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.deleteParam(key)
+	n.setParam(key, value)
+	return nil
+}
+
+// setParam stores value under key, flattening maps. The caller must hold the mutex.
+func (n *Node) setParam(key string, value interface{}) {
+	if m, ok := value.(map[string]interface{}); ok && len(m) > 0 {
+		for k, v := range m {
+			n.setParam(path.Join(key, k), v)
+		}
+		return
+	}
+	n.params[key] = value
+}
+
+// deleteParam removes key and the parameters below it. It reports whether anything was
+// removed. The caller must hold the mutex.
+func (n *Node) deleteParam(key string) bool {
+	prefix := strings.TrimSuffix(key, "/") + "/"
+	deleted := false
+	for k := range n.params {
+		if k == key || strings.HasPrefix(k, prefix) {
+			delete(n.params, k)
+			deleted = true
+		}
+	}
+	return deleted
+}
+
+func (n *Node) HasParam(name string) (bool, error) {
+	key, err := n.resolveParam(name)
+	if err != nil {
+		return false, err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	_, ok := n.getParam(key)
+	return ok, nil
+}
+
+// SearchParam looks for name, after remapping, in the node's namespace and then in each
+// parent namespace.
+func (n *Node) SearchParam(name string) (string, error) {
+	if err := ros.ValidateName(name); err != nil {
+		return "", err
+	}
+	key := strings.TrimPrefix(n.searchKey(name), "/")
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for ns := n.namespace; ; ns = path.Dir(ns) {
+		candidate := path.Join(ns, key)
+		if _, ok := n.getParam(candidate); ok {
+			return candidate, nil
+		}
+		if ns == "/" {
+			return "", fmt.Errorf("parameter %s not found", name)
+		}
+	}
+}
+
+func (n *Node) DeleteParam(name string) error {
+	key, err := n.resolveParam(name)
+	if err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if !n.deleteParam(key) {
+		return fmt.Errorf("parameter %s is not set", key)
+	}
+	return nil
+}
+
+func (n *Node) Logger() ros.Logger {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.logger
+}
+
+func (n *Node) SetLogger(logger ros.Logger) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.logger = logger
+}
+
+func (n *Node) NonRosArgs() []string {
+	return n.nonRosArgs
+}
+
+// Name returns the node's name without its namespace, as ros.Node.Name does.
+func (n *Node) Name() string {
+	return path.Base(n.qualifiedName)
+}
@@ -0,0 +1,533 @@
+package rostest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+type stringMessageType struct{}
+
+func (t *stringMessageType) Text() string            { return "string data" }
+func (t *stringMessageType) MD5Sum() string          { return "992ce8a1687cec8c8bd883ec73ca41d1" }
+func (t *stringMessageType) Name() string            { return "std_msgs/String" }
+func (t *stringMessageType) NewMessage() ros.Message { return &stringMessage{} }
+
+type stringMessage struct {
+	data string
+}
+
+func (m *stringMessage) GetType() ros.MessageType { return &stringMessageType{} }
+
+func (m *stringMessage) Serialize(buf *bytes.Buffer) error {
+	_, err := buf.WriteString(m.data)
+	return err
+}
+
+func (m *stringMessage) Deserialize(buf *bytes.Reader) error {
+	data, err := io.ReadAll(buf)
+	m.data = string(data)
+	return err
+}
+
+// stampedMessage has a header like generated messages.
+type stampedMessage struct {
+	Header struct {
+		Seq     uint32
+		Stamp   ros.Time
+		FrameID string
+	}
+}
+
+func (m *stampedMessage) GetType() ros.MessageType { return &stringMessageType{} }
+
+func (m *stampedMessage) Serialize(buf *bytes.Buffer) error {
+	binary.Write(buf, binary.LittleEndian, []uint32{m.Header.Seq, m.Header.Stamp.Sec, m.Header.Stamp.NSec})
+	_, err := buf.WriteString(m.Header.FrameID)
+	return err
+}
+
+func (m *stampedMessage) Deserialize(buf *bytes.Reader) error {
+	var fields [3]uint32
+	if err := binary.Read(buf, binary.LittleEndian, &fields); err != nil {
+		return err
+	}
+	m.Header.Seq, m.Header.Stamp.Sec, m.Header.Stamp.NSec = fields[0], fields[1], fields[2]
+	frameID, err := io.ReadAll(buf)
+	m.Header.FrameID = string(frameID)
+	return err
+}
+
+type stampedMessageType struct {
+	stringMessageType
+}
+
+func (t *stampedMessageType) NewMessage() ros.Message { return &stampedMessage{} }
+
+type echoService struct {
+	request  stringMessage
+	response stringMessage
+}
+
+func (s *echoService) ReqMessage() ros.Message { return &s.request }
+func (s *echoService) ResMessage() ros.Message { return &s.response }
+
+type echoServiceType struct{}
+
+func (t *echoServiceType) MD5Sum() string                { return "f5c5a07b3aef9fbbf0b2c5e1b9a0dc55" }
+func (t *echoServiceType) Name() string                  { return "rostest/Echo" }
+func (t *echoServiceType) RequestType() ros.MessageType  { return &stringMessageType{} }
+func (t *echoServiceType) ResponseType() ros.MessageType { return &stringMessageType{} }
+func (t *echoServiceType) NewService() ros.Service       { return &echoService{} }
+
+var _ ros.Node = &Node{}
+
+func TestPublishAndSubscribe(t *testing.T) {
+	node := NewNode("/ns/talker")
+	defer node.Shutdown()
+
+	var received []string
+	var publisher string
+	node.NewSubscriber("chatter", &stringMessageType{}, func(msg *stringMessage, event ros.MessageEvent) {
+		received = append(received, msg.data)
+		publisher = event.PublisherName
+	})
+	pub := node.NewPublisher("/ns/chatter", &stringMessageType{})
+	if n := pub.GetNumSubscribers(); n != 1 {
+		t.Errorf("Expected 1 subscriber but got %d", n)
+	}
+
+	msg := &stringMessage{data: "hello"}
+	pub.Publish(msg)
+	msg.data = "changed"
+	if len(received) != 0 {
+		t.Error("Callback must not run before the executor runs it")
+	}
+	if ran := node.Executor().RunPending(); !reflect.DeepEqual(ran, []string{"subscriber /ns/chatter"}) {
+		t.Errorf("Unexpected jobs %v", ran)
+	}
+	if !reflect.DeepEqual(received, []string{"hello"}) || publisher != "/ns/talker" {
+		t.Errorf("Unexpected message %v from %s", received, publisher)
+	}
+
+	published := node.Published("chatter")
+	if len(published) != 1 || published[0].(*stringMessage).data != "hello" {
+		t.Errorf("Expected a copy of the published message but got %v", published)
+	}
+}
+
+func TestPublishHeaders(t *testing.T) {
+	node := NewNode("/camera")
+	defer node.Shutdown()
+	node.Executor().Advance(3 * time.Second)
+
+	pub := node.NewPublisher("image", &stampedMessageType{}, ros.WithHeaderSequence(), ros.WithHeaderStamp(), ros.WithHeaderFrameID("camera_link"))
+	pub.Publish(&stampedMessage{})
+	msg := &stampedMessage{}
+	msg.Header.Stamp = ros.NewTime(1, 0)
+	pub.Publish(msg)
+	published := node.Published("image")
+	if len(published) != 2 {
+		t.Fatalf("Expected 2 messages but got %d", len(published))
+	}
+	first, second := published[0].(*stampedMessage).Header, published[1].(*stampedMessage).Header
+	if first.Seq != 0 || first.Stamp != ros.NewTime(3, 0) || first.FrameID != "camera_link" {
+		t.Errorf("Unexpected header %+v", first)
+	}
+	if second.Seq != 1 || second.Stamp != ros.NewTime(1, 0) {
+		t.Errorf("Unexpected header %+v", second)
+	}
+
+	plain := node.NewPublisher("raw", &stampedMessageType{})
+	msg = &stampedMessage{}
+	msg.Header.Seq = 7
+	plain.Publish(msg)
+	if header := node.Published("raw")[0].(*stampedMessage).Header; header.Seq != 7 || !header.Stamp.IsZero() {
+		t.Errorf("Expected the header to be left alone but got %+v", header)
+	}
+}
+
+func TestInject(t *testing.T) {
+	node := NewNode("listener")
+	defer node.Shutdown()
+	node.Executor().Clock().(*ros.ManualClock).Advance(time.Second)
+
+	msgChan, sub := node.NewSubscriberChan("/chatter", &stringMessageType{}, 1, ros.DropOldest)
+	if err := node.Inject("/chatter", &stringMessage{data: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Inject("/chatter", &stringMessage{data: "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	received := <-msgChan
+	if received.Message.(*stringMessage).data != "second" {
+		t.Errorf("Expected the oldest message to be dropped but got %v", received.Message)
+	}
+	if received.Event.PublisherName != InjectedPublisherName || !received.Event.ReceiptTime.Equal(time.Unix(1, 0)) {
+		t.Errorf("Unexpected event %v", received.Event)
+	}
+
+	sub.Shutdown()
+	if _, ok := <-msgChan; ok {
+		t.Error("Expected the channel to be closed on shutdown")
+	}
+	if err := node.Inject("/chatter", &stringMessage{}); err != nil {
+		t.Errorf("Injecting without subscribers must not fail: %v", err)
+	}
+}
+
+func TestPublisherCallbacks(t *testing.T) {
+	node := NewNode("talker")
+	defer node.Shutdown()
+
+	var events []string
+	connect := func(ssp ros.SingleSubscriberPublisher) {
+		events = append(events, "connect "+ssp.GetTopic())
+		ssp.Publish(&stringMessage{data: "latched"})
+	}
+	disconnect := func(ssp ros.SingleSubscriberPublisher) {
+		events = append(events, "disconnect "+ssp.GetTopic())
+	}
+	node.NewPublisherWithCallbacks("/chatter", &stringMessageType{}, connect, disconnect)
+
+	var received []string
+	sub := node.NewSubscriber("/chatter", &stringMessageType{}, func(msg *stringMessage) {
+		received = append(received, msg.data)
+	})
+	node.SpinOnce()
+	node.SpinOnce()
+	sub.Shutdown()
+	node.SpinOnce()
+
+	if !reflect.DeepEqual(events, []string{"connect /chatter", "disconnect /chatter"}) {
+		t.Errorf("Unexpected callbacks %v", events)
+	}
+	if !reflect.DeepEqual(received, []string{"latched"}) {
+		t.Errorf("Expected the message published by the connect callback but got %v", received)
+	}
+}
+
+func TestServices(t *testing.T) {
+	node := NewNode("client")
+	defer node.Shutdown()
+
+	client := node.NewServiceClient("echo", &echoServiceType{})
+	if err := client.Call(&echoService{}); err == nil {
+		t.Error("Expected a call without a server to fail")
+	}
+
+	server := node.NewServiceServer("/echo", &echoServiceType{}, func(srv *echoService) error {
+		srv.response.data = srv.request.data
+		return nil
+	})
+	srv := &echoService{request: stringMessage{data: "ping"}}
+	if err := client.Call(srv); err != nil {
+		t.Fatal(err)
+	}
+	if srv.response.data != "ping" {
+		t.Errorf("Expected response ping but got %q", srv.response.data)
+	}
+	server.Shutdown()
+
+	stubErr := errors.New("unavailable")
+	node.StubService("/echo", func(srv ros.Service) error {
+		return stubErr
+	})
+	if err := client.Call(&echoService{}); err != stubErr {
+		t.Errorf("Expected the stub's error but got %v", err)
+	}
+	if calls := node.ServiceCalls("/echo"); len(calls) != 1 {
+		t.Errorf("Expected one call to the stub but got %d", len(calls))
+	}
+}
+
+func TestParams(t *testing.T) {
+	node := NewNode("/robot/driver")
+	defer node.Shutdown()
+
+	node.SetParam("~rate", 10)
+	node.SetParam("/robot/frame", "base_link")
+	node.SetParam("/global", true)
+
+	if value, err := node.GetParam("/robot/driver/rate"); err != nil || value != 10 {
+		t.Errorf("Expected private param 10 but got %v, %v", value, err)
+	}
+	if value, err := node.GetParam("frame"); err != nil || value != "base_link" {
+		t.Errorf("Expected relative param base_link but got %v, %v", value, err)
+	}
+	if name, err := node.SearchParam("global"); err != nil || name != "/global" {
+		t.Errorf("Expected to find /global but got %q, %v", name, err)
+	}
+	if err := node.DeleteParam("frame"); err != nil {
+		t.Error(err)
+	}
+	if ok, _ := node.HasParam("frame"); ok {
+		t.Error("Expected frame to be deleted")
+	}
+	if _, err := node.GetParam("missing"); err == nil {
+		t.Error("Expected an error for a missing param")
+	}
+}
+
+func TestName(t *testing.T) {
+	node := NewNode("/robot/driver")
+	defer node.Shutdown()
+	if name := node.Name(); name != "driver" {
+		t.Errorf("Expected the name without namespace but got %s", name)
+	}
+}
+
+func TestTimer(t *testing.T) {
+	node := NewNode("timer")
+
+	var ticks int
+	node.NewTimer(100*time.Millisecond, func(ros.TimerEvent) { ticks++ })
+	node.NewTimer(0, func(ros.TimerEvent) { t.Error("Timer with a zero period must not fire") })
+	for i := 0; i < 3; i++ {
+		node.Executor().Advance(100 * time.Millisecond)
+	}
+	if ticks != 3 {
+		t.Errorf("Expected 3 ticks but got %d", ticks)
+	}
+
+	node.Shutdown()
+	node.Executor().Advance(time.Second)
+	if ticks != 3 || node.OK() {
+		t.Errorf("Expected shutdown to stop the timer, got %d ticks", ticks)
+	}
+}
+
+func TestWaitForMessage(t *testing.T) {
+	node := NewNode("listener")
+	defer node.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := ros.WaitForMessage(ctx, node, "/chatter", &stringMessageType{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to time out but got %v", err)
+	}
+
+	pub := node.NewPublisher("/chatter", &stringMessageType{})
+	go func() {
+		for pub.GetNumSubscribers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		pub.Publish(&stringMessage{data: "hello"})
+	}()
+	msg, event, err := ros.WaitForMessage(context.Background(), node, "/chatter", &stringMessageType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.(*stringMessage).data != "hello" || event.PublisherName != "/listener" {
+		t.Errorf("Unexpected message %v from %s", msg, event.PublisherName)
+	}
+	if n := pub.GetNumSubscribers(); n != 0 {
+		t.Errorf("Expected the subscriber to be shut down but got %d subscribers", n)
+	}
+}
+
+func TestLatestSubscriber(t *testing.T) {
+	node := NewNode("/monitor")
+	defer node.Shutdown()
+	battery := ros.NewLatestSubscriber(node, "battery", &stringMessageType{})
+	if _, _, ok := battery.Latest(); ok || battery.HasReceived() {
+		t.Error("Expected no message before one is received")
+	}
+
+	pub := node.NewPublisher("battery", &stringMessageType{})
+	pub.Publish(&stringMessage{data: "80%"})
+	node.Executor().Advance(time.Second)
+	pub.Publish(&stringMessage{data: "79%"})
+	msg, event, ok := battery.Latest()
+	if !ok || msg.(*stringMessage).data != "79%" || event.PublisherName != "/monitor" {
+		t.Errorf("Expected the last message but got %v %+v", msg, event)
+	}
+	if !battery.HasReceived() {
+		t.Error("Expected a message to have been received")
+	}
+
+	node.Executor().Advance(2 * time.Second)
+	if msg, _, ok := battery.LatestWithin(2 * time.Second); !ok || msg.(*stringMessage).data != "79%" {
+		t.Errorf("Expected the message to be recent enough but got %v", msg)
+	}
+	if msg, _, ok := battery.LatestWithin(time.Second); ok {
+		t.Errorf("Expected the message to be too old but got %v", msg)
+	}
+
+	battery.Shutdown()
+	pub.Publish(&stringMessage{data: "78%"})
+	if msg, _, ok := battery.Latest(); !ok || msg.(*stringMessage).data != "79%" {
+		t.Errorf("Expected the last message to be kept after shutdown but got %v", msg)
+	}
+}
+
+func TestSubscriberMonitoring(t *testing.T) {
+	node := NewNode("/base")
+	defer node.Shutdown()
+	var events []string
+	sub := node.NewSubscriber("heartbeat", &stringMessageType{}, func(*stringMessage) {},
+		ros.WithDeadline(time.Second,
+			func(e ros.DeadlineEvent) { events = append(events, fmt.Sprintf("missed %s %d", e.Topic, e.Missed)) },
+			func(e ros.DeadlineEvent) { events = append(events, "deadline recovered") }),
+		ros.WithLiveliness(1,
+			func(e ros.LivelinessEvent) { events = append(events, fmt.Sprintf("lost %d", e.Publishers)) },
+			func(e ros.LivelinessEvent) { events = append(events, "liveliness recovered") }))
+
+	pub := node.NewPublisher("heartbeat", &stringMessageType{})
+	node.Executor().Advance(500 * time.Millisecond)
+	pub.Publish(&stringMessage{data: "beat"})
+	node.Executor().Advance(900 * time.Millisecond)
+	if len(events) != 0 {
+		t.Errorf("Expected no events while the topic is alive but got %v", events)
+	}
+
+	pub.Shutdown()
+	node.Executor().Advance(200 * time.Millisecond)
+	pub = node.NewPublisher("heartbeat", &stringMessageType{})
+	pub.Publish(&stringMessage{data: "beat"})
+	node.Executor().RunPending()
+	expected := []string{"lost 0", "missed /heartbeat 1", "liveliness recovered", "deadline recovered"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected %v but got %v", expected, events)
+	}
+
+	sub.Shutdown()
+	pub.Shutdown()
+	node.Executor().Advance(time.Hour)
+	if len(events) != len(expected) {
+		t.Errorf("Expected no events after shutdown but got %v", events)
+	}
+}
+
+func TestWaitForService(t *testing.T) {
+	node := NewNode("client")
+	defer node.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := node.WaitForService(ctx, "/echo"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to time out but got %v", err)
+	}
+
+	node.StubService("/echo", func(ros.Service) error { return nil })
+	if err := node.WaitForService(context.Background(), "echo"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	node := NewNode("/robot/driver")
+	defer node.Shutdown()
+	arm := node.Namespace("arm_left", ros.NameMap{"state": "/arm_state"})
+	gripper := arm.Namespace("gripper", nil)
+
+	var received []string
+	node.NewSubscriber("arm_left/gripper/cmd", &stringMessageType{}, func(msg *stringMessage) {
+		received = append(received, msg.data)
+	})
+	gripper.NewPublisher("cmd", &stringMessageType{}).Publish(&stringMessage{data: "close"})
+	arm.NewPublisher("state", &stringMessageType{}).Publish(&stringMessage{data: "moving"})
+	node.Executor().RunPending()
+	if !reflect.DeepEqual(received, []string{"close"}) {
+		t.Errorf("Expected the view to publish in its namespace but got %v", received)
+	}
+	if len(node.Published("/arm_state")) != 1 {
+		t.Error("Expected the view to apply its remapping")
+	}
+
+	if err := arm.SetParam("~rate", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := arm.SetParam("speed", 1.5); err != nil {
+		t.Fatal(err)
+	}
+	if rate, _ := node.GetParam("~rate"); rate != 10 {
+		t.Errorf("Expected private names to resolve against the node but got %v", rate)
+	}
+	if found, err := gripper.SearchParam("speed"); err != nil || found != "/robot/arm_left/speed" {
+		t.Errorf("Expected to find /robot/arm_left/speed but got %q, %v", found, err)
+	}
+
+	sub := gripper.NewSubscriber("cmd", &stringMessageType{}, func(*stringMessage) {})
+	timer := gripper.NewTimer(time.Second, func(ros.TimerEvent) {})
+	gripper.Shutdown()
+	if gripper.OK() || !arm.OK() || !node.OK() {
+		t.Error("Expected only the view to be shut down")
+	}
+	if n := sub.GetNumPublishers(); n != 0 {
+		t.Errorf("Expected the subscriber of the view to be shut down but it has %d publishers", n)
+	}
+	node.Executor().Advance(time.Second)
+	if pending := node.Executor().Pending(); len(pending) != 0 {
+		t.Errorf("Expected the timer of the view to be stopped but got %v", pending)
+	}
+	timer.Stop()
+	node.NewPublisher("arm_left/gripper/cmd", &stringMessageType{}).Publish(&stringMessage{data: "open"})
+	node.Executor().RunPending()
+	if !reflect.DeepEqual(received, []string{"close", "open"}) {
+		t.Errorf("Expected the node's subscriber to keep running but got %v", received)
+	}
+}
+
+func TestNamespaceForgetsShutDownHandles(t *testing.T) {
+	node := NewNode("/robot/driver")
+	defer node.Shutdown()
+	arm := node.Namespace("arm", nil).(*Node)
+
+	for i := 0; i < 3; i++ {
+		arm.NewPublisher("cmd", &stringMessageType{}).Shutdown()
+		arm.NewSubscriber("state", &stringMessageType{}, func(*stringMessage) {}).Shutdown()
+		arm.NewServiceServer("echo", &echoServiceType{}, func(*echoService) error { return nil }).Shutdown()
+		arm.NewTimer(time.Second, func(ros.TimerEvent) {}).Stop()
+	}
+	if n := len(arm.view.releases); n != 0 {
+		t.Errorf("Expected the view to forget the handles that were shut down but it tracks %d", n)
+	}
+}
+
+func TestRemapping(t *testing.T) {
+	node := NewNode("/robot/driver")
+	defer node.Shutdown()
+	arm := node.Namespace("arm", nil)
+
+	if err := node.AddRemapping("arm/cmd", "~arm_cmd"); err != nil {
+		t.Fatal(err)
+	}
+	if name, err := arm.ResolveName("cmd", true); err != nil || name != "/robot/driver/arm_cmd" {
+		t.Errorf("Expected views to use the new remapping but got %q, %v", name, err)
+	}
+	if name, err := arm.ResolveName("cmd", false); err != nil || name != "/robot/arm/cmd" {
+		t.Errorf("Expected /robot/arm/cmd but got %q, %v", name, err)
+	}
+	arm.NewPublisher("cmd", &stringMessageType{}).Publish(&stringMessage{data: "stop"})
+	if len(node.Published("/robot/driver/arm_cmd")) != 1 {
+		t.Error("Expected the publisher to use the remapping")
+	}
+
+	if err := node.AddRemapping("limit", "/limits/speed"); err != nil {
+		t.Fatal(err)
+	}
+	node.SetParam("/limits/speed", 2.0)
+	if found, err := node.SearchParam("limit"); err != nil || found != "/limits/speed" {
+		t.Errorf("Expected the search to use the remapping but got %q, %v", found, err)
+	}
+
+	if err := node.AddRemapping("cmd", "1cmd"); err == nil {
+		t.Error("Expected an error for an invalid remapping")
+	}
+	if _, err := node.ResolveName("a b", true); err == nil {
+		t.Error("Expected an error for an invalid name")
+	}
+	if err := node.SetParam("a b", 1); err == nil {
+		t.Error("Expected an error for an invalid parameter name")
+	}
+}
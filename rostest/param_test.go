@@ -0,0 +1,136 @@
+package rostest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+type limits struct {
+	MaxSpeed float64 `param:"max_speed,required"`
+	MaxAccel float64
+}
+
+type driverConfig struct {
+	Port     string `param:"port,required"`
+	Baud     int
+	Timeout  time.Duration
+	Frames   []string
+	Gains    map[string]float64
+	Limits   limits
+	Verbose  bool
+	Internal string `param:"-"`
+	ignored  int
+}
+
+func TestTypedParamGetters(t *testing.T) {
+	node := NewNode("/driver")
+	node.SetParam("/rate", int32(10))
+	node.SetParam("/scale", int32(2))
+	node.SetParam("/name", "base")
+	node.SetParam("/enabled", true)
+	node.SetParam("/frames", []interface{}{"map", "odom"})
+	node.SetParam("/gains", []interface{}{1.5, int32(2)})
+	node.SetParam("/ns", map[string]interface{}{"a": int32(1)})
+
+	if v, err := ros.GetParamInt(node, "/rate", 5); err != nil || v != 10 {
+		t.Errorf("Unexpected int %d, %v", v, err)
+	}
+	if v, err := ros.GetParamInt(node, "/missing", 5); err != nil || v != 5 {
+		t.Errorf("Expected the default for a missing param but got %d, %v", v, err)
+	}
+	if v, err := ros.GetParamInt(node, "/name", 5); err == nil || v != 5 {
+		t.Errorf("Expected a type error and the default but got %d, %v", v, err)
+	}
+	if v, err := ros.GetParamFloat(node, "/scale", 1); err != nil || v != 2 {
+		t.Errorf("Unexpected float %v, %v", v, err)
+	}
+	if v, err := ros.GetParamString(node, "/name", ""); err != nil || v != "base" {
+		t.Errorf("Unexpected string %q, %v", v, err)
+	}
+	if v, err := ros.GetParamBool(node, "/enabled", false); err != nil || !v {
+		t.Errorf("Unexpected bool %v, %v", v, err)
+	}
+	if v, err := ros.GetParamStringSlice(node, "/frames", nil); err != nil || !reflect.DeepEqual(v, []string{"map", "odom"}) {
+		t.Errorf("Unexpected strings %v, %v", v, err)
+	}
+	if v, err := ros.GetParamFloatSlice(node, "/gains", nil); err != nil || !reflect.DeepEqual(v, []float64{1.5, 2}) {
+		t.Errorf("Unexpected floats %v, %v", v, err)
+	}
+	if v, err := ros.GetParamMap(node, "/ns", nil); err != nil || !reflect.DeepEqual(v, map[string]interface{}{"a": int32(1)}) {
+		t.Errorf("Unexpected map %v, %v", v, err)
+	}
+}
+
+func TestLoadParams(t *testing.T) {
+	node := NewNode("/driver")
+	node.SetParam("~", map[string]interface{}{
+		"port":    "/dev/ttyUSB0",
+		"baud":    int32(115200),
+		"timeout": 0.5,
+		"frames":  []interface{}{"laser"},
+		"gains":   map[string]interface{}{"p": 1.0, "i": int32(0)},
+		"limits":  map[string]interface{}{"max_speed": 2.0},
+	})
+
+	config := driverConfig{Verbose: true, Limits: limits{MaxAccel: 1}}
+	if err := ros.LoadParams(node, "/driver", &config); err != nil {
+		t.Fatal(err)
+	}
+	expected := driverConfig{
+		Port:    "/dev/ttyUSB0",
+		Baud:    115200,
+		Timeout: 500 * time.Millisecond,
+		Frames:  []string{"laser"},
+		Gains:   map[string]float64{"p": 1, "i": 0},
+		Limits:  limits{MaxSpeed: 2, MaxAccel: 1},
+		Verbose: true,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Unexpected config %+v", config)
+	}
+
+	var empty driverConfig
+	err := ros.LoadParams(node, "/other", &empty)
+	var missing *ros.MissingParamError
+	if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Names, []string{"/other/port", "/other/limits/max_speed"}) {
+		t.Errorf("Expected the required params to be reported but got %v", err)
+	}
+
+	node.SetParam("/bad/baud", "fast")
+	if err := ros.LoadParams(node, "/bad", &empty); err == nil || errors.As(err, &missing) {
+		t.Errorf("Expected a type error but got %v", err)
+	}
+}
+
+func TestStoreParams(t *testing.T) {
+	node := NewNode("/driver")
+	config := driverConfig{
+		Port:     "/dev/ttyUSB1",
+		Baud:     9600,
+		Timeout:  2 * time.Second,
+		Limits:   limits{MaxSpeed: 1.5},
+		Internal: "secret",
+	}
+	if err := ros.StoreParams(node, "/stored", &config); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := node.GetParam("/stored/limits/max_speed"); err != nil || v != 1.5 {
+		t.Errorf("Unexpected nested param %v, %v", v, err)
+	}
+	if ok, _ := node.HasParam("/stored/internal"); ok {
+		t.Error("Expected the skipped field not to be stored")
+	}
+
+	var loaded driverConfig
+	if err := ros.LoadParams(node, "/stored", &loaded); err != nil {
+		t.Fatal(err)
+	}
+	config.Internal = ""
+	if !reflect.DeepEqual(loaded, config) {
+		t.Errorf("Round trip changed the config: %+v", loaded)
+	}
+}
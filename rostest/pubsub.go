@@ -0,0 +1,221 @@
+package rostest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fetchrobotics/rosgo/internal/nodeimpl"
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// publisher publishes to the subscribers of its node. Connect and disconnect callbacks
+// are queued on the node's executor rather than started in their own goroutines.
+type publisher struct {
+	node               *Node
+	topic              string
+	msgType            ros.MessageType
+	connectCallback    func(ros.SingleSubscriberPublisher)
+	disconnectCallback func(ros.SingleSubscriberPublisher)
+	headers            *ros.HeaderFiller
+}
+
+func (pub *publisher) Publish(msg ros.Message) {
+	pub.headers.Fill(msg, pub.node.executor.Clock().Now())
+	pub.publish(msg, nil)
+}
+
+// publish records a copy of msg and delivers it to the subscribers of the topic, or only
+// to target if it is set.
+func (pub *publisher) publish(msg ros.Message, target *subscriber) {
+	n := pub.node
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	m, err := copyMessage(msg, pub.msgType)
+	if err != nil {
+		n.logger.Errorf("Failed to publish on %s: %v", pub.topic, err)
+		return
+	}
+	t := n.topic(pub.topic)
+	t.published = append(t.published, m)
+	event := ros.MessageEvent{
+		PublisherName: n.qualifiedName,
+		ReceiptTime:   n.executor.Clock().Now(),
+		ConnectionHeader: map[string]string{
+			"callerid": n.qualifiedName,
+			"topic":    pub.topic,
+			"type":     pub.msgType.Name(),
+			"md5sum":   pub.msgType.MD5Sum(),
+		},
+	}
+	if err := n.deliver(pub.topic, m, event, target); err != nil {
+		n.logger.Errorf("Failed to publish on %s: %v", pub.topic, err)
+	}
+}
+
+func (pub *publisher) GetNumSubscribers() int {
+	pub.node.mutex.Lock()
+	defer pub.node.mutex.Unlock()
+	return len(pub.node.topic(pub.topic).subscribers)
+}
+
+func (pub *publisher) Shutdown() {
+	n := pub.node
+	n.untrack(pub)
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	t := n.topic(pub.topic)
+	if _, ok := t.publishers[pub]; !ok {
+		return
+	}
+	delete(t.publishers, pub)
+	for sub := range t.subscribers {
+		pub.notify(pub.disconnectCallback, sub)
+		sub.monitor.PublishersChanged(len(t.publishers))
+	}
+}
+
+// notify queues callback for the connection between pub and sub. The caller must hold
+// the node's mutex.
+func (pub *publisher) notify(callback func(ros.SingleSubscriberPublisher), sub *subscriber) {
+	if callback == nil {
+		return
+	}
+	ssp := &singleSubscriberPublisher{pub: pub, sub: sub}
+	pub.node.executor.Post(ros.Job{Name: "publisher " + pub.topic, Run: func() {
+		callback(ssp)
+	}})
+}
+
+type singleSubscriberPublisher struct {
+	pub *publisher
+	sub *subscriber
+}
+
+func (ssp *singleSubscriberPublisher) Publish(msg ros.Message) {
+	ssp.pub.publish(msg, ssp.sub)
+}
+
+func (ssp *singleSubscriberPublisher) GetSubscriberName() string {
+	return ssp.pub.node.qualifiedName
+}
+
+func (ssp *singleSubscriberPublisher) GetTopic() string {
+	return ssp.pub.topic
+}
+
+// subscriber receives messages with a callback run on the node's executor, or through
+// a channel that is fed immediately.
+type subscriber struct {
+	node     *Node
+	topic    string
+	msgType  ros.MessageType
+	callback interface{}
+	ch       chan ros.ReceivedMessage
+	policy   ros.DropPolicy
+	monitor  *ros.SubscriptionMonitor
+}
+
+// deliver hands msg to the subscriber. The caller must hold the node's mutex.
+func (sub *subscriber) deliver(msg ros.Message, event ros.MessageEvent) {
+	sub.monitor.MessageReceived()
+	if sub.ch != nil {
+		nodeimpl.PushMessage(sub.ch, sub.policy == ros.DropOldest, ros.ReceivedMessage{Message: msg, Event: event})
+	}
+	if sub.callback != nil {
+		callback := sub.callback
+		sub.node.executor.Post(ros.Job{Name: "subscriber " + sub.topic, Run: func() {
+			nodeimpl.CallMessageCallback(callback, msg, event)
+		}})
+	}
+}
+
+// close closes the subscriber's channel and stops its monitor. The caller must hold the
+// node's mutex.
+func (sub *subscriber) close() {
+	sub.monitor.Stop()
+	if sub.ch != nil {
+		close(sub.ch)
+	}
+}
+
+func (sub *subscriber) GetNumPublishers() int {
+	sub.node.mutex.Lock()
+	defer sub.node.mutex.Unlock()
+	return len(sub.node.topic(sub.topic).publishers)
+}
+
+func (sub *subscriber) Shutdown() {
+	n := sub.node
+	n.untrack(sub)
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	t := n.topic(sub.topic)
+	if _, ok := t.subscribers[sub]; !ok {
+		return
+	}
+	delete(t.subscribers, sub)
+	sub.close()
+	for pub := range t.publishers {
+		pub.notify(pub.disconnectCallback, sub)
+	}
+}
+
+// serviceServer answers calls for a service server or a stub.
+type serviceServer struct {
+	node    *Node
+	name    string
+	srvType ros.ServiceType
+	handler interface{}
+
+	mutex sync.Mutex
+	calls []ros.Service
+}
+
+func (s *serviceServer) Shutdown() {
+	s.node.untrack(s)
+	s.node.mutex.Lock()
+	defer s.node.mutex.Unlock()
+	if s.node.services[s.name] == s {
+		delete(s.node.services, s.name)
+	}
+}
+
+// serviceClient calls services of its node. Calls run the handler synchronously on the
+// calling goroutine, so they need no spinning.
+type serviceClient struct {
+	node    *Node
+	service string
+	srvType ros.ServiceType
+}
+
+func (c *serviceClient) Call(srv ros.Service) error {
+	c.node.mutex.Lock()
+	s, ok := c.node.services[c.service]
+	c.node.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("service %s is not available", c.service)
+	}
+	if s.srvType != nil && c.srvType != nil && s.srvType.MD5Sum() != c.srvType.MD5Sum() {
+		return fmt.Errorf("service %s has type %s but was called with %s", c.service, s.srvType.Name(), c.srvType.Name())
+	}
+
+	// A server sees its own copy of the request, as it would over the wire.
+	call := srv
+	if s.srvType != nil {
+		call = s.srvType.NewService()
+		if err := copyInto(call.ReqMessage(), srv.ReqMessage()); err != nil {
+			return err
+		}
+	}
+	err := nodeimpl.CallServiceHandler(s.handler, call)
+	if err == nil && call != srv {
+		err = copyInto(srv.ResMessage(), call.ResMessage())
+	}
+
+	s.mutex.Lock()
+	s.calls = append(s.calls, srv)
+	s.mutex.Unlock()
+	return err
+}
+
+func (c *serviceClient) Shutdown() {}
@@ -0,0 +1,69 @@
+package rostest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+func TestLoadAndDumpParamYAML(t *testing.T) {
+	node := NewNode("/robot/driver")
+	node.SetParam("~limits/max_accel", 2.0)
+
+	path := filepath.Join(t.TempDir(), "driver.yaml")
+	if err := os.WriteFile(path, []byte("rate: 20\nlimits:\n  max_speed: !degrees 90\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ros.LoadParamFile(node, "~", path); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := node.GetParam("/robot/driver/rate"); err != nil || v != int32(20) {
+		t.Errorf("Unexpected rate %v, %v", v, err)
+	}
+	if v, err := node.GetParam("~limits/max_accel"); err != nil || v != 2.0 {
+		t.Errorf("Expected loading to merge into the namespace but got %v, %v", v, err)
+	}
+
+	dumped := filepath.Join(t.TempDir(), "dump.yaml")
+	if err := ros.DumpParamFile(node, "~", dumped); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dumped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "limits:\n    max_accel: 2.0\n    max_speed: 1.5707963267948966\nrate: 20\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected dump:\n%s", data)
+	}
+
+	other := NewNode("/other")
+	if err := ros.LoadParamYAML(other, "/copy", data); err != nil {
+		t.Fatal(err)
+	}
+	original, _ := node.GetParam("~")
+	copied, _ := other.GetParam("/copy")
+	if !reflect.DeepEqual(copied, map[string]interface{}{
+		"limits": map[string]interface{}{"max_accel": 2.0, "max_speed": 1.5707963267948966},
+		"rate":   int32(20),
+	}) {
+		t.Errorf("Unexpected round trip %v of %v", copied, original)
+	}
+}
+
+func TestLoadEmptyParamYAML(t *testing.T) {
+	node := NewNode("/robot/driver")
+	node.SetParam("~rate", 20)
+
+	for _, data := range []string{"", "# nothing to load\n", "{}", "limits: {}\n"} {
+		if err := ros.LoadParamYAML(node, "~", []byte(data)); err != nil {
+			t.Fatalf("Loading %q: %v", data, err)
+		}
+		if v, err := node.GetParam("~"); err != nil || !reflect.DeepEqual(v, map[string]interface{}{"rate": 20}) {
+			t.Errorf("Expected loading %q to keep the parameters but got %v, %v", data, v, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package tf
+
+import (
+	"geometry_msgs"
+	"strings"
+	"sync"
+	"tf2_msgs"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// Broadcaster publishes transforms on /tf.
+type Broadcaster struct {
+	pub ros.Publisher
+}
+
+// NewBroadcaster advertises /tf on node.
+func NewBroadcaster(node ros.Node, opts ...ros.PublisherOption) *Broadcaster {
+	return &Broadcaster{pub: node.NewPublisher(tfTopic, tf2_msgs.MsgTFMessage, opts...)}
+}
+
+// SendTransform publishes transforms in one message.
+func (b *Broadcaster) SendTransform(transforms ...geometry_msgs.TransformStamped) {
+	b.pub.Publish(&tf2_msgs.TFMessage{Transforms: transforms})
+}
+
+// Shutdown stops the publisher.
+func (b *Broadcaster) Shutdown() {
+	b.pub.Shutdown()
+}
+
+// StaticBroadcaster publishes transforms that do not change over time on /tf_static.
+// The topic is latched: every subscriber that connects receives all transforms sent
+// so far, the latest one for each child frame, in one message.
+type StaticBroadcaster struct {
+	pub ros.Publisher
+
+	mutex      sync.Mutex
+	transforms []geometry_msgs.TransformStamped
+}
+
+// NewStaticBroadcaster advertises /tf_static on node.
+func NewStaticBroadcaster(node ros.Node, opts ...ros.PublisherOption) *StaticBroadcaster {
+	b := &StaticBroadcaster{}
+	b.pub = node.NewPublisherWithCallbacks(tfStaticTopic, tf2_msgs.MsgTFMessage, b.connected, nil, opts...)
+	return b
+}
+
+// SendTransform adds transforms, replacing earlier transforms of the same child
+// frames, and publishes all transforms.
+func (b *StaticBroadcaster) SendTransform(transforms ...geometry_msgs.TransformStamped) {
+	b.mutex.Lock()
+	for _, transform := range transforms {
+		b.replace(transform)
+	}
+	msg := b.message()
+	b.mutex.Unlock()
+	b.pub.Publish(msg)
+}
+
+// replace adds transform or replaces the transform of its child frame. The caller must
+// hold the mutex.
+func (b *StaticBroadcaster) replace(transform geometry_msgs.TransformStamped) {
+	child := strings.TrimPrefix(transform.ChildFrameId, "/")
+	for i := range b.transforms {
+		if strings.TrimPrefix(b.transforms[i].ChildFrameId, "/") == child {
+			b.transforms[i] = transform
+			return
+		}
+	}
+	b.transforms = append(b.transforms, transform)
+}
+
+// message returns a message of all transforms. The caller must hold the mutex.
+func (b *StaticBroadcaster) message() *tf2_msgs.TFMessage {
+	return &tf2_msgs.TFMessage{Transforms: append([]geometry_msgs.TransformStamped(nil), b.transforms...)}
+}
+
+// connected sends all transforms to a new subscriber to emulate latching.
+func (b *StaticBroadcaster) connected(pub ros.SingleSubscriberPublisher) {
+	b.mutex.Lock()
+	msg := b.message()
+	b.mutex.Unlock()
+	if len(msg.Transforms) > 0 {
+		pub.Publish(msg)
+	}
+}
+
+// Shutdown stops the publisher.
+func (b *StaticBroadcaster) Shutdown() {
+	b.pub.Shutdown()
+}
@@ -0,0 +1,364 @@
+// Package tf keeps track of coordinate frames over time, modelled on the tf2 ROS
+// packages. A Buffer stores the transforms between frames and looks up the transform
+// between any two frames of a tree at a time, a Listener fills a Buffer from /tf and
+// /tf_static, and Broadcasters publish transforms on them. Transforms are exchanged as
+// geometry_msgs.TransformStamped messages and computed with Transform.
+package tf
+
+//go:generate gengo -out=$GOPATH/src msg std_msgs/Header
+//go:generate gengo -out=$GOPATH/src msg geometry_msgs/Vector3
+//go:generate gengo -out=$GOPATH/src msg geometry_msgs/Quaternion
+//go:generate gengo -out=$GOPATH/src msg geometry_msgs/Transform
+//go:generate gengo -out=$GOPATH/src msg geometry_msgs/TransformStamped
+//go:generate gengo -out=$GOPATH/src msg tf2_msgs/TFMessage
+
+import (
+	"context"
+	"fmt"
+	"geometry_msgs"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// DefaultCacheTime is the time a Buffer keeps transforms by default, as in tf2.
+const DefaultCacheTime = 10 * time.Second
+
+// maxGraphDepth bounds the walks up the frame tree, which guards against loops.
+const maxGraphDepth = 1000
+
+// Buffer stores the transforms between frames for its cache time and looks up
+// transforms between frames by chaining them through the frame tree. Each frame has at
+// most one parent at a time. Transforms at times between those that were set are
+// interpolated, linearly for translations and spherically for rotations; lookups
+// outside of the stored times fail with an ExtrapolationError.
+//
+// Frame IDs have no leading slash. A zero time in a lookup means the latest time at
+// which all transforms between the frames are available.
+type Buffer struct {
+	cacheTime time.Duration
+
+	mutex       sync.Mutex
+	frames      map[string]*timeCache
+	parents     map[string]int
+	authorities map[string]string
+	changed     chan struct{}
+}
+
+// NewBuffer creates a buffer that keeps transforms for cacheTime. A cacheTime of zero
+// is DefaultCacheTime.
+func NewBuffer(cacheTime time.Duration) *Buffer {
+	if cacheTime <= 0 {
+		cacheTime = DefaultCacheTime
+	}
+	b := &Buffer{cacheTime: cacheTime}
+	b.Clear()
+	return b
+}
+
+// CacheTime returns the time the buffer keeps transforms.
+func (b *Buffer) CacheTime() time.Duration {
+	return b.cacheTime
+}
+
+// Clear removes all transforms from the buffer.
+func (b *Buffer) Clear() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.frames = make(map[string]*timeCache)
+	b.parents = make(map[string]int)
+	b.authorities = make(map[string]string)
+	if b.changed == nil {
+		b.changed = make(chan struct{})
+	}
+}
+
+// SetTransform adds a transform to the buffer. authority names its source, e.g. the
+// node that published it. A static transform is valid at all times and replaces the
+// previous transform of its child frame. A leading slash of the frame IDs is removed.
+func (b *Buffer) SetTransform(transform geometry_msgs.TransformStamped, authority string, static bool) error {
+	parent := strings.TrimPrefix(transform.Header.FrameId, "/")
+	child := strings.TrimPrefix(transform.ChildFrameId, "/")
+	if err := validateTransform(parent, child, TransformFromMsg(transform.Transform), authority); err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	cache, ok := b.frames[child]
+	if !ok || cache.static != static {
+		if ok {
+			cache.release(cache.entries)
+		}
+		cache = newTimeCache(child, b.cacheTime, static, b.parents)
+		b.frames[child] = cache
+	}
+	entry := transformEntry{stamp: transform.Header.Stamp, parent: parent, transform: TransformFromMsg(transform.Transform)}
+	if err := cache.insert(entry); err != nil {
+		return fmt.Errorf("TF_OLD_DATA ignoring data from the past according to authority %s: %w", authority, err)
+	}
+	b.authorities[child] = authority
+	close(b.changed)
+	b.changed = make(chan struct{})
+	return nil
+}
+
+func validateTransform(parent, child string, transform Transform, authority string) error {
+	switch {
+	case parent == child:
+		return &InvalidArgumentError{fmt.Sprintf("TF_SELF_TRANSFORM: Ignoring transform from authority %q with frame_id and child_frame_id %q because they are the same", authority, child)}
+	case parent == "":
+		return &InvalidArgumentError{fmt.Sprintf("TF_NO_FRAME_ID: Ignoring transform with child_frame_id %q from authority %q because frame_id not set", child, authority)}
+	case child == "":
+		return &InvalidArgumentError{fmt.Sprintf("TF_NO_CHILD_FRAME_ID: Ignoring transform from authority %q because child_frame_id not set", authority)}
+	}
+	t, r := transform.Translation, transform.Rotation
+	for _, x := range []float64{t.X, t.Y, t.Z, r.X, r.Y, r.Z, r.W} {
+		if math.IsNaN(x) {
+			return &InvalidArgumentError{fmt.Sprintf("TF_NAN_INPUT: Ignoring transform for child_frame_id %q from authority %q because of a nan value in the transform", child, authority)}
+		}
+	}
+	if math.Abs(r.Dot(r)-1) > 10e-6 {
+		return &InvalidArgumentError{fmt.Sprintf("TF_DENORMALIZED_QUATERNION: Ignoring transform for child_frame_id %q from authority %q because of an invalid quaternion in the transform", child, authority)}
+	}
+	return nil
+}
+
+// FrameExists reports whether the buffer has transforms from or to frame.
+func (b *Buffer) FrameExists(frame string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.frameExists(frame)
+}
+
+// frameExists reports whether frame has transforms. The caller must hold the mutex.
+func (b *Buffer) frameExists(frame string) bool {
+	if _, ok := b.frames[frame]; ok {
+		return true
+	}
+	return b.parents[frame] > 0
+}
+
+// Authority returns the authority of the latest transform of frame to its parent.
+func (b *Buffer) Authority(frame string) (string, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	authority, ok := b.authorities[frame]
+	return authority, ok
+}
+
+// LookupTransform returns the transform from the frame source to the frame target at
+// time t. The result maps coordinates in source to coordinates in target.
+func (b *Buffer) LookupTransform(target, source string, t ros.Time) (geometry_msgs.TransformStamped, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.lookup(target, source, t)
+}
+
+// LookupTransformFull returns the transform from the frame source at sourceTime to the
+// frame target at targetTime, assuming that the frame fixed does not move in between,
+// e.g. to transform an earlier observation into the current frame of a moving robot.
+func (b *Buffer) LookupTransformFull(target string, targetTime ros.Time, source string, sourceTime ros.Time, fixed string) (geometry_msgs.TransformStamped, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err := validateFrameID("fixed_frame", fixed); err != nil {
+		return geometry_msgs.TransformStamped{}, err
+	}
+	sourceToFixed, err := b.lookup(fixed, source, sourceTime)
+	if err != nil {
+		return geometry_msgs.TransformStamped{}, err
+	}
+	fixedToTarget, err := b.lookup(target, fixed, targetTime)
+	if err != nil {
+		return geometry_msgs.TransformStamped{}, err
+	}
+	fixedToTarget.ChildFrameId = sourceToFixed.ChildFrameId
+	fixedToTarget.Transform = TransformFromMsg(fixedToTarget.Transform).Mul(TransformFromMsg(sourceToFixed.Transform)).Msg()
+	return fixedToTarget, nil
+}
+
+// CanTransform reports whether LookupTransform would succeed.
+func (b *Buffer) CanTransform(target, source string, t ros.Time) bool {
+	_, err := b.LookupTransform(target, source, t)
+	return err == nil
+}
+
+// WaitForTransform looks up the transform from source to target at time t like
+// LookupTransform, waiting for it to become available until ctx is done. Use
+// context.WithTimeout to bound the wait, as with the timeouts of lookupTransform and
+// canTransform in tf2. A lookup that fails because ctx is done returns a TimeoutError
+// that wraps the error of the last attempt. Invalid frame IDs fail immediately.
+func (b *Buffer) WaitForTransform(ctx context.Context, target, source string, t ros.Time) (geometry_msgs.TransformStamped, error) {
+	for {
+		b.mutex.Lock()
+		transform, err := b.lookup(target, source, t)
+		changed := b.changed
+		b.mutex.Unlock()
+		if err == nil {
+			return transform, nil
+		}
+		if _, invalid := err.(*InvalidArgumentError); invalid {
+			return geometry_msgs.TransformStamped{}, err
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return geometry_msgs.TransformStamped{}, &TimeoutError{Err: err}
+		}
+	}
+}
+
+func validateFrameID(argument, frame string) error {
+	if frame == "" {
+		return &InvalidArgumentError{fmt.Sprintf("Invalid argument passed to lookupTransform argument %s in tf2 frame_ids cannot be empty", argument)}
+	}
+	if strings.HasPrefix(frame, "/") {
+		return &InvalidArgumentError{fmt.Sprintf("Invalid argument %q passed to lookupTransform argument %s in tf2 frame_ids cannot start with a '/' like: ", frame, argument)}
+	}
+	return nil
+}
+
+// lookup returns the transform from source to target at t. The caller must hold the
+// mutex.
+func (b *Buffer) lookup(target, source string, t ros.Time) (geometry_msgs.TransformStamped, error) {
+	if err := validateFrameID("target_frame", target); err != nil {
+		return geometry_msgs.TransformStamped{}, err
+	}
+	if err := validateFrameID("source_frame", source); err != nil {
+		return geometry_msgs.TransformStamped{}, err
+	}
+	if !b.frameExists(target) {
+		return geometry_msgs.TransformStamped{}, &LookupError{Frame: target, Argument: "target_frame"}
+	}
+	if !b.frameExists(source) {
+		return geometry_msgs.TransformStamped{}, &LookupError{Frame: source, Argument: "source_frame"}
+	}
+	if t.IsZero() {
+		t = b.latestCommonTime(target, source)
+	}
+	transform, err := b.chain(target, source, t)
+	if err != nil {
+		return geometry_msgs.TransformStamped{}, err
+	}
+	result := geometry_msgs.TransformStamped{ChildFrameId: source, Transform: transform.Msg()}
+	result.Header.FrameId = target
+	result.Header.Stamp = t
+	return result, nil
+}
+
+// chain walks up the tree from source and then from target until it meets a frame of
+// the first walk, and combines the transforms of both walks. The caller must hold the
+// mutex.
+func (b *Buffer) chain(target, source string, t ros.Time) (Transform, error) {
+	// fromSource maps each frame above source to the transform from source to it.
+	fromSource := map[string]Transform{source: Identity}
+	var walkErr error
+	frame, transform := source, Identity
+	for depth := 0; ; depth++ {
+		if depth > maxGraphDepth {
+			return Transform{}, fmt.Errorf("the tf tree is invalid because it contains a loop above frame %s", source)
+		}
+		cache, ok := b.frames[frame]
+		if !ok {
+			break
+		}
+		entry, err := cache.get(t)
+		if err != nil {
+			walkErr = err
+			break
+		}
+		frame, transform = entry.parent, entry.transform.Mul(transform)
+		if _, ok := fromSource[frame]; ok {
+			return Transform{}, fmt.Errorf("the tf tree is invalid because it contains a loop at frame %s", frame)
+		}
+		fromSource[frame] = transform
+	}
+
+	frame, transform = target, Identity
+	for depth := 0; ; depth++ {
+		if s, ok := fromSource[frame]; ok {
+			return transform.Inverse().Mul(s), nil
+		}
+		if depth > maxGraphDepth {
+			return Transform{}, fmt.Errorf("the tf tree is invalid because it contains a loop above frame %s", target)
+		}
+		cache, ok := b.frames[frame]
+		if !ok {
+			break
+		}
+		entry, err := cache.get(t)
+		if err != nil {
+			walkErr = err
+			break
+		}
+		frame, transform = entry.parent, entry.transform.Mul(transform)
+	}
+	if walkErr != nil {
+		return Transform{}, walkErr
+	}
+	return Transform{}, &ConnectivityError{Target: target, Source: source}
+}
+
+// latestCommonTime returns the latest time at which all transforms between target and
+// source are available, which is the earliest of the latest times of the transforms
+// on the path between them. It is zero if the path is static or there is none. The
+// caller must hold the mutex.
+func (b *Buffer) latestCommonTime(target, source string) ros.Time {
+	if target == source {
+		if cache, ok := b.frames[source]; ok && !cache.static {
+			return cache.latest().stamp
+		}
+		return ros.Time{}
+	}
+
+	// fromSource maps each frame above source to the earliest latest time on the way
+	// to it.
+	type stampedFrame struct {
+		stamp ros.Time
+		ok    bool
+	}
+	fromSource := map[string]stampedFrame{source: {}}
+	earliest := func(a stampedFrame, cache *timeCache) stampedFrame {
+		if cache.static {
+			return a
+		}
+		stamp := cache.latest().stamp
+		if !a.ok || stamp.Cmp(a.stamp) < 0 {
+			return stampedFrame{stamp, true}
+		}
+		return a
+	}
+	frame, common := source, stampedFrame{}
+	for depth := 0; depth <= maxGraphDepth; depth++ {
+		cache, ok := b.frames[frame]
+		if !ok {
+			break
+		}
+		common = earliest(common, cache)
+		frame = cache.latest().parent
+		if _, ok := fromSource[frame]; ok {
+			break
+		}
+		fromSource[frame] = common
+	}
+
+	frame, common = target, stampedFrame{}
+	for depth := 0; depth <= maxGraphDepth; depth++ {
+		if s, ok := fromSource[frame]; ok {
+			if s.ok && (!common.ok || s.stamp.Cmp(common.stamp) < 0) {
+				common = s
+			}
+			return common.stamp
+		}
+		cache, ok := b.frames[frame]
+		if !ok {
+			break
+		}
+		common = earliest(common, cache)
+		frame = cache.latest().parent
+	}
+	return ros.Time{}
+}
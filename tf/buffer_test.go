@@ -0,0 +1,288 @@
+package tf
+
+import (
+	"context"
+	"errors"
+	"geometry_msgs"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+const epsilon = 1e-9
+
+func near(a, b Vector3) bool {
+	return math.Abs(a.X-b.X) < epsilon && math.Abs(a.Y-b.Y) < epsilon && math.Abs(a.Z-b.Z) < epsilon
+}
+
+// sameRotation reports whether q and r are the same rotation, which holds for r = -q.
+func sameRotation(q, r Quaternion) bool {
+	return math.Abs(math.Abs(q.Dot(r))-1) < epsilon
+}
+
+func stamped(parent, child string, sec uint32, translation Vector3, rotation Quaternion) geometry_msgs.TransformStamped {
+	var t geometry_msgs.TransformStamped
+	t.Header.FrameId = parent
+	t.Header.Stamp = ros.NewTime(sec, 0)
+	t.ChildFrameId = child
+	t.Transform = Transform{Translation: translation, Rotation: rotation}.Msg()
+	return t
+}
+
+func TestTransform(t *testing.T) {
+	quarter := NewQuaternionFromRPY(0, 0, math.Pi/2)
+	if !near(quarter.Rotate(Vector3{1, 0, 0}), Vector3{0, 1, 0}) {
+		t.Errorf("expected a quarter turn about Z but got %v", quarter.Rotate(Vector3{1, 0, 0}))
+	}
+	if !sameRotation(quarter, NewQuaternionFromAxisAngle(Vector3{0, 0, 2}, math.Pi/2)) {
+		t.Errorf("expected the same rotation from an axis and angle")
+	}
+	roll, pitch, yaw := NewQuaternionFromRPY(0.1, -0.2, 0.3).RPY()
+	if math.Abs(roll-0.1) > epsilon || math.Abs(pitch+0.2) > epsilon || math.Abs(yaw-0.3) > epsilon {
+		t.Errorf("unexpected roll, pitch and yaw %v %v %v", roll, pitch, yaw)
+	}
+
+	a := Transform{Translation: Vector3{1, 2, 3}, Rotation: quarter}
+	b := Transform{Translation: Vector3{-1, 0, 0.5}, Rotation: NewQuaternionFromRPY(0.3, 0, 0)}
+	p := Vector3{0.5, -0.25, 2}
+	if !near(a.Mul(b).Apply(p), a.Apply(b.Apply(p))) {
+		t.Errorf("expected Mul to compose transforms")
+	}
+	if !near(a.Inverse().Apply(a.Apply(p)), p) {
+		t.Errorf("expected Inverse to undo the transform")
+	}
+
+	half := Identity.Interpolate(Transform{Translation: Vector3{2, 0, 0}, Rotation: quarter}, 0.5)
+	if !near(half.Translation, Vector3{1, 0, 0}) || !sameRotation(half.Rotation, NewQuaternionFromRPY(0, 0, math.Pi/4)) {
+		t.Errorf("unexpected interpolation %+v", half)
+	}
+	// -quarter is the same rotation, so the shorter way is still an eighth turn.
+	negated := Quaternion{-quarter.X, -quarter.Y, -quarter.Z, -quarter.W}
+	if q := Identity.Rotation.Slerp(negated, 0.5); !sameRotation(q, NewQuaternionFromRPY(0, 0, math.Pi/4)) {
+		t.Errorf("expected slerp along the shorter path but got %+v", q)
+	}
+}
+
+func TestTimeCache(t *testing.T) {
+	parents := map[string]int{}
+	c := newTimeCache("base_link", 10*time.Second, false, parents)
+	if err := c.insert(transformEntry{ros.NewTime(20, 0), "odom", Transform{Translation: Vector3{2, 0, 0}, Rotation: Quaternion{W: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	var extrapolation *ExtrapolationError
+	if _, err := c.get(ros.NewTime(21, 0)); !errors.As(err, &extrapolation) || extrapolation.Direction != ExtrapolationSingle {
+		t.Errorf("expected an extrapolation error from a single transform but got %v", err)
+	}
+	c.insert(transformEntry{ros.NewTime(10, 0), "odom", Transform{Rotation: Quaternion{W: 1}}})
+	c.insert(transformEntry{ros.NewTime(25, 0), "odom", Transform{Translation: Vector3{4, 0, 0}, Rotation: Quaternion{W: 1}}})
+	if len(c.entries) != 2 || c.entries[0].stamp != ros.NewTime(20, 0) {
+		t.Errorf("expected the oldest transform to be pruned but got %+v", c.entries)
+	}
+	if err := c.insert(transformEntry{ros.NewTime(14, 0), "odom", Identity}); err == nil {
+		t.Error("expected data older than the cache time to be rejected")
+	}
+
+	entry, err := c.get(ros.NewTime(21, 0))
+	if err != nil || !near(entry.transform.Translation, Vector3{2.4, 0, 0}) || entry.stamp != ros.NewTime(21, 0) {
+		t.Errorf("unexpected interpolation %+v %v", entry, err)
+	}
+	if _, err := c.get(ros.NewTime(19, 0)); !errors.As(err, &extrapolation) || extrapolation.Direction != ExtrapolationPast {
+		t.Errorf("expected an extrapolation into the past but got %v", err)
+	}
+	if _, err := c.get(ros.NewTime(26, 0)); !errors.As(err, &extrapolation) || extrapolation.Direction != ExtrapolationFuture ||
+		extrapolation.Available != ros.NewTime(25, 0) || extrapolation.Source != "base_link" || extrapolation.Target != "odom" {
+		t.Errorf("expected an extrapolation into the future but got %v", err)
+	}
+	if entry, _ := c.get(ros.Time{}); entry.stamp != ros.NewTime(25, 0) {
+		t.Errorf("expected the latest transform for time zero but got %+v", entry)
+	}
+
+	// A transform at the same time replaces the old one, and transforms to different
+	// parents are not interpolated.
+	c.insert(transformEntry{ros.NewTime(25, 0), "map", Transform{Translation: Vector3{5, 0, 0}, Rotation: Quaternion{W: 1}}})
+	if entry, _ := c.get(ros.NewTime(22, 0)); entry.parent != "odom" || !near(entry.transform.Translation, Vector3{2, 0, 0}) {
+		t.Errorf("expected the earlier transform across a change of parent but got %+v", entry)
+	}
+	if entry, _ := c.get(ros.NewTime(25, 0)); entry.parent != "map" || len(c.entries) != 2 {
+		t.Errorf("expected the transform to be replaced but got %+v", c.entries)
+	}
+	if !reflect.DeepEqual(parents, map[string]int{"odom": 1, "map": 1}) {
+		t.Errorf("expected the parents of the remaining transforms to be counted but got %v", parents)
+	}
+	c.insert(transformEntry{ros.NewTime(40, 0), "map", Identity})
+	if !reflect.DeepEqual(parents, map[string]int{"map": 1}) {
+		t.Errorf("expected pruned parents to be forgotten but got %v", parents)
+	}
+}
+
+// newTestBuffer returns a buffer with the tree
+//
+//	map -> odom -> base_link -> laser
+//	                         -> camera (static)
+//
+// in which base_link moves along X by one meter per second from time 10 to 12.
+func newTestBuffer(t *testing.T) *Buffer {
+	b := NewBuffer(0)
+	set := func(transform geometry_msgs.TransformStamped, static bool) {
+		if err := b.SetTransform(transform, "/test", static); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set(stamped("map", "odom", 10, Vector3{0, 1, 0}, Quaternion{W: 1}), false)
+	set(stamped("map", "odom", 12, Vector3{0, 1, 0}, Quaternion{W: 1}), false)
+	for sec := uint32(10); sec <= 12; sec++ {
+		set(stamped("odom", "base_link", sec, Vector3{float64(sec - 10), 0, 0}, NewQuaternionFromRPY(0, 0, math.Pi/2)), false)
+	}
+	set(stamped("base_link", "laser", 11, Vector3{0.5, 0, 0}, Quaternion{W: 1}), false)
+	set(stamped("base_link", "laser", 13, Vector3{0.5, 0, 0}, Quaternion{W: 1}), false)
+	set(stamped("/base_link", "/camera", 0, Vector3{0, 0, 1}, NewQuaternionFromRPY(0, 0, math.Pi)), true)
+	return b
+}
+
+func TestBufferLookupTransform(t *testing.T) {
+	b := newTestBuffer(t)
+	if b.CacheTime() != DefaultCacheTime {
+		t.Errorf("expected the default cache time but got %v", b.CacheTime())
+	}
+
+	// laser is half a meter ahead of base_link, which faces along Y in map.
+	transform, err := b.LookupTransform("map", "laser", ros.NewTime(11, 500000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transform.Header.FrameId != "map" || transform.ChildFrameId != "laser" || transform.Header.Stamp != ros.NewTime(11, 500000000) {
+		t.Errorf("unexpected frames %+v", transform)
+	}
+	if p := TransformFromMsg(transform.Transform).Apply(Vector3{}); !near(p, Vector3{1.5, 1.5, 0}) {
+		t.Errorf("expected laser at (1.5, 1.5, 0) in map but got %v", p)
+	}
+
+	// Both frames hang off base_link, so only the static transform and that of laser
+	// matter, and the lookup goes through their common parent.
+	transform, err = b.LookupTransform("camera", "laser", ros.NewTime(12, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := TransformFromMsg(transform.Transform).Apply(Vector3{}); !near(p, Vector3{-0.5, 0, -1}) {
+		t.Errorf("expected laser at (-0.5, 0, -1) in camera but got %v", p)
+	}
+	inverse, _ := b.LookupTransform("laser", "camera", ros.NewTime(12, 0))
+	if p := TransformFromMsg(inverse.Transform).Apply(TransformFromMsg(transform.Transform).Apply(Vector3{1, 2, 3})); !near(p, Vector3{1, 2, 3}) {
+		t.Errorf("expected the reverse lookup to be the inverse but got %v", p)
+	}
+
+	// Time zero is the latest time at which the whole chain is available.
+	if transform, err = b.LookupTransform("map", "laser", ros.Time{}); err != nil || transform.Header.Stamp != ros.NewTime(12, 0) {
+		t.Errorf("expected the latest common time 12 but got %+v %v", transform, err)
+	}
+	if transform, err = b.LookupTransform("base_link", "camera", ros.Time{}); err != nil || !transform.Header.Stamp.IsZero() {
+		t.Errorf("expected time zero for a static chain but got %+v %v", transform, err)
+	}
+	if transform, err = b.LookupTransform("laser", "laser", ros.Time{}); err != nil || transform.Header.Stamp != ros.NewTime(13, 0) || TransformFromMsg(transform.Transform) != Identity {
+		t.Errorf("expected identity at the latest time of laser but got %+v %v", transform, err)
+	}
+	if !b.FrameExists("map") || b.FrameExists("/map") || b.FrameExists("gripper") {
+		t.Error("unexpected frames")
+	}
+	// Parents that no transform refers to any more are forgotten.
+	b.SetTransform(stamped("gripper", "tool", 0, Vector3{}, Quaternion{W: 1}), "/test", true)
+	b.SetTransform(stamped("wrist", "tool", 0, Vector3{}, Quaternion{W: 1}), "/test", true)
+	if b.FrameExists("gripper") || !b.FrameExists("wrist") {
+		t.Error("expected the static parent to be replaced")
+	}
+	b.SetTransform(stamped("arm", "tool", 13, Vector3{}, Quaternion{W: 1}), "/test", false)
+	if b.FrameExists("wrist") || !b.FrameExists("arm") {
+		t.Error("expected the static transform to be replaced by a dynamic one")
+	}
+	if authority, ok := b.Authority("camera"); !ok || authority != "/test" {
+		t.Errorf("unexpected authority %q", authority)
+	}
+}
+
+func TestBufferLookupTransformFull(t *testing.T) {
+	b := newTestBuffer(t)
+	// base_link faces along Y of odom and moved two meters along X of odom, to its
+	// right, between time 10 and 12, so its old position is two meters to its left.
+	transform, err := b.LookupTransformFull("base_link", ros.NewTime(12, 0), "base_link", ros.NewTime(10, 0), "odom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := TransformFromMsg(transform.Transform).Apply(Vector3{}); !near(p, Vector3{0, 2, 0}) {
+		t.Errorf("expected the old position two meters along Y but got %v", p)
+	}
+	if _, err := b.LookupTransformFull("base_link", ros.NewTime(12, 0), "base_link", ros.NewTime(10, 0), ""); err == nil {
+		t.Error("expected an error without a fixed frame")
+	}
+}
+
+func TestBufferErrors(t *testing.T) {
+	b := newTestBuffer(t)
+	b.SetTransform(stamped("world", "robot2", 11, Vector3{}, Quaternion{W: 1}), "/test", false)
+
+	var lookup *LookupError
+	if _, err := b.LookupTransform("map", "gripper", ros.NewTime(11, 0)); !errors.As(err, &lookup) || lookup.Argument != "source_frame" {
+		t.Errorf("expected a lookup error but got %v", err)
+	}
+	var connectivity *ConnectivityError
+	if _, err := b.LookupTransform("map", "robot2", ros.NewTime(11, 0)); !errors.As(err, &connectivity) {
+		t.Errorf("expected a connectivity error but got %v", err)
+	}
+	var extrapolation *ExtrapolationError
+	_, err := b.LookupTransform("map", "laser", ros.NewTime(10, 500000000))
+	if !errors.As(err, &extrapolation) || extrapolation.Direction != ExtrapolationPast || extrapolation.Source != "laser" {
+		t.Errorf("expected an extrapolation into the past but got %v", err)
+	}
+	expected := "Lookup would require extrapolation into the past.  Requested time 10.500000 but the earliest data is at time 11.000000, " +
+		"when looking up transform from frame [laser] to frame [base_link]"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected the tf2 message but got %v", err)
+	}
+	if _, err := b.LookupTransform("map", "base_link", ros.NewTime(12, 100)); !errors.As(err, &extrapolation) || extrapolation.Direction != ExtrapolationFuture {
+		t.Errorf("expected an extrapolation into the future but got %v", err)
+	}
+
+	var invalid *InvalidArgumentError
+	if _, err := b.LookupTransform("/map", "laser", ros.Time{}); !errors.As(err, &invalid) {
+		t.Errorf("expected a leading slash to be rejected but got %v", err)
+	}
+	for _, transform := range []geometry_msgs.TransformStamped{
+		stamped("map", "map", 1, Vector3{}, Quaternion{W: 1}),
+		stamped("", "odom", 1, Vector3{}, Quaternion{W: 1}),
+		stamped("map", "", 1, Vector3{}, Quaternion{W: 1}),
+		stamped("map", "odom", 1, Vector3{math.NaN(), 0, 0}, Quaternion{W: 1}),
+		stamped("map", "odom", 1, Vector3{}, Quaternion{W: 2}),
+	} {
+		if err := b.SetTransform(transform, "/test", false); !errors.As(err, &invalid) {
+			t.Errorf("expected %+v to be rejected but got %v", transform, err)
+		}
+	}
+}
+
+func TestBufferWaitForTransform(t *testing.T) {
+	b := newTestBuffer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var timeout *TimeoutError
+	var extrapolation *ExtrapolationError
+	if _, err := b.WaitForTransform(ctx, "map", "base_link", ros.NewTime(13, 0)); !errors.As(err, &timeout) || !errors.As(err, &extrapolation) {
+		t.Errorf("expected a timeout wrapping an extrapolation error but got %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.SetTransform(stamped("map", "odom", 14, Vector3{0, 1, 0}, Quaternion{W: 1}), "/test", false)
+		b.SetTransform(stamped("odom", "base_link", 14, Vector3{4, 0, 0}, Quaternion{W: 1}), "/test", false)
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	transform, err := b.WaitForTransform(ctx, "map", "base_link", ros.NewTime(13, 0))
+	if err != nil || !near(TransformFromMsg(transform.Transform).Translation, Vector3{3, 1, 0}) {
+		t.Errorf("expected the transform once it was set but got %+v %v", transform, err)
+	}
+	if !b.CanTransform("map", "base_link", ros.NewTime(13, 0)) || b.CanTransform("map", "base_link", ros.NewTime(15, 0)) {
+		t.Error("unexpected CanTransform")
+	}
+}
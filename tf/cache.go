@@ -0,0 +1,146 @@
+package tf
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// transformEntry is the transform from a frame to parent at stamp.
+type transformEntry struct {
+	stamp     ros.Time
+	parent    string
+	transform Transform
+}
+
+// timeCache holds the transforms of one frame to its parent, sorted by stamp. Its
+// parent may change over time. Transforms older than cacheTime before the latest one
+// are dropped. A static cache holds a single transform that is valid at all times.
+//
+// parents, which may be nil, counts the entries by parent frame. The caches of a
+// buffer share it, so that the buffer knows the parent frames without scanning them.
+type timeCache struct {
+	frame     string
+	cacheTime time.Duration
+	static    bool
+	entries   []transformEntry
+	parents   map[string]int
+}
+
+func newTimeCache(frame string, cacheTime time.Duration, static bool, parents map[string]int) *timeCache {
+	return &timeCache{frame: frame, cacheTime: cacheTime, static: static, parents: parents}
+}
+
+// insert adds entry to the cache, replacing a transform with the same stamp. Transforms
+// older than the cache time before the latest one are rejected.
+func (c *timeCache) insert(entry transformEntry) error {
+	if c.static {
+		c.release(c.entries)
+		c.retain(entry)
+		c.entries = []transformEntry{entry}
+		return nil
+	}
+	if n := len(c.entries); n > 0 {
+		latest := c.entries[n-1].stamp
+		if latest.UnixNano()-entry.stamp.UnixNano() > c.cacheTime.Nanoseconds() {
+			return fmt.Errorf("data for frame %s at time %f is older than the cache time %v before the latest data at time %f",
+				c.frame, entry.stamp.ToSec(), c.cacheTime, latest.ToSec())
+		}
+	}
+	i := sort.Search(len(c.entries), func(i int) bool { return c.entries[i].stamp.Cmp(entry.stamp) >= 0 })
+	c.retain(entry)
+	if i < len(c.entries) && c.entries[i].stamp == entry.stamp {
+		c.release(c.entries[i : i+1])
+		c.entries[i] = entry
+	} else {
+		c.entries = append(c.entries, transformEntry{})
+		copy(c.entries[i+1:], c.entries[i:])
+		c.entries[i] = entry
+	}
+	c.prune()
+	return nil
+}
+
+// prune drops the transforms older than the cache time before the latest one.
+func (c *timeCache) prune() {
+	latest := c.entries[len(c.entries)-1].stamp.UnixNano()
+	i := 0
+	for i < len(c.entries)-1 && latest-c.entries[i].stamp.UnixNano() > c.cacheTime.Nanoseconds() {
+		i++
+	}
+	c.release(c.entries[:i])
+	c.entries = c.entries[i:]
+}
+
+// retain counts the parent of entry, which is added to the cache.
+func (c *timeCache) retain(entry transformEntry) {
+	if c.parents != nil {
+		c.parents[entry.parent]++
+	}
+}
+
+// release stops counting the parents of entries, which are removed from the cache.
+func (c *timeCache) release(entries []transformEntry) {
+	if c.parents == nil {
+		return
+	}
+	for _, entry := range entries {
+		if c.parents[entry.parent]--; c.parents[entry.parent] <= 0 {
+			delete(c.parents, entry.parent)
+		}
+	}
+}
+
+// latest returns the latest transform of the cache.
+func (c *timeCache) latest() transformEntry {
+	return c.entries[len(c.entries)-1]
+}
+
+// get returns the transform at stamp, interpolated between the transforms before and
+// after it. A zero stamp is the latest transform. The transform of a static cache is
+// returned for any stamp.
+func (c *timeCache) get(stamp ros.Time) (transformEntry, error) {
+	n := len(c.entries)
+	if c.static || stamp.IsZero() {
+		return c.latest(), nil
+	}
+	first, last := c.entries[0], c.entries[n-1]
+	switch {
+	case n == 1 && stamp != first.stamp:
+		return transformEntry{}, c.extrapolationError(ExtrapolationSingle, stamp, first)
+	case stamp.Cmp(first.stamp) < 0:
+		return transformEntry{}, c.extrapolationError(ExtrapolationPast, stamp, first)
+	case stamp.Cmp(last.stamp) > 0:
+		return transformEntry{}, c.extrapolationError(ExtrapolationFuture, stamp, last)
+	}
+
+	i := sort.Search(n, func(i int) bool { return c.entries[i].stamp.Cmp(stamp) >= 0 })
+	after := c.entries[i]
+	if after.stamp == stamp {
+		return after, nil
+	}
+	before := c.entries[i-1]
+	if before.parent != after.parent {
+		// The frame was reparented in between; like tf2, use the earlier transform
+		// rather than interpolate between different parents.
+		return before, nil
+	}
+	ratio := float64(stamp.UnixNano()-before.stamp.UnixNano()) / float64(after.stamp.UnixNano()-before.stamp.UnixNano())
+	return transformEntry{
+		stamp:     stamp,
+		parent:    before.parent,
+		transform: before.transform.Interpolate(after.transform, ratio),
+	}, nil
+}
+
+func (c *timeCache) extrapolationError(direction Extrapolation, requested ros.Time, available transformEntry) error {
+	return &ExtrapolationError{
+		Direction: direction,
+		Requested: requested,
+		Available: available.stamp,
+		Source:    c.frame,
+		Target:    available.parent,
+	}
+}
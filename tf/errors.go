@@ -0,0 +1,100 @@
+package tf
+
+import (
+	"fmt"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+// The errors of the package correspond to the exceptions of tf2 and use the same
+// messages, so that logs read the same as those of C++ and Python nodes.
+
+// LookupError is returned when a frame does not exist in a buffer.
+type LookupError struct {
+	Frame string
+
+	// Argument names the argument of the lookup that named the frame, e.g.
+	// "target_frame".
+	Argument string
+}
+
+func (e *LookupError) Error() string {
+	return fmt.Sprintf("%q passed to lookupTransform argument %s does not exist. ", e.Frame, e.Argument)
+}
+
+// ConnectivityError is returned when two frames are in unconnected trees.
+type ConnectivityError struct {
+	Target string
+	Source string
+}
+
+func (e *ConnectivityError) Error() string {
+	return fmt.Sprintf("Could not find a connection between '%s' and '%s' because they are not part of the same tree."+
+		"Tf has two or more unconnected trees.", e.Target, e.Source)
+}
+
+// Extrapolation tells in which direction a lookup would have to extrapolate.
+type Extrapolation int
+
+const (
+	// ExtrapolationPast is a lookup before the earliest transform of a frame.
+	ExtrapolationPast Extrapolation = iota
+
+	// ExtrapolationFuture is a lookup after the latest transform of a frame.
+	ExtrapolationFuture
+
+	// ExtrapolationSingle is a lookup at another time than the only transform of a
+	// frame.
+	ExtrapolationSingle
+)
+
+// ExtrapolationError is returned when a lookup needs a transform at a time the buffer
+// has no data for. Transforms are interpolated, never extrapolated.
+type ExtrapolationError struct {
+	Direction Extrapolation
+
+	// Requested is the time of the lookup and Available the time of the earliest, the
+	// latest or the only transform of the link from Source to Target.
+	Requested ros.Time
+	Available ros.Time
+	Source    string
+	Target    string
+}
+
+func (e *ExtrapolationError) Error() string {
+	frames := fmt.Sprintf("when looking up transform from frame [%s] to frame [%s]", e.Source, e.Target)
+	switch e.Direction {
+	case ExtrapolationPast:
+		return fmt.Sprintf("Lookup would require extrapolation into the past.  Requested time %f but the earliest data is at time %f, %s",
+			e.Requested.ToSec(), e.Available.ToSec(), frames)
+	case ExtrapolationFuture:
+		return fmt.Sprintf("Lookup would require extrapolation into the future.  Requested time %f but the latest data is at time %f, %s",
+			e.Requested.ToSec(), e.Available.ToSec(), frames)
+	default:
+		return fmt.Sprintf("Lookup would require extrapolation at time %f, but only time %f is in the buffer, %s",
+			e.Requested.ToSec(), e.Available.ToSec(), frames)
+	}
+}
+
+// InvalidArgumentError is returned for a frame ID or transform that is not valid.
+type InvalidArgumentError struct {
+	Reason string
+}
+
+func (e *InvalidArgumentError) Error() string {
+	return e.Reason
+}
+
+// TimeoutError is returned when a transform is not available before the timeout of
+// a wait. Err is the error of the last attempt.
+type TimeoutError struct {
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for transform: %v", e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,63 @@
+package tf
+
+import (
+	"sync"
+	"tf2_msgs"
+
+	"github.com/fetchrobotics/rosgo/ros"
+)
+
+const (
+	tfTopic       = "/tf"
+	tfStaticTopic = "/tf_static"
+
+	// listenerQueueSize is the number of messages of each topic a listener buffers
+	// before dropping the oldest ones.
+	listenerQueueSize = 100
+)
+
+// Listener subscribes to /tf and /tf_static and adds the transforms it receives to
+// a buffer, with the publishing node as their authority. Messages are received on a
+// goroutine of the listener, so the node does not need to spin.
+type Listener struct {
+	node      ros.Node
+	buffer    *Buffer
+	tf        ros.Subscriber
+	tfStatic  ros.Subscriber
+	waitGroup sync.WaitGroup
+}
+
+// NewListener creates a listener that fills buffer from the topics of node.
+func NewListener(node ros.Node, buffer *Buffer, opts ...ros.SubscriberOption) *Listener {
+	l := &Listener{node: node, buffer: buffer}
+	tf, tfSub := node.NewSubscriberChan(tfTopic, tf2_msgs.MsgTFMessage, listenerQueueSize, ros.DropOldest, opts...)
+	tfStatic, tfStaticSub := node.NewSubscriberChan(tfStaticTopic, tf2_msgs.MsgTFMessage, listenerQueueSize, ros.DropOldest, opts...)
+	l.tf, l.tfStatic = tfSub, tfStaticSub
+	l.waitGroup.Add(2)
+	go l.receive(tf, false)
+	go l.receive(tfStatic, true)
+	return l
+}
+
+// Buffer returns the buffer of the listener.
+func (l *Listener) Buffer() *Buffer {
+	return l.buffer
+}
+
+func (l *Listener) receive(received <-chan ros.ReceivedMessage, static bool) {
+	defer l.waitGroup.Done()
+	for msg := range received {
+		for _, transform := range msg.Message.(*tf2_msgs.TFMessage).Transforms {
+			if err := l.buffer.SetTransform(transform, msg.Event.PublisherName, static); err != nil {
+				l.node.Logger().Errorf("tf: %v", err)
+			}
+		}
+	}
+}
+
+// Shutdown unsubscribes from the topics. Transforms received before stay in the buffer.
+func (l *Listener) Shutdown() {
+	l.tf.Shutdown()
+	l.tfStatic.Shutdown()
+	l.waitGroup.Wait()
+}
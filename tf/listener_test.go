@@ -0,0 +1,85 @@
+package tf
+
+import (
+	"context"
+	"errors"
+	"geometry_msgs"
+	"reflect"
+	"testing"
+	"tf2_msgs"
+	"time"
+
+	"github.com/fetchrobotics/rosgo/ros"
+	"github.com/fetchrobotics/rosgo/rostest"
+)
+
+func TestBroadcasterAndListener(t *testing.T) {
+	node := rostest.NewNode("/robot/tf_test")
+	defer node.Shutdown()
+	buffer := NewBuffer(0)
+	listener := NewListener(node, buffer)
+	defer listener.Shutdown()
+
+	broadcaster := NewBroadcaster(node)
+	defer broadcaster.Shutdown()
+	odom := stamped("map", "odom", 5, Vector3{1, 0, 0}, Quaternion{W: 1})
+	base := stamped("odom", "base_link", 5, Vector3{0, 2, 0}, Quaternion{W: 1})
+	broadcaster.SendTransform(odom, base)
+	published := node.Published("/tf")
+	if len(published) != 1 || !reflect.DeepEqual(published[0].(*tf2_msgs.TFMessage).Transforms, []geometry_msgs.TransformStamped{odom, base}) {
+		t.Errorf("expected both transforms in one message but got %+v", published)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	transform, err := buffer.WaitForTransform(ctx, "map", "base_link", ros.NewTime(5, 0))
+	if err != nil || !near(TransformFromMsg(transform.Transform).Translation, Vector3{1, 2, 0}) {
+		t.Errorf("expected the broadcast transforms in the buffer but got %+v %v", transform, err)
+	}
+	if authority, _ := buffer.Authority("odom"); authority != "/robot/tf_test" {
+		t.Errorf("expected the publisher as authority but got %q", authority)
+	}
+
+	// A static transform has no time, so it is found at any time.
+	node.Inject("/tf_static", &tf2_msgs.TFMessage{Transforms: []geometry_msgs.TransformStamped{stamped("base_link", "laser", 0, Vector3{0, 0, 1}, Quaternion{W: 1})}})
+	if transform, err = buffer.WaitForTransform(ctx, "map", "laser", ros.NewTime(5, 0)); err != nil || !near(TransformFromMsg(transform.Transform).Translation, Vector3{1, 2, 1}) {
+		t.Errorf("expected the static transform in the buffer but got %+v %v", transform, err)
+	}
+
+	listener.Shutdown()
+	node.Inject("/tf", &tf2_msgs.TFMessage{Transforms: []geometry_msgs.TransformStamped{stamped("map", "odom", 6, Vector3{}, Quaternion{W: 1})}})
+	var extrapolation *ExtrapolationError
+	if _, err := buffer.LookupTransform("map", "odom", ros.NewTime(6, 0)); !errors.As(err, &extrapolation) {
+		t.Errorf("expected no transforms after shutdown but got %v", err)
+	}
+}
+
+func TestStaticBroadcaster(t *testing.T) {
+	node := rostest.NewNode("/robot/tf_test")
+	defer node.Shutdown()
+	broadcaster := NewStaticBroadcaster(node)
+	defer broadcaster.Shutdown()
+
+	laser := stamped("base_link", "laser", 0, Vector3{0, 0, 1}, Quaternion{W: 1})
+	camera := stamped("base_link", "camera", 0, Vector3{1, 0, 0}, Quaternion{W: 1})
+	broadcaster.SendTransform(laser)
+	broadcaster.SendTransform(camera)
+	laser.Transform.Translation.Z = 2
+	broadcaster.SendTransform(laser)
+	published := node.Published("/tf_static")
+	if len(published) != 3 || !reflect.DeepEqual(published[2].(*tf2_msgs.TFMessage).Transforms, []geometry_msgs.TransformStamped{laser, camera}) {
+		t.Errorf("expected all transforms with the latest of each frame but got %+v", published)
+	}
+
+	// A late subscriber receives all transforms as if the topic were latched.
+	var received []geometry_msgs.TransformStamped
+	sub := ros.Subscribe(node, "/tf_static", func(msg *tf2_msgs.TFMessage, event ros.MessageEvent) {
+		received = append(received, msg.Transforms...)
+	})
+	defer sub.Shutdown()
+	node.Executor().RunPending()
+	node.Executor().RunPending()
+	if !reflect.DeepEqual(received, []geometry_msgs.TransformStamped{laser, camera}) {
+		t.Errorf("expected the latched transforms but got %+v", received)
+	}
+}
@@ -0,0 +1,197 @@
+package tf
+
+import (
+	"geometry_msgs"
+	"math"
+)
+
+// Vector3 is a vector or a point in 3D space. It converts to and from a
+// geometry_msgs.Vector3.
+type Vector3 struct {
+	X, Y, Z float64
+}
+
+// Quaternion is a rotation in 3D space. It converts to and from a
+// geometry_msgs.Quaternion.
+type Quaternion struct {
+	X, Y, Z, W float64
+}
+
+// Transform maps coordinates in a child frame to coordinates in its parent frame by
+// rotating and then translating them.
+type Transform struct {
+	Translation Vector3
+	Rotation    Quaternion
+}
+
+// TransformFromMsg converts a geometry_msgs.Transform, like tf2::fromMsg.
+func TransformFromMsg(m geometry_msgs.Transform) Transform {
+	return Transform{Vector3(m.Translation), Quaternion(m.Rotation)}
+}
+
+// Msg converts t to a geometry_msgs.Transform, like tf2::toMsg.
+func (t Transform) Msg() geometry_msgs.Transform {
+	return geometry_msgs.Transform{
+		Translation: geometry_msgs.Vector3(t.Translation),
+		Rotation:    geometry_msgs.Quaternion(t.Rotation),
+	}
+}
+
+// Identity is the transform that maps every frame to itself.
+var Identity = Transform{Rotation: Quaternion{W: 1}}
+
+// Add returns the sum of v and w.
+func (v Vector3) Add(w Vector3) Vector3 {
+	return Vector3{v.X + w.X, v.Y + w.Y, v.Z + w.Z}
+}
+
+// Scale returns v multiplied by s.
+func (v Vector3) Scale(s float64) Vector3 {
+	return Vector3{v.X * s, v.Y * s, v.Z * s}
+}
+
+// Dot returns the dot product of v and w.
+func (v Vector3) Dot(w Vector3) float64 {
+	return v.X*w.X + v.Y*w.Y + v.Z*w.Z
+}
+
+// Cross returns the cross product of v and w.
+func (v Vector3) Cross(w Vector3) Vector3 {
+	return Vector3{v.Y*w.Z - v.Z*w.Y, v.Z*w.X - v.X*w.Z, v.X*w.Y - v.Y*w.X}
+}
+
+// Lerp interpolates linearly between v at ratio 0 and w at ratio 1.
+func (v Vector3) Lerp(w Vector3, ratio float64) Vector3 {
+	return v.Add(w.Add(v.Scale(-1)).Scale(ratio))
+}
+
+// NewQuaternionFromAxisAngle returns the rotation by angle radians about axis, which
+// does not have to be normalized.
+func NewQuaternionFromAxisAngle(axis Vector3, angle float64) Quaternion {
+	norm := math.Sqrt(axis.Dot(axis))
+	if norm == 0 {
+		return Quaternion{W: 1}
+	}
+	s := math.Sin(angle/2) / norm
+	return Quaternion{axis.X * s, axis.Y * s, axis.Z * s, math.Cos(angle / 2)}
+}
+
+// NewQuaternionFromRPY returns the rotation by roll about X, then pitch about Y and
+// then yaw about Z, all about the fixed axes, like tf2::Quaternion::setRPY.
+func NewQuaternionFromRPY(roll, pitch, yaw float64) Quaternion {
+	sr, cr := math.Sincos(roll / 2)
+	sp, cp := math.Sincos(pitch / 2)
+	sy, cy := math.Sincos(yaw / 2)
+	return Quaternion{
+		X: sr*cp*cy - cr*sp*sy,
+		Y: cr*sp*cy + sr*cp*sy,
+		Z: cr*cp*sy - sr*sp*cy,
+		W: cr*cp*cy + sr*sp*sy,
+	}
+}
+
+// RPY returns the roll, pitch and yaw of q as defined by NewQuaternionFromRPY.
+func (q Quaternion) RPY() (roll, pitch, yaw float64) {
+	roll = math.Atan2(2*(q.W*q.X+q.Y*q.Z), 1-2*(q.X*q.X+q.Y*q.Y))
+	sinp := 2 * (q.W*q.Y - q.Z*q.X)
+	pitch = math.Asin(math.Max(-1, math.Min(1, sinp)))
+	yaw = math.Atan2(2*(q.W*q.Z+q.X*q.Y), 1-2*(q.Y*q.Y+q.Z*q.Z))
+	return roll, pitch, yaw
+}
+
+// Dot returns the dot product of q and r.
+func (q Quaternion) Dot(r Quaternion) float64 {
+	return q.X*r.X + q.Y*r.Y + q.Z*r.Z + q.W*r.W
+}
+
+// Norm returns the length of q, which is 1 for rotations.
+func (q Quaternion) Norm() float64 {
+	return math.Sqrt(q.Dot(q))
+}
+
+// Normalize returns q scaled to length 1.
+func (q Quaternion) Normalize() Quaternion {
+	n := q.Norm()
+	return Quaternion{q.X / n, q.Y / n, q.Z / n, q.W / n}
+}
+
+// Mul returns the rotation by r followed by q.
+func (q Quaternion) Mul(r Quaternion) Quaternion {
+	return Quaternion{
+		X: q.W*r.X + q.X*r.W + q.Y*r.Z - q.Z*r.Y,
+		Y: q.W*r.Y - q.X*r.Z + q.Y*r.W + q.Z*r.X,
+		Z: q.W*r.Z + q.X*r.Y - q.Y*r.X + q.Z*r.W,
+		W: q.W*r.W - q.X*r.X - q.Y*r.Y - q.Z*r.Z,
+	}
+}
+
+// Inverse returns the inverse rotation of the unit quaternion q.
+func (q Quaternion) Inverse() Quaternion {
+	return Quaternion{-q.X, -q.Y, -q.Z, q.W}
+}
+
+// Rotate returns v rotated by the unit quaternion q.
+func (q Quaternion) Rotate(v Vector3) Vector3 {
+	u := Vector3{q.X, q.Y, q.Z}
+	t := u.Cross(v).Scale(2)
+	return v.Add(t.Scale(q.W)).Add(u.Cross(t))
+}
+
+// Slerp interpolates spherically between the unit quaternions q at ratio 0 and r at
+// ratio 1 along the shortest path.
+func (q Quaternion) Slerp(r Quaternion, ratio float64) Quaternion {
+	dot := q.Dot(r)
+	if dot < 0 {
+		// q and -q are the same rotation; take the shorter way around.
+		r = Quaternion{-r.X, -r.Y, -r.Z, -r.W}
+		dot = -dot
+	}
+	var s0, s1 float64
+	if dot > 0.9995 {
+		// The rotations are too close to divide by the sine of their angle.
+		s0, s1 = 1-ratio, ratio
+	} else {
+		theta := math.Acos(dot)
+		sin := math.Sin(theta)
+		s0 = math.Sin((1-ratio)*theta) / sin
+		s1 = math.Sin(ratio*theta) / sin
+	}
+	return Quaternion{
+		s0*q.X + s1*r.X,
+		s0*q.Y + s1*r.Y,
+		s0*q.Z + s1*r.Z,
+		s0*q.W + s1*r.W,
+	}.Normalize()
+}
+
+// Mul returns the transform that applies u and then t. If t maps frame b to frame a
+// and u maps frame c to frame b, the result maps frame c to frame a.
+func (t Transform) Mul(u Transform) Transform {
+	return Transform{
+		Translation: t.Rotation.Rotate(u.Translation).Add(t.Translation),
+		Rotation:    t.Rotation.Mul(u.Rotation),
+	}
+}
+
+// Inverse returns the transform that undoes t.
+func (t Transform) Inverse() Transform {
+	inverse := t.Rotation.Inverse()
+	return Transform{
+		Translation: inverse.Rotate(t.Translation).Scale(-1),
+		Rotation:    inverse,
+	}
+}
+
+// Apply returns the point v transformed by t.
+func (t Transform) Apply(v Vector3) Vector3 {
+	return t.Rotation.Rotate(v).Add(t.Translation)
+}
+
+// Interpolate interpolates between t at ratio 0 and u at ratio 1, linearly for the
+// translation and spherically for the rotation.
+func (t Transform) Interpolate(u Transform, ratio float64) Transform {
+	return Transform{
+		Translation: t.Translation.Lerp(u.Translation, ratio),
+		Rotation:    t.Rotation.Slerp(u.Rotation, ratio),
+	}
+}
@@ -2,6 +2,7 @@ package xmlrpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/xml"
 	"errors"
@@ -465,16 +466,29 @@ func parseResponse(d *xml.Decoder) (ok bool, result interface{}, err error) {
 
 // Call a XMLRPC API in a remote host.
 func Call(url string, method string, args ...interface{}) (res interface{}, err error) {
+	return CallContext(context.Background(), http.DefaultClient, url, method, args...)
+}
+
+// CallContext calls a XMLRPC API in a remote host using client.
+// The request is abandoned when ctx is done.
+func CallContext(ctx context.Context, client *http.Client, url string, method string, args ...interface{}) (res interface{}, err error) {
 	var buffer bytes.Buffer
 	err = emitRequest(&buffer, method, args...)
 	if err != nil {
 		err = fmt.Errorf("Building request failed for %v", err)
 		return
 	}
+	var req *http.Request
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, &buffer)
+	if err != nil {
+		err = fmt.Errorf("Building request failed for %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "text/xml")
 	var r *http.Response
-	r, err = http.Post(url, "text/xml", &buffer)
+	r, err = client.Do(req)
 	if err != nil {
-		err = fmt.Errorf("Sending request failed for %v", err)
+		err = fmt.Errorf("Sending request failed for %w", err)
 		return
 	}
 	defer r.Body.Close()